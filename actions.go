@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -17,19 +19,20 @@ const (
 	// minimumPlayers is the number of non-host players required to start, so
 	// that someone holds the starting initiative and the game can't soft-lock.
 	minimumPlayers = 1
-	// recommendedPlayers is how many non-host players the game plays best with.
-	// fewer than this still starts, but only after the host confirms.
-	recommendedPlayers = 2
-	// promptSeconds is how long the spinner has to complete a prompt challenge.
-	// The spinner's countdown runs locally; the host may rule it complete at
-	// any time but can only rule it failed once this has elapsed.
-	promptSeconds = 60
-	// promptGraceSeconds is the server-side allowance before the host may rule
-	// a prompt failed: a couple seconds past promptSeconds to absorb the latency
-	// between the spinner's local clock and the server.
-	promptGraceSeconds = promptSeconds + 2
+	// promptGraceSeconds is how long past the game's prompt_seconds the host
+	// must wait to rule a prompt failed, absorbing the latency between the
+	// spinner's local countdown and the server. The host may rule a prompt
+	// complete at any time.
+	promptGraceSeconds = 2
 )
 
+// promptElapsed is how many seconds the game's running prompt has had, by
+// the database clock. The simulator swaps in a clock of its own, on which
+// every prompt's time is up the moment the host looks.
+var promptElapsed = func(ctx context.Context, gameID string) (int32, error) {
+	return queries.SpinLatestElapsedSeconds(ctx, gameID)
+}
+
 // modifierNotPending rejects a modifier action (flip, shred, clone, transfer)
 // that can't run because the game is not in the pending state. It returns true
 // when it has written an error and the caller should return.
@@ -106,6 +109,21 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "server error", http.StatusInternalServerError)
 		return
 	}
+	// a caller's tokens are their own to mint and revoke, seated or watching
+	if action == "token" || action == "revoke-tokens" {
+		if !state.canWatch(cookieKey) {
+			log.Warn("prohibiting unauthorized player access")
+			http.Error(w, "player not in game", http.StatusForbidden)
+			return
+		}
+		tokenAction(w, r, log, &state, action, cookieKey)
+		return
+	}
+	if state.isSpectator(cookieKey) {
+		log.Warn("spectator attempted an action")
+		http.Error(w, "spectators can't take actions", http.StatusForbidden)
+		return
+	}
 	if !state.isPlayerInGame(cookieKey) {
 		log.Warn("prohibiting unauthorized player access")
 		http.Error(w, "player not in game", http.StatusForbidden)
@@ -121,6 +139,24 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 		attrStateID.Int(int(state.Game.StateID)),
 		attrCallerName.String(state.CallerName),
 	)
+	markSeen(r.Context(), log, gameID, cookieKey)
+	// the host seat can change hands in any state short of game over, so a
+	// game whose host dropped out can still be ruled on
+	if state.Game.StateID != stateOver &&
+		(action == "transfer-host" || action == "vote-host") {
+		hostAction(w, r, log, &state, action, cookieKey)
+		return
+	}
+	// likewise a player may leave, or be kicked, at any point
+	if state.Game.StateID != stateOver && (action == "leave" || action == "kick") {
+		leaveAction(w, r, log, &state, action, cookieKey)
+		return
+	}
+	// and the host may take back a mistake whenever it's noticed
+	if state.Game.StateID != stateOver && action == "undo" {
+		undoAction(w, r, log, &state, cookieKey)
+		return
+	}
 	switch state.Game.StateID {
 	case stateOver: // game over
 		log.Warn("request to ended game", "game_id", gameID)
@@ -128,6 +164,147 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	case stateInviting, stateCreated: // pregame
 		switch action {
+		case "add-bot":
+			botAction(w, r, log, &state, cookieKey)
+			return
+		case "deck":
+			if !state.isHost(cookieKey) {
+				log.Warn("non-host attempted to pick the deck", "game_id", gameID)
+				http.Error(w, "only the host can pick the deck", http.StatusForbidden)
+				return
+			}
+			// empty picks the generic deck
+			var deckID pgtype.Int4
+			if v := r.FormValue("deck_id"); v != "" {
+				id, err := strconv.ParseInt(v, 10, 32)
+				if err != nil {
+					http.Error(w, "invalid deck id", http.StatusBadRequest)
+					return
+				}
+				deck, err := queries.Deck(r.Context(), int32(id))
+				if errors.Is(err, pgx.ErrNoRows) {
+					http.Error(w, "deck not found", http.StatusNotFound)
+					return
+				}
+				if err != nil {
+					log.Error("fetch deck", "error", err, "deck_id", id)
+					http.Error(w, "server error", http.StatusInternalServerError)
+					return
+				}
+				if deck.CardCount == 0 {
+					http.Error(w, "deck has no cards", http.StatusConflict)
+					return
+				}
+				deckID = pgInt(int32(id))
+			}
+			err := queries.GameDeckSet(r.Context(), sqlc.GameDeckSetParams{
+				ID:     gameID,
+				DeckID: deckID,
+			})
+			if err != nil {
+				log.Error("set deck", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			log.Info("deck picked", "deck_id", deckID.Int32, "generic", !deckID.Valid)
+			invalidate(r.Context(), gameID)
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
+			return
+		case "packs":
+			if !state.isHost(cookieKey) {
+				log.Warn("non-host attempted to pick packs", "game_id", gameID)
+				http.Error(w, "only the host can pick the card packs", http.StatusForbidden)
+				return
+			}
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "invalid form", http.StatusBadRequest)
+				return
+			}
+			// every checked pack is on, every other pack off
+			enabled := r.Form["pack"]
+			for _, slug := range enabled {
+				if !slices.ContainsFunc(state.Packs, func(p sqlc.GamePacksRow) bool {
+					return p.Slug == slug
+				}) {
+					http.Error(w, "unknown card pack", http.StatusBadRequest)
+					return
+				}
+			}
+			// with no packs a game without a deck would deal only written
+			// cards; keep the current picks and redraw the table to show them
+			if len(enabled) == 0 {
+				w.Header().Set("HX-Trigger",
+					`{"notice":"Keep at least one card pack.","refreshTable":true}`,
+				)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			err := queries.GamePacksSet(r.Context(), sqlc.GamePacksSetParams{
+				GameID:  gameID,
+				Enabled: enabled,
+			})
+			if err != nil {
+				log.Error("set packs", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			log.Info("packs picked", "packs", enabled)
+			invalidate(r.Context(), gameID)
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
+			return
+		case "settings":
+			if !state.isHost(cookieKey) {
+				log.Warn("non-host attempted to change settings", "game_id", gameID)
+				http.Error(w, "only the host can change the settings", http.StatusForbidden)
+				return
+			}
+			// an invalid setting keeps the current settings; the notice says
+			// why and the table redraws to show what's still in effect
+			settings, err := settingsFromForm(r, state.Game)
+			if err == nil {
+				// the deck must fill the wheel, counting the cards the players
+				// joined so far will write
+				written := int64(settings.CardsPerPlayer) * int64(len(state.Players))
+				if capacity := state.deckCapacity(); capacity+written < int64(settings.CardCount) {
+					err = fmt.Errorf("The deck holds %d cards and players write %d, short of a deck size of %d.",
+						capacity, written, settings.CardCount,
+					)
+				}
+			}
+			if err != nil {
+				log.Info("settings rejected", "error", err)
+				w.Header().Set("HX-Trigger",
+					`{"notice":`+strconv.Quote(err.Error())+`,"refreshTable":true}`,
+				)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			n, err := queries.GameSettingsSet(r.Context(), settings)
+			if err != nil {
+				log.Error("set settings", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			if n == 0 {
+				log.Warn("settings raced the start", "game_id", gameID)
+				http.Error(w, "game already started", http.StatusConflict)
+				return
+			}
+			log.Info("settings changed",
+				"wheel_slots", settings.WheelSlots,
+				"card_count", settings.CardCount,
+				"cards_per_player", settings.CardsPerPlayer,
+				"starting_points", settings.StartingPoints,
+				"initiative_timer", settings.InitiativeTimer,
+				"prompt_seconds", settings.PromptSeconds,
+				"recommended_players", settings.RecommendedPlayers,
+			)
+			invalidate(r.Context(), gameID)
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
+			return
 		case "start":
 			if !state.isHost(cookieKey) {
 				log.Warn("non-host attempted to start game", "game_id", gameID)
@@ -150,90 +327,155 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				return
 			}
-			// the game plays best with two or more, but a single non-host
-			// player is allowed once the host confirms via the start dialog.
+			// the game plays best with the host's recommended_players, but
+			// fewer are allowed once the host confirms via the start dialog.
 			// without confirmation, ask first instead of starting.
-			if state.nonHostPlayers() < recommendedPlayers &&
+			if state.nonHostPlayers() < int(state.Game.RecommendedPlayers) &&
 				r.URL.Query().Get("confirm") != "1" {
 				log.Info("prompting host to start with deficit of players",
 					"game_id", gameID,
 					"non_host_players", state.nonHostPlayers(),
-					"recommended", recommendedPlayers,
+					"recommended", state.Game.RecommendedPlayers,
 				)
 				w.Header().Set("HX-Trigger", `{"confirmStart":""}`)
 				w.WriteHeader(http.StatusOK)
 				return
 			}
-			// populate and shuffle the deck
-			err = queries.GameCardsInitGeneric(r.Context(), gameID)
-			if err != nil {
-				log.Error("init deck",
-					"error", err,
-					"game_id", gameID,
+			// players write their own cards before play when the game asks
+			// for any; the phase starts the game itself once it closes.
+			if state.Game.CardsPerPlayer > 0 {
+				n, err := queries.GameStateTransition(r.Context(), sqlc.GameStateTransitionParams{
+					ID:        gameID,
+					FromState: state.Game.StateID,
+					ToState:   stateWriting,
+				})
+				if err != nil {
+					log.Error("open writing phase", "error", err)
+					http.Error(w, "server error", http.StatusInternalServerError)
+					return
+				}
+				if n == 0 {
+					log.Warn("start raced another request", "game_id", gameID)
+					http.Error(w, "game already started", http.StatusConflict)
+					return
+				}
+				log.Info("writing phase opened",
+					"cards_per_player", state.Game.CardsPerPlayer,
 				)
-				http.Error(w, "server error", http.StatusInternalServerError)
+				if err := recordEvent(r.Context(), log, queries, sqlc.EventCreateParams{
+					GameID:    gameID,
+					EventType: "writing",
+				}); err != nil {
+					log.Error("log writing event", "error", err, "game_id", gameID)
+				}
+				invalidate(r.Context(), gameID)
+				w.Header().Set("HX-Trigger", "refreshTable")
+				w.WriteHeader(http.StatusOK)
 				return
 			}
-			err = queries.GameCardsShuffle(r.Context(), gameID)
+			started, err := startGame(r.Context(), log, gameID, state.Game.StateID)
 			if err != nil {
-				log.Error("shuffle deck",
-					"error", err,
-					"game_id", gameID,
-				)
+				log.Error("start game", "error", err)
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
-			log.Info("deck initialized and shuffled",
-				"game_id", gameID,
-			)
+			if !started {
+				log.Warn("start raced another request", "game_id", gameID)
+				http.Error(w, "game already started", http.StatusConflict)
+				return
+			}
 
-			// set game to in-progress
-			err := queries.GameUpdate(r.Context(), sqlc.GameUpdateParams{
-				ID:                gameID,
-				StateID:           stateReady, // in progress
-				InitiativeCurrent: pgtype.Int4{Int32: 0, Valid: true},
-			})
+			// invalidate cache for this game
+			invalidate(r.Context(), gameID)
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
+			return
+		default:
+			log.Warn(ErrActionInvalid.Error())
+			http.Error(w, ErrActionInvalid.Error(), http.StatusTooEarly)
+			return
+		}
+	case stateWriting: // pregame, players writing cards
+		switch action {
+		case "write":
+			if cardsOwed(state.Game, state.CardsWritten, int32(state.CallerID)) <= 0 {
+				log.Warn("card written with none owed")
+				http.Error(w, "no cards owed", http.StatusConflict)
+				return
+			}
+			card, err := cardFromForm(r)
 			if err != nil {
-				log.Error("start game", "error", err)
-				http.Error(w, "server error", http.StatusInternalServerError)
+				log.Debug("invalid card", "error", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			log.Info("game started")
-
-			// start initiative with first non-host player
-			err = queries.GameUpdate(r.Context(), sqlc.GameUpdateParams{
-				ID:                gameID,
-				StateID:           stateTurn,
-				InitiativeCurrent: pgtype.Int4{Int32: 1, Valid: true},
+			gameCardID, err := queries.GameCardCreate(r.Context(), sqlc.GameCardCreateParams{
+				GameID:  gameID,
+				Type:    card.Type,
+				Front:   card.Front,
+				Back:    card.Back,
+				Creator: pgInt(int32(state.CallerID)),
 			})
 			if err != nil {
-				log.Error("update initiative", "error", err)
+				log.Error("write card", "error", err)
 				http.Error(w, "server error", http.StatusInternalServerError)
+				return
 			}
-			log.Info("initiative initiated", "state", "ready", "initiative", 1)
-
-			// log the start, and the first player's turn so they hear the ding.
-			// state is already committed, so these are best-effort: a failure
-			// shouldn't 500 a game that has already started.
-			if err := recordEvent(r.Context(), log, queries, sqlc.EventCreateParams{
+			log.Info("card written", "game_card_id", gameCardID, "type", card.Type)
+			// no game_card_id on the event: the feed would show everyone
+			// the card before it's spun.
+			if err := writeEvent(w, r, log, queries, sqlc.EventCreateParams{
 				GameID:    gameID,
-				EventType: "start",
+				EventType: "write",
+				ActorID:   pgInt(int32(state.CallerID)),
 			}); err != nil {
-				log.Error("log start event", "error", err, "game_id", gameID)
+				return
+			}
+			invalidate(r.Context(), gameID)
+
+			// the last card owed closes the phase and starts the game. recount
+			// after the write: the request's state can't see a concurrent one.
+			fresh, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
+			if err != nil {
+				log.Error("recount written cards", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			if fresh.cardsOutstanding() == 0 {
+				started, err := startGame(r.Context(), log, gameID, stateWriting)
+				if err != nil {
+					log.Error("start game after writing", "error", err)
+					http.Error(w, "server error", http.StatusInternalServerError)
+					return
+				}
+				if started {
+					invalidate(r.Context(), gameID)
+				}
+			}
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
+			return
+		case "close":
+			if !state.isHost(cookieKey) {
+				log.Warn("non-host attempted to close writing", "game_id", gameID)
+				http.Error(w, "only the host can close writing", http.StatusForbidden)
+				return
 			}
-			firstPlayer, err := queries.InitiativeCurrentPlayer(r.Context(), gameID)
+			log.Info("host closing writing phase",
+				"cards_outstanding", state.cardsOutstanding(),
+			)
+			started, err := startGame(r.Context(), log, gameID, stateWriting)
 			if err != nil {
-				log.Error("find first turn player", "error", err, "game_id", gameID)
-			} else if err := recordEvent(r.Context(), log, queries, sqlc.EventCreateParams{
-				GameID:    gameID,
-				EventType: "turn",
-				TargetID:  pgInt(firstPlayer),
-			}); err != nil {
-				log.Error("log first turn event", "error", err, "game_id", gameID)
+				log.Error("start game", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
 			}
-
-			// invalidate cache for this game
-			cache.Delete(gameID)
+			if !started {
+				log.Warn("close raced another request", "game_id", gameID)
+				http.Error(w, "game already started", http.StatusConflict)
+				return
+			}
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -319,7 +561,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 					}); err != nil {
 						return
 					}
-					cache.Delete(gameID)
+					invalidate(r.Context(), gameID)
 					w.Header().Set("HX-Trigger", "refreshTable")
 					w.WriteHeader(http.StatusOK)
 					return
@@ -385,13 +627,13 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 					"player_id", id,
 					"prompt", lastSpin.Front,
 				)
-				cache.Delete(gameID)
+				invalidate(r.Context(), gameID)
 				// newPrompt opens the spinner's challenge popup and starts their
 				// local countdown; the spin event already dinged the spinner.
-				// carry the window so the client matches the server's promptSeconds.
+				// carry the window so the client matches the game's prompt_seconds.
 				trigger := `{"refreshTable":null,"newPrompt":{"prompt":` +
 					strconv.Quote(lastSpin.Front) +
-					`,"window":` + strconv.Itoa(promptSeconds) + `}}`
+					`,"window":` + strconv.Itoa(int(state.Game.PromptSeconds)) + `}}`
 				w.Header().Set("HX-Trigger", trigger)
 				w.WriteHeader(http.StatusOK)
 				return
@@ -401,7 +643,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				// they drew and wait for them to acknowledge (POST
 				// /action/acknowledge), which advances the turn. initiative
 				// stays on them until they've seen it.
-				cache.Delete(gameID)
+				invalidate(r.Context(), gameID)
 				fresh, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
 				if err != nil {
 					log.Error("refresh state after spin", "error", err)
@@ -455,7 +697,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 					"player_id", id,
 					"game_card_id", gcID,
 				)
-				cache.Delete(gameID)
+				invalidate(r.Context(), gameID)
 				w.Header().Set("HX-Trigger",
 					`{"refreshTable":null,"modifierShredded":"`+lastSpin.ModifierEffect.String+`"}`)
 				w.WriteHeader(http.StatusOK)
@@ -484,7 +726,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 					"player_id", id,
 					"game_card_id", gcID,
 				)
-				cache.Delete(gameID)
+				invalidate(r.Context(), gameID)
 				w.Header().Set("HX-Trigger",
 					`{"refreshTable":null,"modifierShredded":"`+lastSpin.ModifierEffect.String+`"}`)
 				w.WriteHeader(http.StatusOK)
@@ -512,7 +754,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", `{"refreshTable":null,"loadModifier":null}`)
 			w.WriteHeader(http.StatusOK)
 
@@ -555,12 +797,18 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
-			if err := advanceTurn(r.Context(), log, queries, gameID); err != nil {
+			advanced, err := advanceTurnFrom(r.Context(), log, queries, gameID, state.Game.InitiativeCurrent.Int32)
+			if err != nil {
 				log.Error("advance after acknowledge", "error", err, "game_id", gameID)
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
-			cache.Delete(gameID)
+			if !advanced {
+				log.Warn("acknowledge raced another", "game_id", gameID, "player_id", id)
+				http.Error(w, "nothing to acknowledge", http.StatusConflict)
+				return
+			}
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -586,13 +834,19 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "nothing to advance", http.StatusConflict)
 				return
 			}
-			if err := advanceTurn(r.Context(), log, queries, gameID); err != nil {
+			advanced, err := advanceTurnFrom(r.Context(), log, queries, gameID, state.Game.InitiativeCurrent.Int32)
+			if err != nil {
 				log.Error("advance turn by host", "error", err, "game_id", gameID)
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
+			if !advanced {
+				log.Warn("advance raced another", "game_id", gameID)
+				http.Error(w, "nothing to advance", http.StatusConflict)
+				return
+			}
 			log.Info("host advanced initiative", "game_id", gameID)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -631,7 +885,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 			}); err != nil {
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -670,7 +924,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 			}); err != nil {
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -707,7 +961,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 			}); err != nil {
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -754,7 +1008,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 			return
@@ -900,7 +1154,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"game_id", gameID,
 				"game_card_id", gcID,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 		case "shred":
@@ -1046,7 +1300,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"game_id", gameID,
 				"card_id", cardID,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 		case "clone":
@@ -1227,7 +1481,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"card_id", cardID,
 				"target_player_id", targetID,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 
@@ -1334,13 +1588,14 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "target player not in game", http.StatusBadRequest)
 				return
 			}
-			err = queries.GameCardMove(r.Context(), sqlc.GameCardMoveParams{
+			moved, err := queries.GameCardMove(r.Context(), sqlc.GameCardMoveParams{
 				ID:     int32(cardID),
 				GameID: gameID,
 				PlayerID: pgtype.Int4{
 					Int32: int32(targetID),
 					Valid: true,
 				},
+				Holder: pgInt(int32(xferPlayerID)),
 			})
 			if err != nil {
 				log.Error("transfer card",
@@ -1352,6 +1607,14 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
+			if moved == 0 {
+				log.Warn("transfer raced another request",
+					"game_id", gameID,
+					"game_card_id", cardID,
+				)
+				http.Error(w, "card has moved", http.StatusConflict)
+				return
+			}
 			// add an event for the transfer (sender -> recipient)
 			if err := writeEvent(w, r, log, queries, sqlc.EventCreateParams{
 				GameID:     gameID,
@@ -1409,7 +1672,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"card_id", cardID,
 				"target_player_id", targetID,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 
@@ -1545,7 +1808,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"accuser", accuserID,
 				"game_card_id", gcID,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", fmt.Sprintf(
 				`{"refreshTable":null,"infractionCreated":{"id":%d}}`,
 				infractionID,
@@ -1802,7 +2065,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"verdict", verdict,
 				"points", penalty,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 		case "succeed", "fail":
@@ -1834,7 +2097,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			if action == "fail" {
-				elapsed, err := queries.SpinLatestElapsedSeconds(r.Context(), gameID)
+				elapsed, err := promptElapsed(r.Context(), gameID)
 				if err != nil {
 					log.Error("measure prompt elapsed",
 						"error", err,
@@ -1843,11 +2106,11 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 					http.Error(w, "server error", http.StatusInternalServerError)
 					return
 				}
-				if elapsed < promptGraceSeconds {
+				if grace := state.Game.PromptSeconds + promptGraceSeconds; elapsed < grace {
 					log.Debug("prompt failed too early, within grace",
 						"game_id", gameID,
 						"elapsed", elapsed,
-						"grace", promptGraceSeconds,
+						"grace", grace,
 					)
 					http.Error(w, "challenge still in progress", http.StatusTooEarly)
 					return
@@ -1982,7 +2245,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 				"player_id", spinnerID,
 				"rules_held", rulesHeld,
 			)
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			w.Header().Set("HX-Trigger", "refreshTable")
 			w.WriteHeader(http.StatusOK)
 		case "end":
@@ -2007,9 +2270,10 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 			}); err != nil {
 				return
 			}
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			log.Info("game ended")
-			w.WriteHeader(http.StatusGone)
+			w.Header().Set("HX-Trigger", "refreshTable")
+			w.WriteHeader(http.StatusOK)
 			return
 		default:
 			log.Warn("unsupported action requested")
@@ -0,0 +1,886 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// apiPrefix roots the versioned JSON API. Everything under it answers in
+// JSON, errors included; the htmx fragments stay where they are.
+const apiPrefix = "/api/v1"
+
+const (
+	apiEventsPage    = 100     // events per page when the caller names no limit
+	apiEventsPageMax = 500     // the most events one page holds
+	apiBodyMax       = 1 << 16 // largest action body read
+)
+
+// apiError is the body of every API error.
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Status int `json:"status"`
+	// Code is the status in snake_case (not_found, conflict, ...), or for a
+	// refused action: refused, or confirm_required when the action asks to
+	// be sent again with confirm.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiGame is a game as the caller sees it.
+type apiGame struct {
+	ID      string `json:"id"`
+	State   string `json:"state"`
+	StateID int32  `json:"state_id"`
+	Role    role   `json:"role"`
+	// CallerID is the caller's player id, seated or watching.
+	CallerID     int32  `json:"caller_id"`
+	TurnPlayerID *int32 `json:"turn_player_id"`
+	// TurnSecondsLeft is null when no turn clock is running.
+	TurnSecondsLeft *int             `json:"turn_seconds_left"`
+	AwaitingAck     bool             `json:"awaiting_ack"`
+	HostAway        bool             `json:"host_away"`
+	Deck            string           `json:"deck"` // empty for the generic deck
+	Settings        map[string]int32 `json:"settings"`
+	Updated         time.Time        `json:"updated"`
+}
+
+type apiPlayer struct {
+	ID     int32  `json:"id"`
+	Name   string `json:"name"`
+	Points int32  `json:"points"`
+	Seat   int32  `json:"seat"` // initiative; the host sits at 0
+	Host   bool   `json:"host"`
+	Turn   bool   `json:"turn"`
+	Bot    bool   `json:"bot"`
+}
+
+type apiPlayers struct {
+	Players    []apiPlayer `json:"players"`
+	Spectators []string    `json:"spectators"`
+}
+
+// apiCard is a card a player holds, face up.
+type apiCard struct {
+	ID             int32  `json:"id"`
+	Type           string `json:"type"`
+	Content        string `json:"content"`
+	Flipped        bool   `json:"flipped"`
+	FromClone      bool   `json:"from_clone"`
+	ModifierEffect string `json:"modifier_effect,omitempty"`
+}
+
+type apiHand struct {
+	PlayerID int32     `json:"player_id"`
+	Cards    []apiCard `json:"cards"`
+}
+
+type apiHands struct {
+	Hands []apiHand `json:"hands"`
+}
+
+type apiWheelSlot struct {
+	Slot      int32 `json:"slot"`
+	StackSize int64 `json:"stack_size"`
+}
+
+// apiWheel summarizes the wheel: how deep each slot runs, not what's on it.
+type apiWheel struct {
+	Slots []apiWheelSlot `json:"slots"`
+	Cards int64          `json:"cards"`
+}
+
+type apiInfraction struct {
+	ID         int32     `json:"id"`
+	AccusedID  int32     `json:"accused_id"`
+	AccuserID  int32     `json:"accuser_id"`
+	GameCardID int32     `json:"game_card_id"`
+	Active     bool      `json:"active"`
+	Affirmed   *bool     `json:"affirmed"` // null until decided
+	Created    time.Time `json:"created"`
+}
+
+type apiInfractions struct {
+	Infractions []apiInfraction `json:"infractions"`
+}
+
+// apiEvent is one game log entry. Card is the face the feed shows: the back
+// once flipped, else the front.
+type apiEvent struct {
+	ID          int32  `json:"id"`
+	Type        string `json:"type"`
+	Actor       string `json:"actor,omitempty"`
+	Target      string `json:"target,omitempty"`
+	PointsDelta *int32 `json:"points_delta,omitempty"`
+	Affirmed    *bool  `json:"affirmed,omitempty"`
+	Card        string `json:"card,omitempty"`
+	CardType    string `json:"card_type,omitempty"`
+	Undone      string `json:"undone,omitempty"` // an undo's: the type of event it reversed
+}
+
+// apiEvents is a page of the game log. Pass NextSince as since for the next
+// page; More says whether one is already waiting.
+type apiEvents struct {
+	Events    []apiEvent `json:"events"`
+	NextSince int32      `json:"next_since"`
+	More      bool       `json:"more"`
+}
+
+// apiActionResult answers an action that went through. Signals carries what
+// the action told the page, e.g. newCard with the rule drawn or
+// infractionCreated with its id.
+type apiActionResult struct {
+	OK      bool                       `json:"ok"`
+	Signals map[string]json.RawMessage `json:"signals,omitempty"`
+}
+
+// apiCreated answers a create.
+type apiCreated struct {
+	GameID string `json:"game_id"`
+}
+
+// apiJoined answers a join: who the caller now is, and a bearer token to act
+// as them with.
+type apiJoined struct {
+	GameID    string `json:"game_id"`
+	PlayerID  int32  `json:"player_id"`
+	Spectator bool   `json:"spectator"`
+	TokenID   int32  `json:"token_id"`
+	Token     string `json:"token"` // sent only this once
+}
+
+// apiRoute is one API endpoint: what routes it, what documents it, and what
+// serves it. apiRoutes drives both the mux and the OpenAPI document.
+type apiRoute struct {
+	Method   string
+	Path     string
+	Summary  string
+	Query    []apiParam
+	Body     any // a zero value of the request body, if it takes one
+	Response any // a zero value of the response body
+	handler  http.HandlerFunc
+}
+
+// apiParam is a query parameter, for the OpenAPI document.
+type apiParam struct {
+	Name        string
+	Description string
+}
+
+var apiRoutes = []apiRoute{
+	{Method: http.MethodPost, Path: "/games", Summary: "Create a game; its first player to join hosts it",
+		Body: apiNoParams{}, Response: apiCreated{}, handler: apiCreateHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}", Summary: "The game, as the caller sees it",
+		Response: apiGame{}, handler: apiGameHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/players", Summary: "Seated players, in seat order, and spectators",
+		Response: apiPlayers{}, handler: apiPlayersHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/hands", Summary: "The cards each non-host player holds",
+		Response: apiHands{}, handler: apiHandsHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/wheel", Summary: "How deep each wheel slot runs",
+		Response: apiWheel{}, handler: apiWheelHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/infractions", Summary: "Every accusation and its verdict",
+		Response: apiInfractions{}, handler: apiInfractionsHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/summary", Summary: "The game in numbers, and its awards; final once the game is over",
+		Response: gameSummary{}, handler: apiSummaryHandler},
+	{Method: http.MethodGet, Path: "/games/{game_id}/events", Summary: "A page of the game log, oldest first",
+		Query: []apiParam{
+			{"since", "only events after this id (default 0)"},
+			{"limit", fmt.Sprintf("events per page (default %d, at most %d)", apiEventsPage, apiEventsPageMax)},
+		},
+		Response: apiEvents{}, handler: apiEventsHandler},
+	{Method: http.MethodPost, Path: "/games/{game_id}/join", Summary: "Join the game, seated or watching, and get a bearer token",
+		Body: apiJoinParams{}, Response: apiJoined{}, handler: apiJoinHandler},
+}
+
+// The JSON bodies the actions, and join, take. Each is carried out by the
+// handler behind the page's form, so each field goes over as the form value
+// its json name (or form tag) names.
+type (
+	apiNoParams   struct{}
+	apiJoinParams struct {
+		Username string `json:"username"`
+		Spectate bool   `json:"spectate,omitempty"` // watch without a seat
+	}
+	apiDeckParams struct {
+		DeckID *int32 `json:"deck_id,omitempty"` // omit for the generic deck
+	}
+	apiPacksParams struct {
+		Packs []string `json:"packs" form:"pack"`
+	}
+	apiSettingsParams struct {
+		WheelSlots         *int32 `json:"wheel_slots,omitempty"`
+		CardCount          *int32 `json:"card_count,omitempty"`
+		CardsPerPlayer     *int32 `json:"cards_per_player,omitempty"`
+		StartingPoints     *int32 `json:"starting_points,omitempty"`
+		InitiativeTimer    *int32 `json:"initiative_timer,omitempty"`
+		PromptSeconds      *int32 `json:"prompt_seconds,omitempty"`
+		RecommendedPlayers *int32 `json:"recommended_players,omitempty"`
+		HostTimeout        *int32 `json:"host_timeout,omitempty"`
+	}
+	apiStartParams struct {
+		// Confirm starts with fewer players than recommended.
+		Confirm bool `json:"confirm,omitempty"`
+	}
+	apiWriteParams struct {
+		Type  string `json:"type"` // rule or prompt
+		Front string `json:"front"`
+		Back  string `json:"back,omitempty"`
+	}
+	apiCardParams struct {
+		GameCardID int32 `json:"game_card_id"`
+	}
+	apiCardTargetParams struct {
+		GameCardID     int32 `json:"game_card_id"`
+		TargetPlayerID int32 `json:"target_player_id"`
+	}
+	apiAccuseParams struct {
+		DefendantID int32 `json:"defendant_id"`
+		GameCardID  int32 `json:"game_card_id"`
+	}
+	apiDecideParams struct {
+		InfractionID int32  `json:"infraction_id"`
+		Verdict      string `json:"verdict"`          // affirm or absolve
+		Amount       *int32 `json:"amount,omitempty"` // points, on affirm
+	}
+	apiPlayerParams struct {
+		PlayerID int32 `json:"player_id"`
+	}
+	apiLeaveParams struct {
+		Cards string `json:"cards,omitempty"` // wheel (default), shred or share
+	}
+	apiKickParams struct {
+		PlayerID int32  `json:"player_id"`
+		Cards    string `json:"cards,omitempty"` // wheel (default), shred or share
+	}
+	apiRevokeParams struct {
+		PlayerID *int32 `json:"player_id,omitempty"` // another player's, host only
+		TokenID  *int32 `json:"token_id,omitempty"`  // omit for all of them
+	}
+)
+
+// apiAction is an action the API carries out, with its body and what it
+// does, for the OpenAPI document.
+type apiAction struct {
+	Name    string
+	Summary string
+	Params  any
+}
+
+var apiActions = []apiAction{
+	{"deck", "Host picks the deck, before the start", apiDeckParams{}},
+	{"packs", "Host picks the card packs, before the start", apiPacksParams{}},
+	{"settings", "Host changes settings, before the start; a setting left out keeps its value", apiSettingsParams{}},
+	{"add-bot", "Host seats a bot that plays its own turns, before the start", apiNoParams{}},
+	{"start", "Host starts the game", apiStartParams{}},
+	{"write", "Write a card, while players write", apiWriteParams{}},
+	{"close", "Host closes card writing", apiNoParams{}},
+	{"spin", "Spin the wheel, on your turn", apiNoParams{}},
+	{"acknowledge", "Accept the rule drawn and end your turn", apiNoParams{}},
+	{"advance", "Host advances past a drawn rule", apiNoParams{}},
+	{"pause", "Host pauses the game", apiNoParams{}},
+	{"resume", "Host resumes the game", apiNoParams{}},
+	{"endgame", "Host ends the game once the deck is spent", apiNoParams{}},
+	{"continue", "Host plays on once the deck is spent", apiNoParams{}},
+	{"flip", "Spend a flip modifier on a rule", apiCardParams{}},
+	{"shred", "Spend a shred modifier on a rule", apiCardParams{}},
+	{"clone", "Spend a clone modifier copying a rule to a player", apiCardTargetParams{}},
+	{"transfer", "Spend a transfer modifier moving a rule to a player", apiCardTargetParams{}},
+	{"accuse", "Accuse a player of breaking a rule they hold", apiAccuseParams{}},
+	{"decide", "Host rules on an accusation", apiDecideParams{}},
+	{"succeed", "Host passes a prompt challenge", apiNoParams{}},
+	{"fail", "Host fails a prompt challenge, once its time is up", apiNoParams{}},
+	{"end", "Host ends the game", apiNoParams{}},
+	{"transfer-host", "Host hands the host seat to a player", apiPlayerParams{}},
+	{"vote-host", "Vote for a new host while the host is away", apiPlayerParams{}},
+	{"leave", "Give up your seat", apiLeaveParams{}},
+	{"kick", "Host removes a player", apiKickParams{}},
+	{"undo", "Host takes back the latest verdict, prompt points, transfer or clone", apiNoParams{}},
+	{"token", "Mint yourself a bearer token, seated or watching; the apiToken signal carries it", apiNoParams{}},
+	{"revoke-tokens", "Revoke your bearer tokens, or just token_id; the host may revoke another player's", apiRevokeParams{}},
+}
+
+// apiActionsPath routes every action; the OpenAPI document lists each.
+const apiActionsPath = "/games/{game_id}/actions/{action}"
+
+// registerAPI routes the API, wrapping each endpoint like the pages it
+// mirrors.
+func registerAPI(mux *http.ServeMux, wrap func(http.Handler) http.Handler) {
+	for _, route := range apiRoutes {
+		mux.Handle(apiPrefix+route.Path, wrap(apiMethod(route.Method, route.handler)))
+	}
+	mux.Handle(apiPrefix+apiActionsPath, wrap(apiMethod(http.MethodPost, apiActionHandler)))
+	mux.Handle(apiPrefix+"/openapi.json", wrap(apiMethod(http.MethodGet, openAPIHandler)))
+}
+
+// apiMethod turns away any method but method, in JSON.
+func apiMethod(method string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// writeJSON sends body as JSON with status.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("encode api response", "error", err)
+	}
+}
+
+// writeAPIError sends an error body whose code is status in snake_case.
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	code := strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	writeAPICodeError(w, status, code, message)
+}
+
+func writeAPICodeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{apiErrorBody{Status: status, Code: code, Message: message}})
+}
+
+// apiCaller loads the game for an API read and checks the caller may see
+// it, answering with an error itself when not.
+func apiCaller(w http.ResponseWriter, r *http.Request) (view, bool) {
+	gameID := r.PathValue("game_id")
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(attrGameID.String(gameID))
+	log := log.With("handler", "apiHandler", "game_id", gameID, "path", r.URL.Path)
+	cookieID, cookieKey, err := cookie(r)
+	switch err {
+	case nil:
+	case ErrCookieMissing:
+		writeAPIError(w, http.StatusUnauthorized, "session cookie missing")
+		return view{}, false
+	case ErrCookieInvalid:
+		writeAPIError(w, http.StatusForbidden, "invalid session cookie")
+		return view{}, false
+	case ErrTokenInvalid:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeAPIError(w, http.StatusUnauthorized, "invalid bearer token")
+		return view{}, false
+	default:
+		log.Error("unexpected error getting cookie", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return view{}, false
+	}
+	span.SetAttributes(attrPlayerID.String(cookieID))
+	state, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
+	if err == ErrStateNoGame {
+		writeAPIError(w, http.StatusNotFound, "game not found")
+		return view{}, false
+	}
+	if err != nil {
+		log.Error("unexpected error getting state", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return view{}, false
+	}
+	if !state.canWatch(cookieKey) {
+		log.Warn("prohibiting unauthorized player access")
+		writeAPIError(w, http.StatusForbidden, "player not in game")
+		return view{}, false
+	}
+	v := state.viewFor(cookieKey)
+	if v.Game.StateID != stateOver && v.Role != roleSpectator {
+		markSeen(r.Context(), log, gameID, cookieKey)
+	}
+	return v, true
+}
+
+func apiGameHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	game := apiGame{
+		ID:          v.Game.ID,
+		State:       v.Game.StateName,
+		StateID:     v.Game.StateID,
+		Role:        v.Role,
+		CallerID:    int32(v.CallerID),
+		AwaitingAck: v.AwaitingAck,
+		HostAway:    v.Game.HostAway,
+		Deck:        v.Game.DeckName.String,
+		Settings:    make(map[string]int32, len(gameSettings)),
+		Updated:     v.Updated,
+	}
+	for _, p := range v.Players {
+		if p.Initiative.Int32 != 0 && p.Initiative.Int32 == v.Game.InitiativeCurrent.Int32 {
+			game.TurnPlayerID = &p.PlayerID
+		}
+	}
+	if left := v.TurnSecondsLeft(); left >= 0 {
+		game.TurnSecondsLeft = &left
+	}
+	for _, s := range v.Settings() {
+		game.Settings[s.Field] = s.Value
+	}
+	writeJSON(w, http.StatusOK, game)
+}
+
+func apiPlayersHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	body := apiPlayers{
+		Players:    make([]apiPlayer, 0, len(v.Players)),
+		Spectators: make([]string, 0, len(v.Spectators)),
+	}
+	for _, p := range v.Players {
+		seat := p.Initiative.Int32
+		body.Players = append(body.Players, apiPlayer{
+			ID:     p.PlayerID,
+			Name:   p.Name,
+			Points: p.Points.Int32,
+			Seat:   seat,
+			Host:   seat == 0,
+			Turn:   seat != 0 && seat == v.Game.InitiativeCurrent.Int32,
+			Bot:    p.Bot,
+		})
+	}
+	body.Spectators = append(body.Spectators, v.Spectators...)
+	writeJSON(w, http.StatusOK, body)
+}
+
+func apiHandsHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	body := apiHands{Hands: make([]apiHand, 0, len(v.Players))}
+	for _, p := range v.Players {
+		if p.Initiative.Int32 == 0 {
+			continue // the host holds no cards
+		}
+		hand := apiHand{PlayerID: p.PlayerID, Cards: []apiCard{}}
+		for _, c := range v.CardsPlayers {
+			if c.PlayerID.Int32 != p.PlayerID {
+				continue
+			}
+			hand.Cards = append(hand.Cards, apiCardFrom(c))
+		}
+		body.Hands = append(body.Hands, hand)
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func apiCardFrom(c sqlc.GameCardsPlayerViewRow) apiCard {
+	content, _ := c.Content.(string)
+	return apiCard{
+		ID:             c.ID,
+		Type:           c.Type,
+		Content:        content,
+		Flipped:        c.Flipped.Bool,
+		FromClone:      c.FromClone.Bool,
+		ModifierEffect: c.ModifierEffect.String,
+	}
+}
+
+func apiWheelHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	body := apiWheel{Slots: make([]apiWheelSlot, 0, len(v.Wheel))}
+	for _, slot := range v.Wheel {
+		body.Slots = append(body.Slots, apiWheelSlot{Slot: slot.Slot, StackSize: slot.StackSize})
+		body.Cards += slot.StackSize
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func apiInfractionsHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	body := apiInfractions{Infractions: make([]apiInfraction, 0, len(v.Infractions))}
+	for _, inf := range v.Infractions {
+		out := apiInfraction{
+			ID:         inf.ID,
+			AccusedID:  inf.Accused,
+			AccuserID:  inf.Accuser,
+			GameCardID: inf.GameCardID,
+			Active:     inf.Active.Bool,
+			Created:    inf.Created.Time,
+		}
+		if inf.Affirmed.Valid {
+			out.Affirmed = &inf.Affirmed.Bool
+		}
+		body.Infractions = append(body.Infractions, out)
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func apiSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	summary, err := summarizeGame(r.Context(), v)
+	if err != nil {
+		log.Error("summarize game", "error", err, "game_id", v.Game.ID)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func apiEventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	since, err := apiQueryInt(query, "since", 0, math.MaxInt32)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := apiQueryInt(query, "limit", apiEventsPage, apiEventsPageMax)
+	if err != nil || limit == 0 {
+		writeAPIError(w, http.StatusBadRequest,
+			fmt.Sprintf("limit must be 1 to %d", apiEventsPageMax))
+		return
+	}
+	v, ok := apiCaller(w, r)
+	if !ok {
+		return
+	}
+	// one past the page says whether there's more
+	events, err := queries.EventListSince(r.Context(), sqlc.EventListSinceParams{
+		GameID:    v.Game.ID,
+		ID:        int32(since),
+		MaxEvents: pgInt(int32(limit + 1)),
+	})
+	if err != nil {
+		log.Error("list events", "error", err, "game_id", v.Game.ID)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	body := apiEvents{NextSince: int32(since)}
+	if len(events) > limit {
+		events, body.More = events[:limit], true
+	}
+	body.Events = make([]apiEvent, 0, len(events))
+	for _, e := range events {
+		out := apiEvent{
+			ID:       e.ID,
+			Type:     e.EventType,
+			Actor:    e.ActorName.String,
+			Target:   e.TargetName.String,
+			Card:     e.CardFront,
+			CardType: e.CardType,
+			Undone:   e.UndoneType,
+		}
+		if e.CardFlipped && e.CardBack != "" {
+			out.Card = e.CardBack
+		}
+		if e.PointsDelta.Valid {
+			out.PointsDelta = &e.PointsDelta.Int32
+		}
+		if e.InfractionAffirmed.Valid {
+			out.Affirmed = &e.InfractionAffirmed.Bool
+		}
+		body.Events = append(body.Events, out)
+		body.NextSince = e.ID
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// apiQueryInt reads query parameter name as an integer from 0 to max, or def
+// when it's absent.
+func apiQueryInt(query url.Values, name string, def, max int) (int, error) {
+	s := query.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > max {
+		return 0, fmt.Errorf("%s must be 0 to %d", name, max)
+	}
+	return n, nil
+}
+
+// apiActionHandler carries out an action from a JSON body. It hands the
+// action to actionHandler as the form post the page would make, then turns
+// the answer into JSON: an error stays an error, a notice (the page's way of
+// refusing) becomes a 409, and what's left is success.
+func apiActionHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("game_id")
+	name := r.PathValue("action")
+	log := log.With("handler", "apiActionHandler", "game_id", gameID, "action", name)
+	if !apiJSON(w, r) {
+		return
+	}
+	var action *apiAction
+	for i := range apiActions {
+		if apiActions[i].Name == name {
+			action = &apiActions[i]
+		}
+	}
+	if action == nil {
+		writeAPIError(w, http.StatusNotFound, ErrActionInvalid.Error())
+		return
+	}
+	params := reflect.New(reflect.TypeOf(action.Params))
+	if !apiDecode(w, r, name, params.Interface()) {
+		return
+	}
+
+	inner := r.Clone(r.Context())
+	inner.URL.Path = fmt.Sprintf("/%s/action/%s", gameID, name)
+	inner.URL.RawQuery = formValues(params.Elem()).Encode()
+	inner.Body = http.NoBody
+	inner.ContentLength = 0
+	inner.Header.Del("Content-Type")
+	rec := &apiRecorder{header: make(http.Header), status: http.StatusOK}
+	actionHandler(rec, inner)
+
+	if rec.status >= http.StatusBadRequest {
+		writeAPIError(w, rec.status, strings.TrimSpace(rec.body.String()))
+		return
+	}
+	signals := htmxTriggers(rec.header.Get("HX-Trigger"))
+	if notice, ok := signals["notice"]; ok {
+		var message string
+		json.Unmarshal(notice, &message)
+		log.Info("action refused", "notice", message)
+		writeAPICodeError(w, http.StatusConflict, "refused", message)
+		return
+	}
+	if _, ok := signals["confirmStart"]; ok {
+		writeAPICodeError(w, http.StatusConflict, "confirm_required",
+			"The game has fewer players than it recommends; send confirm to start anyway.")
+		return
+	}
+	delete(signals, "refreshTable") // only the page redraws
+	writeJSON(w, http.StatusOK, apiActionResult{OK: true, Signals: signals})
+}
+
+// apiJSON turns away a post that isn't application/json, which a page on
+// another site can't send without the browser asking first; that stands in
+// for the csrf token.
+func apiJSON(w http.ResponseWriter, r *http.Request) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, "posts take application/json")
+		return false
+	}
+	return true
+}
+
+// apiDecode reads a JSON body, which may be empty, into params, answering
+// with an error itself when it's malformed or names an unknown field.
+func apiDecode(w http.ResponseWriter, r *http.Request, what string, params any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiBodyMax))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(params); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s body: %v", what, err))
+		return false
+	}
+	return true
+}
+
+// apiCreateHandler creates a game, by way of createHandler, answering with
+// its id.
+func apiCreateHandler(w http.ResponseWriter, r *http.Request) {
+	log := log.With("handler", "apiCreateHandler")
+	if !apiJSON(w, r) || !apiDecode(w, r, "create", &apiNoParams{}) {
+		return
+	}
+	inner := r.Clone(r.Context())
+	inner.URL.Path = "/create"
+	inner.Body = http.NoBody
+	inner.ContentLength = 0
+	rec := &apiRecorder{header: make(http.Header), status: http.StatusOK}
+	createHandler(rec, inner)
+
+	if rec.status >= http.StatusBadRequest {
+		writeAPIError(w, rec.status, strings.TrimSpace(rec.body.String()))
+		return
+	}
+	gameID, ok := strings.CutSuffix(strings.TrimPrefix(rec.header.Get("Location"), "/"), "/join")
+	if !ok {
+		log.Error("game not created", "location", rec.header.Get("Location"))
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	log.Info("game created by api", "game_id", gameID)
+	writeJSON(w, http.StatusOK, apiCreated{GameID: gameID})
+}
+
+// joinStatus is the status each join page alert answers with.
+var joinStatus = map[string]int{
+	alertNotFound:   http.StatusNotFound,
+	alertOver:       http.StatusGone,
+	alertInProgress: http.StatusConflict,
+	alertNameTaken:  http.StatusConflict,
+	alertError:      http.StatusInternalServerError,
+}
+
+// apiJoinHandler joins a game from a JSON body. Like an action, it hands the
+// join to joinHandler as the form post the join page makes, then turns the
+// redirect it answers with into JSON: an alert becomes an error, and a new
+// session becomes the player it's for, with a bearer token minted for them.
+// The session cookie is passed on too.
+func apiJoinHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("game_id")
+	log := log.With("handler", "apiJoinHandler", "game_id", gameID)
+	if !apiJSON(w, r) {
+		return
+	}
+	var params apiJoinParams
+	if !apiDecode(w, r, "join", &params) {
+		return
+	}
+
+	inner := r.Clone(r.Context())
+	inner.URL.Path = fmt.Sprintf("/%s/join", gameID)
+	inner.URL.RawQuery = formValues(reflect.ValueOf(params)).Encode()
+	inner.Body = http.NoBody
+	inner.ContentLength = 0
+	inner.Header.Del("Content-Type")
+	rec := &apiRecorder{header: make(http.Header), status: http.StatusOK}
+	joinHandler(rec, inner)
+
+	if rec.status >= http.StatusBadRequest {
+		writeAPIError(w, rec.status, strings.TrimSpace(rec.body.String()))
+		return
+	}
+	location, err := url.Parse(rec.header.Get("Location"))
+	if err != nil {
+		log.Error("parse join redirect", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	if code := location.Query().Get("alert"); code != "" {
+		status, ok := joinStatus[code]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		writeAPIError(w, status, alertMessages[code])
+		return
+	}
+	var session *http.Cookie
+	for _, c := range (&http.Response{Header: rec.header}).Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil {
+		// joinHandler bounced a caller already in the game
+		writeAPIError(w, http.StatusConflict, "already in this game")
+		return
+	}
+	id, _, _ := strings.Cut(session.Value, ":")
+	playerID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		log.Error("parse joined session", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	tokenID, token, err := mintToken(r.Context(), gameID, int32(playerID))
+	if err != nil {
+		log.Error("mint token", "error", err, "player_id", playerID)
+		writeAPIError(w, http.StatusInternalServerError, "server error")
+		return
+	}
+	log.Info("joined by api", "player_id", playerID, "spectator", params.Spectate, "token_id", tokenID)
+	http.SetCookie(w, session)
+	writeJSON(w, http.StatusOK, apiJoined{
+		GameID:    gameID,
+		PlayerID:  int32(playerID),
+		Spectator: params.Spectate,
+		TokenID:   tokenID,
+		Token:     token,
+	})
+}
+
+// apiRecorder holds a page handler's answer for the API handler replaying
+// it to translate.
+type apiRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *apiRecorder) Header() http.Header         { return rec.header }
+func (rec *apiRecorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+func (rec *apiRecorder) WriteHeader(status int)      { rec.status = status }
+
+// htmxTriggers parses an HX-Trigger header: a JSON object of events and
+// their details, or a bare comma-separated list of event names.
+func htmxTriggers(header string) map[string]json.RawMessage {
+	triggers := make(map[string]json.RawMessage)
+	if header == "" {
+		return triggers
+	}
+	if strings.HasPrefix(header, "{") {
+		if err := json.Unmarshal([]byte(header), &triggers); err == nil {
+			return triggers
+		}
+	}
+	for _, name := range strings.Split(header, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			triggers[name] = json.RawMessage("null")
+		}
+	}
+	return triggers
+}
+
+// formValues turns an action's params into the form values actionHandler
+// reads: a field is named by its form tag, else its json name; a nil
+// pointer, empty string or false is left out, a true is "1", and a slice
+// repeats its name.
+func formValues(params reflect.Value) url.Values {
+	values := make(url.Values)
+	t := params.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if form := field.Tag.Get("form"); form != "" {
+			name = form
+		}
+		v := params.Field(i)
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Slice:
+			for j := range v.Len() {
+				values.Add(name, fmt.Sprint(v.Index(j).Interface()))
+			}
+		case reflect.Bool:
+			if v.Bool() {
+				values.Set(name, "1")
+			}
+		case reflect.String:
+			if v.String() != "" {
+				values.Set(name, v.String())
+			}
+		default:
+			values.Set(name, fmt.Sprint(v.Interface()))
+		}
+	}
+	return values
+}
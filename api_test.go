@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grackleclub/rulette/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormValues(t *testing.T) {
+	amount := int32(3)
+	tests := []struct {
+		params any
+		want   string
+	}{
+		{apiPacksParams{Packs: []string{"core", "spicy"}}, "pack=core&pack=spicy"},
+		{apiDecideParams{InfractionID: 7, Verdict: "affirm", Amount: &amount}, "amount=3&infraction_id=7&verdict=affirm"},
+		{apiDecideParams{InfractionID: 7, Verdict: "absolve"}, "infraction_id=7&verdict=absolve"},
+		{apiStartParams{Confirm: true}, "confirm=1"},
+		{apiStartParams{}, ""},
+		{apiKickParams{PlayerID: 4}, "player_id=4"},
+		{apiDeckParams{}, ""},
+		{apiJoinParams{Username: "Bot", Spectate: true}, "spectate=1&username=Bot"},
+		{apiRevokeParams{TokenID: &amount}, "token_id=3"},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, formValues(reflect.ValueOf(tc.params)).Encode(), "%#v", tc.params)
+	}
+}
+
+func TestHTMXTriggers(t *testing.T) {
+	require.Empty(t, htmxTriggers(""))
+	require.Equal(t, map[string]json.RawMessage{"refreshTable": json.RawMessage("null")},
+		htmxTriggers("refreshTable"))
+	got := htmxTriggers(`{"notice":"Wait your turn.","refreshTable":true}`)
+	require.JSONEq(t, `"Wait your turn."`, string(got["notice"]))
+	require.Contains(t, got, "refreshTable")
+}
+
+func TestAPIActionRejects(t *testing.T) {
+	initLogger(nil)
+	mux := http.NewServeMux()
+	registerAPI(mux, func(h http.Handler) http.Handler { return h })
+	post := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, apiPrefix+"/games/abc123/"+path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	tests := []struct {
+		name   string
+		w      *httptest.ResponseRecorder
+		status int
+	}{
+		{"form post", post("actions/spin", "application/x-www-form-urlencoded", ""), http.StatusUnsupportedMediaType},
+		{"unknown action", post("actions/dance", "application/json", "{}"), http.StatusNotFound},
+		{"unknown field", post("actions/accuse", "application/json", `{"defendant":1}`), http.StatusBadRequest},
+		{"wrong type", post("actions/accuse", "application/json", `{"defendant_id":"one"}`), http.StatusBadRequest},
+		{"form join", post("join", "application/x-www-form-urlencoded", "username=Bot"), http.StatusUnsupportedMediaType},
+		{"join unknown field", post("join", "application/json", `{"name":"Bot"}`), http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.status, tc.w.Code)
+			require.Equal(t, "application/json", tc.w.Header().Get("Content-Type"))
+			var body apiError
+			require.NoError(t, json.Unmarshal(tc.w.Body.Bytes(), &body))
+			require.Equal(t, tc.status, body.Error.Status)
+			require.NotEmpty(t, body.Error.Code)
+			require.NotEmpty(t, body.Error.Message)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/games/abc123/actions/spin", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"method_not_allowed"`)
+}
+
+func TestOpenAPI(t *testing.T) {
+	var doc struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(openAPIDoc(), &doc))
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	for _, route := range apiRoutes {
+		require.Contains(t, doc.Paths[apiPrefix+route.Path], strings.ToLower(route.Method), route.Path)
+	}
+	for _, action := range apiActions {
+		require.Contains(t, doc.Paths, apiPrefix+"/games/{game_id}/actions/"+action.Name)
+	}
+	for _, name := range []string{"Game", "Players", "Events", "Error", "ActionResult", "AccuseParams", "JoinParams", "Joined"} {
+		require.Contains(t, doc.Components.Schemas, name)
+	}
+
+	// the settings body names every setting the form takes
+	fields := make(map[string]bool)
+	settings := reflect.TypeFor[apiSettingsParams]()
+	for i := range settings.NumField() {
+		field := settings.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	for _, setting := range gameSettings {
+		require.True(t, fields[setting.Field], "settings body lacks %s", setting.Field)
+	}
+}
+
+// jsonFields names a struct's fields as JSON does, with their options.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		fields = append(fields, t.Field(i).Tag.Get("json"))
+	}
+	return fields
+}
+
+func TestClientTypes(t *testing.T) {
+	pairs := []struct{ api, client any }{
+		{apiGame{}, client.Game{}},
+		{apiPlayer{}, client.Player{}},
+		{apiPlayers{}, client.Players{}},
+		{apiCard{}, client.Card{}},
+		{apiHand{}, client.Hand{}},
+		{apiWheelSlot{}, client.WheelSlot{}},
+		{apiWheel{}, client.Wheel{}},
+		{apiInfraction{}, client.Infraction{}},
+		{apiEvent{}, client.Event{}},
+		{apiEvents{}, client.Events{}},
+		{gameSummary{}, client.Summary{}},
+		{summaryPlayer{}, client.SummaryPlayer{}},
+		{award{}, client.Award{}},
+		{apiJoined{}, client.Joined{}},
+		{apiSettingsParams{}, client.Settings{}},
+	}
+	for _, pair := range pairs {
+		api, c := reflect.TypeOf(pair.api), reflect.TypeOf(pair.client)
+		require.Equal(t, jsonFields(api), jsonFields(c), "client.%s has drifted from %s", c.Name(), api.Name())
+	}
+	require.Equal(t, []string{string(roleHost), string(roleTurn), string(rolePlayer), string(roleSpectator)},
+		[]string{client.RoleHost, client.RoleTurn, client.RolePlayer, client.RoleSpectator})
+	require.Equal(t, []int{stateCreated, stateInviting, stateReady, stateTurn, statePending, stateChallenge, statePrompt, stateEnding, stateOver, stateWriting},
+		[]int{client.StateCreated, client.StateInviting, client.StateReady, client.StateTurn, client.StatePending, client.StateChallenge, client.StatePrompt, client.StateEnding, client.StateOver, client.StateWriting})
+}
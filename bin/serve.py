@@ -14,6 +14,9 @@ def main():
     with open(cookie_file) as f:
         for line in f:
             line = line.strip()
+            # curl marks HttpOnly cookies with a prefix that reads as a comment
+            if line.startswith('#HttpOnly_'):
+                line = line[len('#HttpOnly_'):]
             if not line or line.startswith('#'):
                 continue
             parts = line.split('\t')
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// botsMax caps the bots one game seats, so a solo host still plays a game
+// and not a spectacle.
+const botsMax = 6
+
+// botClock plays the bots' turns until ctx is done: every botClockInterval
+// each bot whose turn it is makes one move. The host still judges prompts
+// and accusations, so a bot only ever spins, acknowledges, or spends a
+// modifier. Every instance runs one, and a lock per game keeps two from
+// playing the same game's bots at once; should an acknowledge still land
+// twice, from state read before the first, the second is refused, since a
+// turn only advances from the seat that holds it.
+func botClock(ctx context.Context) {
+	ticker := time.NewTicker(botClockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			botClockTick(ctx)
+		}
+	}
+}
+
+// botClockTick makes the next move of every bot whose turn it is.
+func botClockTick(ctx context.Context) {
+	turns, err := queries.GamesBotTurn(ctx)
+	if err != nil {
+		log.Error("list bot turns", "error", err)
+		return
+	}
+	for _, turn := range turns {
+		if err := botTurn(ctx, turn); err != nil {
+			log.Error("play bot turn", "error", err, "game_id", turn.GameID, "player_id", turn.PlayerID)
+		}
+	}
+}
+
+// botTurn makes the bot's next move in its game, if it has one and no other
+// instance is making it. The game's bot lock is held until the move is made.
+func botTurn(ctx context.Context, turn sqlc.GamesBotTurnRow) error {
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	locked, err := queries.WithTx(tx).GameBotLock(ctx, turn.GameID)
+	if err != nil {
+		return fmt.Errorf("lock game: %w", err)
+	}
+	if !locked {
+		log.Debug("bot turn under way elsewhere", "game_id", turn.GameID, "player_id", turn.PlayerID)
+		return nil
+	}
+	s, err := stateFromCacheOrDB(ctx, &cache, turn.GameID)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	var effect string
+	if s.Game.StateID == statePending {
+		spin, err := queries.SpinPendingModifier(ctx, turn.GameID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("check pending modifier: %w", err)
+		}
+		effect = spin.ModifierEffect.String
+	}
+	action, params := botMove(&s, turn.PlayerID, effect)
+	if action == "" {
+		log.Debug("bot has no move", "game_id", turn.GameID, "player_id", turn.PlayerID, "state_id", s.Game.StateID)
+		return nil
+	}
+	return botAct(ctx, turn, action, params)
+}
+
+// botMove picks the bot's move: on its turn it spins, then acknowledges the
+// rule drawn; owed a modifier's target it spends it on the first rule it
+// holds, cloning or transferring that rule to the leader, the player with
+// the most points. No move ("") leaves the turn to the turn clock.
+func botMove(s *state, botID int32, effect string) (string, url.Values) {
+	switch s.Game.StateID {
+	case stateTurn:
+		if s.AwaitingAck {
+			return "acknowledge", nil
+		}
+		return "spin", nil
+	case statePending:
+		var cardID int32
+		for _, c := range s.CardsPlayers {
+			if c.PlayerID.Int32 == botID && c.Type == "rule" {
+				cardID = c.ID
+				break
+			}
+		}
+		if cardID == 0 {
+			return "", nil
+		}
+		params := url.Values{"game_card_id": {strconv.Itoa(int(cardID))}}
+		switch effect {
+		case modFlip, modShred:
+		case modClone, modTransfer:
+			params.Set("target_player_id", strconv.Itoa(int(botTarget(s, botID))))
+		default:
+			return "", nil
+		}
+		return effect, params
+	}
+	return "", nil
+}
+
+// botTarget returns the leader among the bot's opponents, seat order
+// breaking ties, or the bot itself when it plays alone.
+func botTarget(s *state, botID int32) int32 {
+	target, best := botID, int32(0)
+	for _, p := range s.Players {
+		if p.PlayerID == botID || p.Initiative.Int32 == 0 {
+			continue
+		}
+		if target == botID || p.Points.Int32 > best {
+			target, best = p.PlayerID, p.Points.Int32
+		}
+	}
+	return target
+}
+
+// botAct carries out the bot's move through actionHandler, as the bot, so it
+// is held to every rule a player is.
+func botAct(ctx context.Context, turn sqlc.GamesBotTurnRow, action string, params url.Values) error {
+	ctx = context.WithValue(ctx, bearerKey{}, bearer{
+		playerID: turn.PlayerID,
+		keyHash:  turn.SessionKey.String,
+	})
+	target := "/" + turn.GameID + "/action/" + action
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return fmt.Errorf("build %s: %w", action, err)
+	}
+	rec := &apiRecorder{header: make(http.Header), status: http.StatusOK}
+	actionHandler(rec, r)
+	if rec.status >= http.StatusBadRequest {
+		return fmt.Errorf("%s: %d %s", action, rec.status, strings.TrimSpace(rec.body.String()))
+	}
+	log.Info("bot moved", "game_id", turn.GameID, "player_id", turn.PlayerID, "action", action)
+	return nil
+}
+
+// botName returns the first of "Bot 1", "Bot 2", ... no one at the table,
+// seated or watching, already goes by.
+func botName(s *state) string {
+	names := make(map[string]bool, len(s.Players)+len(s.Spectators))
+	for _, p := range s.Players {
+		names[p.Name] = true
+	}
+	for _, sp := range s.Spectators {
+		names[sp.Name] = true
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("Bot %d", n)
+		if !names[name] {
+			return name
+		}
+	}
+}
+
+// botAction seats a bot at the end of the table, for the host, before the
+// start. The bot's session key is made and hashed here and never handed
+// out: botClock alone acts as it.
+func botAction(w http.ResponseWriter, r *http.Request, log *slog.Logger, s *state, cookieKey string) {
+	gameID := s.Game.ID
+	if !s.isHost(cookieKey) {
+		log.Warn("non-host attempted to add a bot", "game_id", gameID)
+		http.Error(w, "only the host can add bots", http.StatusForbidden)
+		return
+	}
+	var bots int
+	for _, p := range s.Players {
+		if p.Bot {
+			bots++
+		}
+	}
+	if bots >= botsMax {
+		log.Info("bot refused", "bots", bots)
+		w.Header().Set("HX-Trigger", `{"notice":`+strconv.Quote(fmt.Sprintf("A game seats at most %d bots.", botsMax))+`}`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	name := botName(s)
+	id, err := queries.PlayerCreate(r.Context(), name)
+	if err != nil {
+		log.Error("create bot player", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		log.Error("make secret", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	err = queries.GameBotCreate(r.Context(), sqlc.GameBotCreateParams{
+		GameID:     gameID,
+		PlayerID:   id,
+		SessionKey: pgtype.Text{String: hashSessionKey(hex.EncodeToString(secret)), Valid: true},
+	})
+	if err != nil {
+		log.Error("add bot to game", "error", err, "player_id", id)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("bot added", "player_id", id, "name", name)
+	invalidate(r.Context(), gameID)
+	w.Header().Set("HX-Trigger", "refreshTable")
+	w.WriteHeader(http.StatusOK)
+}
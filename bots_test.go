@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBotMove(t *testing.T) {
+	s := state{
+		Game: sqlc.GameStateRow{StateID: stateTurn, InitiativeCurrent: pgInt(2)},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 10, Initiative: pgInt(0), Points: pgInt(99)},
+			{PlayerID: 11, Initiative: pgInt(1), Points: pgInt(20)},
+			{PlayerID: 12, Initiative: pgInt(2), Points: pgInt(20), Bot: true},
+			{PlayerID: 13, Initiative: pgInt(3), Points: pgInt(25)},
+		},
+		CardsPlayers: []sqlc.GameCardsPlayerViewRow{
+			{ID: 1, PlayerID: pgInt(11), Type: "rule"},
+			{ID: 2, PlayerID: pgInt(12), Type: "modifier"},
+			{ID: 3, PlayerID: pgInt(12), Type: "rule"},
+			{ID: 4, PlayerID: pgInt(12), Type: "rule"},
+		},
+	}
+
+	action, params := botMove(&s, 12, "")
+	require.Equal(t, "spin", action)
+	require.Empty(t, params)
+
+	s.AwaitingAck = true
+	action, _ = botMove(&s, 12, "")
+	require.Equal(t, "acknowledge", action)
+
+	s.Game.StateID = statePending
+	for effect, want := range map[string]url.Values{
+		modFlip:     {"game_card_id": {"3"}},
+		modShred:    {"game_card_id": {"3"}},
+		modClone:    {"game_card_id": {"3"}, "target_player_id": {"13"}},
+		modTransfer: {"game_card_id": {"3"}, "target_player_id": {"13"}},
+	} {
+		action, params := botMove(&s, 12, effect)
+		require.Equal(t, effect, action)
+		require.Equal(t, want, params, effect)
+	}
+	action, _ = botMove(&s, 12, "")
+	require.Empty(t, action, "no modifier pending")
+
+	s.CardsPlayers = s.CardsPlayers[:2]
+	action, _ = botMove(&s, 12, modFlip)
+	require.Empty(t, action, "no rule to spend it on")
+
+	for _, id := range []int32{stateReady, statePrompt, stateChallenge} {
+		s.Game.StateID = id
+		action, _ = botMove(&s, 12, "")
+		require.Empty(t, action, "the host's to judge, or paused: state %d", id)
+	}
+}
+
+func TestBotTarget(t *testing.T) {
+	s := state{Players: []sqlc.GamePlayerPointsRow{
+		{PlayerID: 10, Initiative: pgInt(0), Points: pgInt(99)},
+		{PlayerID: 12, Initiative: pgInt(1), Points: pgInt(20), Bot: true},
+	}}
+	require.Equal(t, int32(12), botTarget(&s, 12), "alone, itself")
+
+	s.Players = append(s.Players,
+		sqlc.GamePlayerPointsRow{PlayerID: 13, Initiative: pgInt(2), Points: pgInt(-5)},
+		sqlc.GamePlayerPointsRow{PlayerID: 14, Initiative: pgInt(3), Points: pgInt(-5)},
+	)
+	require.Equal(t, int32(13), botTarget(&s, 12), "seat order breaks ties, below zero too")
+
+	s.Players[3].Points = pgInt(0)
+	require.Equal(t, int32(14), botTarget(&s, 12))
+}
+
+func TestBotName(t *testing.T) {
+	s := state{
+		Players:    []sqlc.GamePlayerPointsRow{{Name: "Bot 1"}, {Name: "Bot 3"}},
+		Spectators: []sqlc.GameSpectatorsRow{{Name: "Bot 2"}},
+	}
+	require.Equal(t, "Bot 4", botName(&s))
+	s.Players = s.Players[1:]
+	require.Equal(t, "Bot 1", botName(&s))
+}
+
+func TestCardsOutstandingBots(t *testing.T) {
+	s := state{
+		Game: sqlc.GameStateRow{CardsPerPlayer: 2},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 10, Initiative: pgInt(0)},
+			{PlayerID: 11, Initiative: pgInt(1), Bot: true},
+		},
+	}
+	require.Equal(t, 2, s.cardsOutstanding(), "bots write no cards")
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // stateFromCacheOrDB returns the current state of the game specified by gameID,
-// drawing from cache if newer than maxCacheAge, otherwise fetching from the database.
+// drawing from the local cache if newer than maxCacheAge, then from the shared
+// game_cache table (filled by any instance, unexpired for sharedCacheAge),
+// otherwise fetching from the database and filling both tiers.
 func stateFromCacheOrDB(ctx context.Context, cache *sync.Map, gameID string) (state, error) {
 	ctx, span := otel.Tracer(otelScope).Start(ctx, "cache.lookup")
 	defer span.End()
@@ -26,21 +33,39 @@ func stateFromCacheOrDB(ctx context.Context, cache *sync.Map, gameID string) (st
 		cacheAge := time.Since(cachedState.Updated)
 		if cacheAge < maxCacheAge {
 			log.Debug("cache hit", "cache_age", cacheAge)
-			span.SetAttributes(attribute.Bool("cache.hit", true))
+			span.SetAttributes(
+				attribute.Bool("cache.hit", true),
+				attribute.String("cache.tier", "local"),
+			)
 			if cacheHits != nil {
-				cacheHits.Add(ctx, 1)
+				cacheHits.Add(ctx, 1, metric.WithAttributes(attribute.String("cache.tier", "local")))
 			}
 			return *cachedState, nil
 		}
 		log.Debug("cache stale", "cache_age", cacheAge)
 	}
 
+	// shared cache hit
+	if sharedState, ok := stateFromSharedCache(ctx, gameID); ok {
+		log.Debug("shared cache hit")
+		span.SetAttributes(
+			attribute.Bool("cache.hit", true),
+			attribute.String("cache.tier", "shared"),
+		)
+		if cacheHits != nil {
+			cacheHits.Add(ctx, 1, metric.WithAttributes(attribute.String("cache.tier", "shared")))
+		}
+		cache.Store(gameID, &sharedState)
+		return sharedState, nil
+	}
+
 	// cache miss
 	log.Debug("cache miss")
 	span.SetAttributes(attribute.Bool("cache.hit", false))
 	if cacheMisses != nil {
 		cacheMisses.Add(ctx, 1)
 	}
+	version := sharedCacheVersion(ctx, gameID)
 	stateFresh, err := fetchStateFromDB(ctx, gameID)
 	if err != nil {
 		return state{}, err
@@ -51,17 +76,96 @@ func stateFromCacheOrDB(ctx context.Context, cache *sync.Map, gameID string) (st
 	stateFresh.Config = make(map[string]string)
 	stateFresh.Config["refresh"] = defaultFrontendRefresh
 
-	// Update the cache
+	// Update the cache, unless an invalidation landed mid-fetch: then the
+	// state is already stale, fine to answer with but not to keep
+	if !storeSharedCache(ctx, gameID, version, stateFresh) {
+		log.Debug("invalidated during fetch, not cached", "game_id", gameID)
+		return stateFresh, nil
+	}
 	cache.Store(gameID, &stateFresh)
 	log.Debug("cache updated", "game_id", gameID)
 
 	return stateFresh, nil
 }
 
-// cacheJanitor periodically evicts cache entries older than cacheTTL.
-// Runs until ctx is cancelled. The cache has no other eviction policy,
-// so without this, entries accumulate indefinitely (game ended, last
-// player left, etc.) and grow process memory unbounded.
+// stateFromSharedCache loads a game's state from the game_cache table, which
+// any instance may have filled. It reports false on a miss, when the shared
+// tier is disabled (sharedCacheAge of zero), or on any error, which is logged:
+// the caller just falls through to the database.
+func stateFromSharedCache(ctx context.Context, gameID string) (state, bool) {
+	if sharedCacheAge <= 0 {
+		return state{}, false
+	}
+	ctx, span := otel.Tracer(otelScope).Start(ctx, "db.CacheGet")
+	defer span.End()
+	value, err := queries.CacheGet(ctx, gameID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state{}, false
+	}
+	if err != nil {
+		log.Warn("read shared cache", "error", err, "game_id", gameID)
+		return state{}, false
+	}
+	var s state
+	if err := json.Unmarshal(value, &s); err != nil {
+		log.Warn("decode shared cache", "error", err, "game_id", gameID)
+		return state{}, false
+	}
+	return s, true
+}
+
+// sharedCacheVersion reads the game's shared cache version ahead of a fetch,
+// for storeSharedCache to store the result at. Zero when the shared tier is
+// disabled, or on an error, which is logged; storing at a wrong version just
+// stores nothing.
+func sharedCacheVersion(ctx context.Context, gameID string) int64 {
+	if sharedCacheAge <= 0 {
+		return 0
+	}
+	ctx, span := otel.Tracer(otelScope).Start(ctx, "db.CacheVersion")
+	defer span.End()
+	version, err := queries.CacheVersion(ctx, gameID)
+	if err != nil {
+		log.Warn("read shared cache version", "error", err, "game_id", gameID)
+	}
+	return version
+}
+
+// storeSharedCache writes a freshly fetched state to the game_cache table for
+// sharedCacheAge, so other instances can skip the full fetch. It stores
+// nothing, and reports false, when the game was invalidated since version was
+// read: the state is stale, and its fetch mustn't outlive the invalidation.
+// Other failures are logged and otherwise ignored.
+func storeSharedCache(ctx context.Context, gameID string, version int64, s state) bool {
+	if sharedCacheAge <= 0 {
+		return true
+	}
+	value, err := json.Marshal(s)
+	if err != nil {
+		log.Warn("encode shared cache", "error", err, "game_id", gameID)
+		return true
+	}
+	ctx, span := otel.Tracer(otelScope).Start(ctx, "db.CacheSet")
+	defer span.End()
+	stored, err := queries.CacheSet(ctx, sqlc.CacheSetParams{
+		GameID:  gameID,
+		Value:   value,
+		Version: version,
+		TtlMs:   int32(sharedCacheAge.Milliseconds()),
+	})
+	if err != nil {
+		log.Warn("write shared cache", "error", err, "game_id", gameID)
+		return true
+	}
+	return stored > 0
+}
+
+// cacheJanitor periodically evicts cache entries older than cacheTTL, and
+// sweeps expired rows from the shared game_cache table. Runs until ctx is
+// cancelled. The local cache has no other eviction policy, so without this,
+// entries accumulate indefinitely (game ended, last player left, etc.) and
+// grow process memory unbounded. Expired shared rows are already ignored by
+// reads; the sweep just keeps the table small.
 func cacheJanitor(ctx context.Context, cache *sync.Map) {
 	tick := time.NewTicker(cacheJanitorInterval)
 	defer tick.Stop()
@@ -85,10 +189,92 @@ func cacheJanitor(ctx context.Context, cache *sync.Map) {
 				}
 				return true
 			})
+			if err := queries.CacheClean(ctx); err != nil {
+				log.Warn("clean shared cache", "error", err)
+			}
 		}
 	}
 }
 
+// invalidate drops the cached state for a game (locally and in the shared
+// game_cache table) after a mutation, tells any open streams to refetch, and
+// broadcasts the eviction to other instances over cacheChannel, unless
+// cacheBroadcast is off. Call it once the change is committed. The broadcast is
+// best-effort: a failure is logged, and peers fall back to maxCacheAge.
+func invalidate(ctx context.Context, gameID string) {
+	cache.Delete(gameID)
+	if err := queries.CacheClear(ctx, gameID); err != nil {
+		log.Error("clear shared cache", "error", err, "game_id", gameID)
+	}
+	streams.publish(gameID)
+	if !cacheBroadcast {
+		return
+	}
+	if err := queries.CacheInvalidate(ctx, instanceID+":"+gameID); err != nil {
+		log.Error("broadcast cache invalidation", "error", err, "game_id", gameID)
+	}
+}
+
+// cacheListener subscribes to cacheChannel on a dedicated connection and
+// evicts (and notifies the streams of) every game another instance reports
+// changed. Runs until ctx is cancelled. If the connection drops, it
+// reconnects after cacheListenRetry and flushes the whole local cache, since
+// any invalidations sent in the meantime were missed.
+func cacheListener(ctx context.Context, pool *pgxpool.Pool, cache *sync.Map) {
+	for {
+		err := listenOnce(ctx, pool, cache)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error("cache listener disconnected, retrying",
+			"error", err,
+			"retry_in", cacheListenRetry,
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cacheListenRetry):
+		}
+		cache.Range(func(k, _ any) bool {
+			cache.Delete(k)
+			return true
+		})
+	}
+}
+
+// listenOnce holds one LISTEN session open until it fails.
+func listenOnce(ctx context.Context, pool *pgxpool.Pool, cache *sync.Map) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listen connection: %w", err)
+	}
+	// a LISTENing connection must not go back to the pool, where another
+	// caller would inherit the subscription.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+cacheChannel); err != nil {
+		return fmt.Errorf("listen on %s: %w", cacheChannel, err)
+	}
+	log.Info("cache listener subscribed", "channel", cacheChannel)
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		origin, gameID, ok := strings.Cut(n.Payload, ":")
+		if !ok {
+			log.Warn("malformed cache invalidation", "payload", n.Payload)
+			continue
+		}
+		if origin == instanceID {
+			continue // our own broadcast, already evicted locally
+		}
+		cache.Delete(gameID)
+		streams.publish(gameID)
+		log.Debug("cache evicted by peer", "game_id", gameID, "origin", origin)
+	}
+}
+
 // fetchStateFromDB retrieves the game state and players from the database for the given gameID.
 func fetchStateFromDB(ctx context.Context, gameID string) (state, error) {
 	tr := otel.Tracer(otelScope)
@@ -108,6 +294,12 @@ func fetchStateFromDB(ctx context.Context, gameID string) (state, error) {
 	if err != nil {
 		return state{}, ErrFetchPlayers
 	}
+	spctx, spspan := tr.Start(ctx, "db.GameSpectators")
+	spectators, err := queries.GameSpectators(spctx, gameID)
+	spspan.End()
+	if err != nil {
+		return state{}, fmt.Errorf("fetch spectators for game: %w", err)
+	}
 	cpctx, cpspan := tr.Start(ctx, "db.GameCardsPlayerView")
 	cardsPlayers, err := queries.GameCardsPlayerView(cpctx, gameID)
 	cpspan.End()
@@ -151,6 +343,59 @@ func fetchStateFromDB(ctx context.Context, gameID string) (state, error) {
 		}
 	}
 
+	// the turn clock runs mid-turn and while a modifier choice is owed
+	var turnDeadline time.Time
+	if game.InitiativeTimer > 0 &&
+		(game.StateID == stateTurn || game.StateID == statePending) {
+		tctx, tspan := tr.Start(ctx, "db.GameTurnSecondsLeft")
+		left, err := queries.GameTurnSecondsLeft(tctx, gameID)
+		tspan.End()
+		if err != nil {
+			return state{}, fmt.Errorf("fetch turn clock: %w", err)
+		}
+		turnDeadline = time.Now().Add(time.Duration(left * float64(time.Second)))
+	}
+
+	// only the writing phase needs each player's written card count
+	var cardsWritten []sqlc.GameCardsWrittenRow
+	if game.StateID == stateWriting {
+		wctx, wspan := tr.Start(ctx, "db.GameCardsWritten")
+		cardsWritten, err = queries.GameCardsWritten(wctx, gameID)
+		wspan.End()
+		if err != nil {
+			return state{}, fmt.Errorf("fetch written cards for game: %w", err)
+		}
+	}
+
+	// only the pregame shows the host's deck and pack pickers
+	var decks []sqlc.DecksRow
+	var packs []sqlc.GamePacksRow
+	if game.StateID == stateCreated || game.StateID == stateInviting {
+		dctx, dspan := tr.Start(ctx, "db.Decks")
+		decks, err = queries.Decks(dctx)
+		dspan.End()
+		if err != nil {
+			return state{}, fmt.Errorf("fetch decks: %w", err)
+		}
+		pctx, pspan := tr.Start(ctx, "db.GamePacks")
+		packs, err = queries.GamePacks(pctx, gameID)
+		pspan.End()
+		if err != nil {
+			return state{}, fmt.Errorf("fetch packs for game: %w", err)
+		}
+	}
+
+	// players may vote a new host only while the host is away
+	var hostVotes []sqlc.HostVotesRow
+	if game.HostAway && game.StateID != stateOver {
+		hctx, hspan := tr.Start(ctx, "db.HostVotes")
+		hostVotes, err = queries.HostVotes(hctx, gameID)
+		hspan.End()
+		if err != nil {
+			return state{}, fmt.Errorf("fetch host votes for game: %w", err)
+		}
+	}
+
 	log.Debug("fetched game state and players",
 		"player_count", len(players),
 		"game_id", gameID,
@@ -159,10 +404,16 @@ func fetchStateFromDB(ctx context.Context, gameID string) (state, error) {
 	return state{
 		Game:         game,
 		Players:      players,
+		Spectators:   spectators,
 		Updated:      time.Now().UTC(),
 		CardsWheel:   cardsWheel,
 		CardsPlayers: cardsPlayers,
 		Infractions:  infractions,
 		AwaitingAck:  awaitingAck,
+		TurnDeadline: turnDeadline,
+		CardsWritten: cardsWritten,
+		Decks:        decks,
+		Packs:        packs,
+		HostVotes:    hostVotes,
 	}, nil
 }
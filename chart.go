@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+)
+
+// the points chart's drawing area, in SVG user units; it scales to fit
+const (
+	chartWidth  = 320
+	chartHeight = 160
+	chartLeft   = 30 // room for the points labels
+	chartRight  = 8
+	chartTop    = 8
+	chartBottom = 8
+	chartSeries = 6 // line colors before they repeat
+)
+
+// pointsChart is each seated player's running points over the game, change
+// by change, as the points fragment and game-over screen draw it. Steps is
+// how many changes there were; with none there's nothing to draw.
+type pointsChart struct {
+	Width, Height int
+	Left, Right   int // the plot's x bounds
+	Steps         int
+	Lines         []chartLine
+	Markers       []chartMarker
+	Ticks         []chartTick
+	StartY        float64 // the starting balance, drawn as a baseline
+}
+
+// chartLine is one player's points, as an SVG polyline's points.
+type chartLine struct {
+	Name   string
+	Series int
+	Points string
+	Final  int32
+}
+
+// chartMarker marks one change, at the balance it left.
+type chartMarker struct {
+	X, Y   float64
+	Series int
+	Cause  string // accusation, prompt, undo or host
+	Title  string
+}
+
+// chartTick labels a balance on the points axis.
+type chartTick struct {
+	Y     float64
+	Label string
+}
+
+// pointsChartFor reads the game's points ledger and charts it.
+func pointsChartFor(ctx context.Context, v view) (*pointsChart, error) {
+	ledger, err := queries.PointChangesByGame(ctx, v.Game.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list point changes: %w", err)
+	}
+	return newPointsChart(v, ledger), nil
+}
+
+// newPointsChart charts the seated non-host players' balances from the
+// starting points through each change in ledger, a step per change. The
+// changes of those who left aren't charted, and take no step.
+func newPointsChart(v view, ledger []sqlc.PointChangesByGameRow) *pointsChart {
+	c := &pointsChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartLeft,
+		Right:  chartWidth - chartRight,
+	}
+	start := v.Game.StartingPoints
+	series := make(map[int32]int)
+	balances := make([][]int32, 0, len(v.Players)) // by series: start, then after each step
+	for _, p := range v.Players {
+		if p.Initiative.Int32 == 0 {
+			continue
+		}
+		series[p.PlayerID] = len(c.Lines)
+		c.Lines = append(c.Lines, chartLine{Name: p.Name, Series: len(c.Lines) % chartSeries})
+		balances = append(balances, []int32{start})
+	}
+	type change struct {
+		line  int
+		delta int32
+		cause string
+	}
+	var changes []change
+	lo, hi := start, start
+	for _, pc := range ledger {
+		i, ok := series[pc.PlayerID.Int32]
+		if !ok {
+			continue
+		}
+		for j := range balances {
+			now := balances[j][len(balances[j])-1]
+			if j == i {
+				now += pc.Delta
+				lo, hi = min(lo, now), max(hi, now)
+			}
+			balances[j] = append(balances[j], now)
+		}
+		changes = append(changes, change{line: i, delta: pc.Delta, cause: pointsCause(pc)})
+	}
+	c.Steps = len(changes)
+	if c.Steps == 0 {
+		return c
+	}
+	if lo == hi {
+		hi++
+	}
+
+	x := func(step int) float64 {
+		return tenth(float64(c.Left) + float64(step)*float64(c.Right-c.Left)/float64(c.Steps))
+	}
+	y := func(points int32) float64 {
+		span := float64(chartHeight - chartTop - chartBottom)
+		return tenth(float64(chartTop) + float64(hi-points)*span/float64(hi-lo))
+	}
+	c.StartY = y(start)
+	for _, points := range []int32{hi, start, lo} {
+		if len(c.Ticks) > 0 && c.Ticks[len(c.Ticks)-1].Label == strconv.Itoa(int(points)) {
+			continue
+		}
+		c.Ticks = append(c.Ticks, chartTick{Y: y(points), Label: strconv.Itoa(int(points))})
+	}
+
+	for i := range c.Lines {
+		b := balances[i]
+		var pts strings.Builder
+		fmt.Fprintf(&pts, "%s,%s", coord(x(0)), coord(y(b[0])))
+		for step := 1; step < len(b); step++ {
+			if b[step] != b[step-1] {
+				// a step: across at the old balance, then up or down
+				fmt.Fprintf(&pts, " %s,%s %s,%s", coord(x(step)), coord(y(b[step-1])), coord(x(step)), coord(y(b[step])))
+			}
+		}
+		if last := len(b) - 1; b[last] == b[last-1] {
+			fmt.Fprintf(&pts, " %s,%s", coord(x(c.Steps)), coord(y(b[last])))
+		}
+		c.Lines[i].Points = pts.String()
+		c.Lines[i].Final = b[len(b)-1]
+	}
+	for step, ch := range changes {
+		after := balances[ch.line][step+1]
+		c.Markers = append(c.Markers, chartMarker{
+			X:      x(step + 1),
+			Y:      y(after),
+			Series: c.Lines[ch.line].Series,
+			Cause:  ch.cause,
+			Title:  fmt.Sprintf("%s %+d, %s (%d)", c.Lines[ch.line].Name, ch.delta, ch.cause, after),
+		})
+	}
+	return c
+}
+
+// pointsCause names what made a points change: an affirmed accusation, a
+// prompt passed, the host's undo of either, or otherwise the host.
+func pointsCause(pc sqlc.PointChangesByGameRow) string {
+	switch {
+	case pc.Cause == "undo":
+		return "undo"
+	case pc.InfractionID.Valid || pc.Cause == "points":
+		return "accusation"
+	case pc.Cause == "prompt":
+		return "prompt"
+	}
+	return "host"
+}
+
+// tenth rounds an SVG coordinate to a tenth of a unit, plenty at the
+// chart's size.
+func tenth(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+// coord writes an SVG coordinate, already rounded by tenth.
+func coord(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointsChart(t *testing.T) {
+	v := view{
+		Game: sqlc.GameStateRow{ID: "chart", StartingPoints: 10},
+		Players: []playerView{
+			{PlayerID: 10, Name: "Host", Initiative: pgInt(0)},
+			{PlayerID: 11, Name: "Ann", Initiative: pgInt(1)},
+			{PlayerID: 12, Name: "Bo", Initiative: pgInt(2)},
+		},
+	}
+	require.Zero(t, newPointsChart(v, nil).Steps, "nothing to draw before any change")
+
+	ledger := []sqlc.PointChangesByGameRow{
+		{PlayerID: pgInt(12), Delta: -4, InfractionID: pgInt(1), Cause: "points"},
+		{PlayerID: pgInt(13), Delta: 5, Cause: "prompt"}, // left the game
+		{PlayerID: pgInt(11), Delta: 6, Cause: "prompt"},
+	}
+	c := newPointsChart(v, ledger)
+	require.Equal(t, 2, c.Steps, "a departed player's change takes no step")
+	require.Len(t, c.Lines, 2, "the host isn't charted")
+
+	// x runs 30 to 312 over two steps; y runs 8 (16 points) to 152 (6 points)
+	require.Equal(t, []chartTick{{Y: 8, Label: "16"}, {Y: 94.4, Label: "10"}, {Y: 152, Label: "6"}}, c.Ticks)
+	require.Equal(t, 94.4, c.StartY)
+	require.Equal(t, chartLine{Name: "Ann", Series: 0, Points: "30,94.4 312,94.4 312,8", Final: 16}, c.Lines[0])
+	require.Equal(t, chartLine{Name: "Bo", Series: 1, Points: "30,94.4 171,94.4 171,152 312,152", Final: 6}, c.Lines[1])
+	require.Equal(t, []chartMarker{
+		{X: 171, Y: 152, Series: 1, Cause: "accusation", Title: "Bo -4, accusation (6)"},
+		{X: 312, Y: 8, Series: 0, Cause: "prompt", Title: "Ann +6, prompt (16)"},
+	}, c.Markers)
+}
+
+func TestPointsChartSeries(t *testing.T) {
+	v := view{Game: sqlc.GameStateRow{StartingPoints: 5}}
+	var ledger []sqlc.PointChangesByGameRow
+	for id := int32(1); id <= 8; id++ {
+		v.Players = append(v.Players, playerView{PlayerID: id, Initiative: pgInt(id)})
+		ledger = append(ledger, sqlc.PointChangesByGameRow{PlayerID: pgInt(id)})
+	}
+	c := newPointsChart(v, ledger)
+	require.Equal(t, 8, c.Steps)
+	require.Equal(t, 0, c.Lines[6].Series, "colors repeat past the palette")
+	require.Len(t, c.Ticks, 2, "a flat chart still has a scale")
+	require.Equal(t, "host", c.Markers[0].Cause)
+	require.Equal(t, "undo", pointsCause(sqlc.PointChangesByGameRow{InfractionID: pgInt(1), Cause: "undo"}),
+		"a verdict's points given back are the undo's")
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// What becomes of a departing player's cards, for Leave and Kick.
+const (
+	CardsWheel = "wheel" // back on the wheel (the default)
+	CardsShred = "shred" // gone
+	CardsShare = "share" // dealt out to the players left
+)
+
+func (c *Client) act(ctx context.Context, name string, params any) error {
+	_, err := c.Action(ctx, name, params)
+	return err
+}
+
+type cardParams struct {
+	GameCardID int32 `json:"game_card_id"`
+}
+
+type cardTargetParams struct {
+	GameCardID     int32 `json:"game_card_id"`
+	TargetPlayerID int32 `json:"target_player_id"`
+}
+
+type playerParams struct {
+	PlayerID int32 `json:"player_id"`
+}
+
+// Deck picks the deck the game deals from, by id; nil picks the generic
+// deck. Host only, before the start.
+func (c *Client) Deck(ctx context.Context, deckID *int32) error {
+	return c.act(ctx, "deck", struct {
+		DeckID *int32 `json:"deck_id,omitempty"`
+	}{deckID})
+}
+
+// Packs picks the generic deck's card packs, by slug. Host only, before the
+// start.
+func (c *Client) Packs(ctx context.Context, packs ...string) error {
+	if packs == nil {
+		packs = []string{}
+	}
+	return c.act(ctx, "packs", struct {
+		Packs []string `json:"packs"`
+	}{packs})
+}
+
+// Settings are the host's settings; a nil field keeps its value.
+type Settings struct {
+	WheelSlots         *int32 `json:"wheel_slots,omitempty"`
+	CardCount          *int32 `json:"card_count,omitempty"`
+	CardsPerPlayer     *int32 `json:"cards_per_player,omitempty"`
+	StartingPoints     *int32 `json:"starting_points,omitempty"`
+	InitiativeTimer    *int32 `json:"initiative_timer,omitempty"`
+	PromptSeconds      *int32 `json:"prompt_seconds,omitempty"`
+	RecommendedPlayers *int32 `json:"recommended_players,omitempty"`
+	HostTimeout        *int32 `json:"host_timeout,omitempty"`
+}
+
+// Settings changes the game's settings. Host only, before the start.
+func (c *Client) Settings(ctx context.Context, settings Settings) error {
+	return c.act(ctx, "settings", settings)
+}
+
+// AddBot seats a bot that plays its own turns: it spins, acknowledges, and
+// spends its modifiers, leaving prompts and accusations to the host. Host
+// only, before the start; Kick removes one.
+func (c *Client) AddBot(ctx context.Context) error { return c.act(ctx, "add-bot", nil) }
+
+// Start starts the game. With fewer players than the game recommends it
+// fails with ErrConfirmRequired unless confirm is set. Host only.
+func (c *Client) Start(ctx context.Context, confirm bool) error {
+	return c.act(ctx, "start", struct {
+		Confirm bool `json:"confirm,omitempty"`
+	}{confirm})
+}
+
+// Write writes a card while players write their own: a "rule" with its
+// front and back, or a "prompt" with only a front.
+func (c *Client) Write(ctx context.Context, cardType, front, back string) error {
+	return c.act(ctx, "write", struct {
+		Type  string `json:"type"`
+		Front string `json:"front"`
+		Back  string `json:"back,omitempty"`
+	}{cardType, front, back})
+}
+
+// Close ends card writing. Host only.
+func (c *Client) Close(ctx context.Context) error { return c.act(ctx, "close", nil) }
+
+// Spin is what a spin drew: a rule (wait for Acknowledge), a prompt (a
+// challenge for the host to rule on), or a modifier (owed a target: Flip,
+// Shred, Clone or Transfer).
+type Spin struct {
+	Rule          string // the rule drawn, if one was
+	Prompt        string // the prompt drawn, if one was
+	PromptSeconds int    // how long the prompt runs
+	Modifier      bool   // a modifier was drawn
+}
+
+// Spin spins the wheel, on the client's turn.
+func (c *Client) Spin(ctx context.Context) (Spin, error) {
+	signals, err := c.Action(ctx, "spin", nil)
+	if err != nil {
+		return Spin{}, err
+	}
+	var spin Spin
+	if _, err := signals.Decode("newCard", &spin.Rule); err != nil {
+		return spin, err
+	}
+	var prompt struct {
+		Prompt string `json:"prompt"`
+		Window int    `json:"window"`
+	}
+	if _, err := signals.Decode("newPrompt", &prompt); err != nil {
+		return spin, err
+	}
+	spin.Prompt, spin.PromptSeconds = prompt.Prompt, prompt.Window
+	_, spin.Modifier = signals["loadModifier"]
+	return spin, nil
+}
+
+// Acknowledge accepts the rule drawn and ends the client's turn.
+func (c *Client) Acknowledge(ctx context.Context) error { return c.act(ctx, "acknowledge", nil) }
+
+// Advance moves past a drawn rule for the player who drew it. Host only.
+func (c *Client) Advance(ctx context.Context) error { return c.act(ctx, "advance", nil) }
+
+// Pause pauses the game. Host only.
+func (c *Client) Pause(ctx context.Context) error { return c.act(ctx, "pause", nil) }
+
+// Resume resumes a paused game. Host only.
+func (c *Client) Resume(ctx context.Context) error { return c.act(ctx, "resume", nil) }
+
+// EndGame ends a game whose deck is spent. Host only.
+func (c *Client) EndGame(ctx context.Context) error { return c.act(ctx, "endgame", nil) }
+
+// Continue plays on once the deck is spent. Host only.
+func (c *Client) Continue(ctx context.Context) error { return c.act(ctx, "continue", nil) }
+
+// Flip spends the client's flip modifier on the rule gameCardID.
+func (c *Client) Flip(ctx context.Context, gameCardID int32) error {
+	return c.act(ctx, "flip", cardParams{gameCardID})
+}
+
+// Shred spends the client's shred modifier on the rule gameCardID.
+func (c *Client) Shred(ctx context.Context, gameCardID int32) error {
+	return c.act(ctx, "shred", cardParams{gameCardID})
+}
+
+// Clone spends the client's clone modifier copying the rule gameCardID to
+// targetPlayerID.
+func (c *Client) Clone(ctx context.Context, gameCardID, targetPlayerID int32) error {
+	return c.act(ctx, "clone", cardTargetParams{gameCardID, targetPlayerID})
+}
+
+// Transfer spends the client's transfer modifier moving the rule gameCardID
+// to targetPlayerID.
+func (c *Client) Transfer(ctx context.Context, gameCardID, targetPlayerID int32) error {
+	return c.act(ctx, "transfer", cardTargetParams{gameCardID, targetPlayerID})
+}
+
+// Accuse accuses defendantID of breaking the rule gameCardID they hold, and
+// returns the infraction's id for the host to Decide.
+func (c *Client) Accuse(ctx context.Context, defendantID, gameCardID int32) (int32, error) {
+	signals, err := c.Action(ctx, "accuse", struct {
+		DefendantID int32 `json:"defendant_id"`
+		GameCardID  int32 `json:"game_card_id"`
+	}{defendantID, gameCardID})
+	if err != nil {
+		return 0, err
+	}
+	var created struct {
+		ID int32 `json:"id"`
+	}
+	ok, err := signals.Decode("infractionCreated", &created)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("accuse: no infraction created")
+	}
+	return created.ID, nil
+}
+
+// Decide rules on infractionID: affirmed, the accused loses amount points;
+// else they're absolved and amount is ignored. Host only.
+func (c *Client) Decide(ctx context.Context, infractionID int32, affirm bool, amount int32) error {
+	params := struct {
+		InfractionID int32  `json:"infraction_id"`
+		Verdict      string `json:"verdict"`
+		Amount       *int32 `json:"amount,omitempty"`
+	}{InfractionID: infractionID, Verdict: "absolve"}
+	if affirm {
+		params.Verdict = "affirm"
+		params.Amount = &amount
+	}
+	return c.act(ctx, "decide", params)
+}
+
+// Succeed passes the running prompt challenge. Host only.
+func (c *Client) Succeed(ctx context.Context) error { return c.act(ctx, "succeed", nil) }
+
+// Fail fails the running prompt challenge, once its time is up. Host only.
+func (c *Client) Fail(ctx context.Context) error { return c.act(ctx, "fail", nil) }
+
+// End ends the game. Host only.
+func (c *Client) End(ctx context.Context) error { return c.act(ctx, "end", nil) }
+
+// TransferHost hands the host seat to playerID, taking their seat. Host
+// only.
+func (c *Client) TransferHost(ctx context.Context, playerID int32) error {
+	return c.act(ctx, "transfer-host", playerParams{playerID})
+}
+
+// VoteHost votes for playerID to host while the host is away.
+func (c *Client) VoteHost(ctx context.Context, playerID int32) error {
+	return c.act(ctx, "vote-host", playerParams{playerID})
+}
+
+// Leave gives up the client's seat; cards says what becomes of its cards
+// ("" for CardsWheel). The client's tokens go with it.
+func (c *Client) Leave(ctx context.Context, cards string) error {
+	return c.act(ctx, "leave", struct {
+		Cards string `json:"cards,omitempty"`
+	}{cards})
+}
+
+// Kick removes playerID from the game; cards says what becomes of their
+// cards ("" for CardsWheel). Host only.
+func (c *Client) Kick(ctx context.Context, playerID int32, cards string) error {
+	return c.act(ctx, "kick", struct {
+		PlayerID int32  `json:"player_id"`
+		Cards    string `json:"cards,omitempty"`
+	}{playerID, cards})
+}
+
+// Undo takes back the latest verdict, prompt points, transfer or clone not
+// yet undone; again, the one before. Host only.
+func (c *Client) Undo(ctx context.Context) error { return c.act(ctx, "undo", nil) }
+
+// Token mints another bearer token for the client's player, e.g. for a
+// second process, returning its id and the token.
+func (c *Client) Token(ctx context.Context) (int32, string, error) {
+	signals, err := c.Action(ctx, "token", nil)
+	if err != nil {
+		return 0, "", err
+	}
+	var minted struct {
+		ID    int32  `json:"id"`
+		Token string `json:"token"`
+	}
+	ok, err := signals.Decode("apiToken", &minted)
+	if err != nil {
+		return 0, "", err
+	}
+	if !ok {
+		return 0, "", fmt.Errorf("token: none minted")
+	}
+	return minted.ID, minted.Token, nil
+}
+
+// RevokeTokens revokes playerID's tokens (0 for the client's own; another
+// player's is host only), or only tokenID when it's non-zero, and returns
+// how many went. Revoking the token the client acts with leaves it
+// ErrUnauthorized.
+func (c *Client) RevokeTokens(ctx context.Context, playerID, tokenID int32) (int, error) {
+	params := struct {
+		PlayerID *int32 `json:"player_id,omitempty"`
+		TokenID  *int32 `json:"token_id,omitempty"`
+	}{}
+	if playerID != 0 {
+		params.PlayerID = &playerID
+	}
+	if tokenID != 0 {
+		params.TokenID = &tokenID
+	}
+	signals, err := c.Action(ctx, "revoke-tokens", params)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	_, err = signals.Decode("tokensRevoked", &n)
+	return n, err
+}
@@ -0,0 +1,281 @@
+// Package client drives rulette games over the JSON API, for bots, tests and
+// tooling. A Client plays as one player: Join (or Watch) a game, then read it
+// and act on it with typed methods.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// apiPrefix roots the API under the server's address.
+const apiPrefix = "/api/v1"
+
+// Client is one player's connection to a rulette server. It carries the
+// bearer token its join minted, and keeps the session cookie that came with
+// it. A Client is safe for concurrent use once joined.
+type Client struct {
+	base  string
+	http  *http.Client
+	game  string
+	id    int32
+	token string
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://rulette.grackle.club". A nil hc gets a default client; either way
+// the client keeps cookies, giving hc a jar if it has none.
+func New(baseURL string, hc *http.Client) *Client {
+	if hc == nil {
+		hc = &http.Client{}
+	}
+	if hc.Jar == nil {
+		jar, _ := cookiejar.New(nil) // never errors without options
+		copied := *hc
+		copied.Jar = jar
+		hc = &copied
+	}
+	return &Client{base: strings.TrimRight(baseURL, "/"), http: hc}
+}
+
+// WithToken returns a client playing as whoever token stands for in gameID:
+// one minted earlier, by Token or on the game page.
+func (c *Client) WithToken(gameID, token string) *Client {
+	copied := *c
+	copied.game = gameID
+	copied.token = token
+	copied.id = 0
+	return &copied
+}
+
+// GameID is the game the client joined, or "" before it has.
+func (c *Client) GameID() string { return c.game }
+
+// PlayerID is the client's player id in its game, or 0 before a join (or
+// when made WithToken; Game's CallerID says).
+func (c *Client) PlayerID() int32 { return c.id }
+
+// BearerToken is the token the client acts with, or "" before a join.
+func (c *Client) BearerToken() string { return c.token }
+
+// Create makes a new game and returns its id. Create doesn't join it: the
+// first client to Join the game hosts it.
+func (c *Client) Create(ctx context.Context) (string, error) {
+	var created struct {
+		GameID string `json:"game_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/games", struct{}{}, &created); err != nil {
+		return "", err
+	}
+	return created.GameID, nil
+}
+
+// Join takes a seat in gameID as username; the first to join hosts.
+func (c *Client) Join(ctx context.Context, gameID, username string) error {
+	return c.join(ctx, gameID, username, false)
+}
+
+// Watch joins gameID as a spectator: reads, but no actions beyond Token and
+// RevokeTokens.
+func (c *Client) Watch(ctx context.Context, gameID, username string) error {
+	return c.join(ctx, gameID, username, true)
+}
+
+func (c *Client) join(ctx context.Context, gameID, username string, spectate bool) error {
+	body := struct {
+		Username string `json:"username"`
+		Spectate bool   `json:"spectate,omitempty"`
+	}{username, spectate}
+	var joined Joined
+	if err := c.do(ctx, http.MethodPost, "/games/"+url.PathEscape(gameID)+"/join", body, &joined); err != nil {
+		return err
+	}
+	c.game = joined.GameID
+	c.id = joined.PlayerID
+	c.token = joined.Token
+	return nil
+}
+
+// do sends a request to the API path, encoding body as JSON when it's
+// non-nil, and decodes a success into out when it's non-nil. An error answer
+// comes back as an *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode %s body: %w", path, err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+apiPrefix+path, reader)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// gamePath is path under the client's game.
+func (c *Client) gamePath(path string) (string, error) {
+	if c.game == "" {
+		return "", ErrNotJoined
+	}
+	return "/games/" + url.PathEscape(c.game) + path, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	p, err := c.gamePath(path)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodGet, p, nil, out)
+}
+
+// Action carries out the named action with params, a struct the action's
+// JSON body decodes from (nil for none), and returns the signals it raised.
+// The typed methods cover every action; Action is for one they don't yet.
+func (c *Client) Action(ctx context.Context, name string, params any) (Signals, error) {
+	p, err := c.gamePath("/actions/" + url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = struct{}{}
+	}
+	var result struct {
+		OK      bool    `json:"ok"`
+		Signals Signals `json:"signals"`
+	}
+	if err := c.do(ctx, http.MethodPost, p, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Signals == nil {
+		result.Signals = Signals{}
+	}
+	return result.Signals, nil
+}
+
+// Signals are what an action told the page: e.g. newCard with the rule
+// drawn, or infractionCreated with its id.
+type Signals map[string]json.RawMessage
+
+// Decode reads signal name into out, reporting whether it was raised.
+func (s Signals) Decode(name string, out any) (bool, error) {
+	raw, ok := s[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return true, fmt.Errorf("decode %s signal: %w", name, err)
+	}
+	return true, nil
+}
+
+// Error is an error the server answered with.
+type Error struct {
+	Status int
+	// Code is the status in snake_case (not_found, conflict, ...), or refused
+	// when the game turned the action down, or confirm_required when it
+	// should be sent again confirmed.
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("rulette: %d %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("rulette: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// The kinds of Error, for errors.Is.
+var (
+	ErrUnauthorized    = errors.New("no session or token, or a revoked one")        // 401
+	ErrForbidden       = errors.New("not allowed to do that")                       // 403
+	ErrNotFound        = errors.New("no such game or action")                       // 404
+	ErrConflict        = errors.New("not possible as the game stands")              // 409, including the two below
+	ErrRefused         = errors.New("the game turned the action down")              // 409 refused
+	ErrConfirmRequired = errors.New("the action must be confirmed")                 // 409 confirm_required
+	ErrGone            = errors.New("game over")                                    // 410
+	ErrLocked          = errors.New("an accusation or challenge is being resolved") // 423
+	ErrTooEarly        = errors.New("not possible in this phase of the game")       // 425
+	ErrRateLimited     = errors.New("too many requests")                            // 429
+	ErrNotJoined       = errors.New("client hasn't joined a game")
+)
+
+// Is matches e against the kinds above.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrConflict:
+		return e.Status == http.StatusConflict
+	case ErrRefused:
+		return e.Status == http.StatusConflict && e.Code == "refused"
+	case ErrConfirmRequired:
+		return e.Status == http.StatusConflict && e.Code == "confirm_required"
+	case ErrGone:
+		return e.Status == http.StatusGone
+	case ErrLocked:
+		return e.Status == http.StatusLocked
+	case ErrTooEarly:
+		return e.Status == http.StatusTooEarly
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// readError makes an *Error of an error answer: the API's error body, or
+// for anything else (a rate limit, a proxy) the status and its text.
+func readError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	var body struct {
+		Error struct {
+			Status  int    `json:"status"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(b, &body) == nil && body.Error.Code != "" {
+		return &Error{Status: resp.StatusCode, Code: body.Error.Code, Message: body.Error.Message}
+	}
+	return &Error{
+		Status:  resp.StatusCode,
+		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(resp.StatusCode)), " ", "_"),
+		Message: strings.TrimSpace(string(b)),
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fake answers each API path with a canned status and body, and records the
+// last request.
+type fake struct {
+	answers map[string]func(w http.ResponseWriter)
+	last    *http.Request
+	body    map[string]any
+}
+
+func (f *fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.last = r
+	f.body = nil
+	if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+		json.Unmarshal(b, &f.body)
+	}
+	answer, ok := f.answers[r.Method+" "+r.URL.Path]
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"status":404,"code":"not_found","message":"no route"}}`))
+		return
+	}
+	answer(w)
+}
+
+func reply(status int, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestJoin(t *testing.T) {
+	ctx := context.Background()
+	f := &fake{answers: map[string]func(http.ResponseWriter){
+		"POST /api/v1/games":               reply(http.StatusOK, `{"game_id":"abc123"}`),
+		"POST /api/v1/games/abc123/join":   reply(http.StatusOK, `{"game_id":"abc123","player_id":7,"token_id":1,"token":"rlt_x"}`),
+		"GET /api/v1/games/abc123/players": reply(http.StatusOK, `{"players":[{"id":7,"name":"Ada","seat":0,"host":true}],"spectators":[]}`),
+	}}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	c := New(server.URL+"/", nil)
+	_, err := c.Players(ctx)
+	require.ErrorIs(t, err, ErrNotJoined)
+
+	id, err := c.Create(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "abc123", id)
+	require.Equal(t, "application/json", f.last.Header.Get("Content-Type"))
+
+	require.NoError(t, c.Join(ctx, id, "Ada"))
+	require.Equal(t, map[string]any{"username": "Ada"}, f.body)
+	require.Equal(t, "abc123", c.GameID())
+	require.Equal(t, int32(7), c.PlayerID())
+	require.Equal(t, "rlt_x", c.BearerToken())
+
+	players, err := c.Players(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer rlt_x", f.last.Header.Get("Authorization"))
+	require.Len(t, players.Players, 1)
+	require.True(t, players.Players[0].Host)
+
+	other := c.WithToken("def456", "rlt_y")
+	require.Equal(t, "def456", other.GameID())
+	require.Equal(t, "abc123", c.GameID(), "WithToken leaves the original be")
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		is     []error
+		isNot  []error
+		code   string
+	}{
+		{"refused", http.StatusConflict, `{"error":{"status":409,"code":"refused","message":"Wait your turn."}}`,
+			[]error{ErrConflict, ErrRefused}, []error{ErrConfirmRequired}, "refused"},
+		{"confirm", http.StatusConflict, `{"error":{"status":409,"code":"confirm_required","message":"confirm"}}`,
+			[]error{ErrConflict, ErrConfirmRequired}, []error{ErrRefused}, "confirm_required"},
+		{"conflict", http.StatusConflict, `{"error":{"status":409,"code":"conflict","message":"nothing to acknowledge"}}`,
+			[]error{ErrConflict}, []error{ErrRefused, ErrConfirmRequired}, "conflict"},
+		{"locked", http.StatusLocked, `{"error":{"status":423,"code":"locked","message":"interruption in progress"}}`,
+			[]error{ErrLocked}, []error{ErrConflict}, "locked"},
+		{"too early", http.StatusTooEarly, `{"error":{"status":425,"code":"too_early","message":"action invalid"}}`,
+			[]error{ErrTooEarly}, nil, "too_early"},
+		{"gone", http.StatusGone, `{"error":{"status":410,"code":"gone","message":"game over"}}`,
+			[]error{ErrGone}, nil, "gone"},
+		{"revoked", http.StatusUnauthorized, `{"error":{"status":401,"code":"unauthorized","message":"invalid bearer token"}}`,
+			[]error{ErrUnauthorized}, []error{ErrForbidden}, "unauthorized"},
+		{"rate limit", http.StatusTooManyRequests, "Too Many Requests\n",
+			[]error{ErrRateLimited}, nil, "too_many_requests"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &fake{answers: map[string]func(http.ResponseWriter){
+				"POST /api/v1/games/abc123/actions/spin": reply(tc.status, tc.body),
+			}}
+			server := httptest.NewServer(f)
+			defer server.Close()
+			_, err := New(server.URL, nil).WithToken("abc123", "rlt_x").Spin(context.Background())
+			for _, target := range tc.is {
+				require.ErrorIs(t, err, target)
+			}
+			for _, target := range tc.isNot {
+				require.NotErrorIs(t, err, target)
+			}
+			var apiErr *Error
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, tc.status, apiErr.Status)
+			require.Equal(t, tc.code, apiErr.Code)
+			require.NotEmpty(t, apiErr.Message)
+		})
+	}
+}
+
+func TestSignals(t *testing.T) {
+	ctx := context.Background()
+	f := &fake{answers: map[string]func(http.ResponseWriter){
+		"POST /api/v1/games/abc123/actions/spin":          reply(http.StatusOK, `{"ok":true,"signals":{"newPrompt":{"prompt":"Sing.","window":60}}}`),
+		"POST /api/v1/games/abc123/actions/accuse":        reply(http.StatusOK, `{"ok":true,"signals":{"infractionCreated":{"id":12}}}`),
+		"POST /api/v1/games/abc123/actions/decide":        reply(http.StatusOK, `{"ok":true}`),
+		"POST /api/v1/games/abc123/actions/revoke-tokens": reply(http.StatusOK, `{"ok":true,"signals":{"tokensRevoked":2}}`),
+	}}
+	server := httptest.NewServer(f)
+	defer server.Close()
+	c := New(server.URL, nil).WithToken("abc123", "rlt_x")
+
+	spin, err := c.Spin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Spin{Prompt: "Sing.", PromptSeconds: 60}, spin)
+
+	id, err := c.Accuse(ctx, 4, 9)
+	require.NoError(t, err)
+	require.Equal(t, int32(12), id)
+	require.Equal(t, map[string]any{"defendant_id": 4.0, "game_card_id": 9.0}, f.body)
+
+	require.NoError(t, c.Decide(ctx, id, false, 5))
+	require.Equal(t, map[string]any{"infraction_id": 12.0, "verdict": "absolve"}, f.body)
+	require.NoError(t, c.Decide(ctx, id, true, 5))
+	require.Equal(t, map[string]any{"infraction_id": 12.0, "verdict": "affirm", "amount": 5.0}, f.body)
+
+	n, err := c.RevokeTokens(ctx, 0, 3)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, map[string]any{"token_id": 3.0}, f.body)
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// The game states Game.StateID holds.
+const (
+	StateCreated   = 0 // no one has joined
+	StateInviting  = 1 // players are joining
+	StateReady     = 2 // ready to start, or paused
+	StateTurn      = 3 // a player is mid-turn
+	StatePending   = 4 // a modifier's target is owed
+	StateChallenge = 5 // an accusation awaits the host
+	StatePrompt    = 6 // a prompt challenge is running
+	StateEnding    = 7 // the deck is spent
+	StateOver      = 8 // game over
+	StateWriting   = 9 // players are writing cards
+)
+
+// The roles Game.Role holds.
+const (
+	RoleHost      = "host"
+	RoleTurn      = "turn"
+	RolePlayer    = "player"
+	RoleSpectator = "spectator"
+)
+
+// Joined answers a join.
+type Joined struct {
+	GameID    string `json:"game_id"`
+	PlayerID  int32  `json:"player_id"`
+	Spectator bool   `json:"spectator"`
+	TokenID   int32  `json:"token_id"`
+	Token     string `json:"token"`
+}
+
+// Game is a game as the client sees it.
+type Game struct {
+	ID      string `json:"id"`
+	State   string `json:"state"`
+	StateID int32  `json:"state_id"`
+	Role    string `json:"role"`
+	// CallerID is the client's player id, seated or watching.
+	CallerID     int32  `json:"caller_id"`
+	TurnPlayerID *int32 `json:"turn_player_id"`
+	// TurnSecondsLeft is nil when no turn clock is running.
+	TurnSecondsLeft *int             `json:"turn_seconds_left"`
+	AwaitingAck     bool             `json:"awaiting_ack"`
+	HostAway        bool             `json:"host_away"`
+	Deck            string           `json:"deck"`
+	Settings        map[string]int32 `json:"settings"`
+	Updated         time.Time        `json:"updated"`
+}
+
+// Player is a seated player.
+type Player struct {
+	ID     int32  `json:"id"`
+	Name   string `json:"name"`
+	Points int32  `json:"points"`
+	Seat   int32  `json:"seat"` // the host sits at 0
+	Host   bool   `json:"host"`
+	Turn   bool   `json:"turn"`
+	Bot    bool   `json:"bot"` // plays its own turns on the server
+}
+
+// Players are the seated players, in seat order, and the spectators' names.
+type Players struct {
+	Players    []Player `json:"players"`
+	Spectators []string `json:"spectators"`
+}
+
+// Card is a card a player holds, face up.
+type Card struct {
+	ID             int32  `json:"id"`
+	Type           string `json:"type"`
+	Content        string `json:"content"`
+	Flipped        bool   `json:"flipped"`
+	FromClone      bool   `json:"from_clone"`
+	ModifierEffect string `json:"modifier_effect,omitempty"`
+}
+
+// Hand is the cards one player holds.
+type Hand struct {
+	PlayerID int32  `json:"player_id"`
+	Cards    []Card `json:"cards"`
+}
+
+type hands struct {
+	Hands []Hand `json:"hands"`
+}
+
+// WheelSlot is how many cards one slot of the wheel holds.
+type WheelSlot struct {
+	Slot      int32 `json:"slot"`
+	StackSize int64 `json:"stack_size"`
+}
+
+// Wheel is how deep each slot of the wheel runs.
+type Wheel struct {
+	Slots []WheelSlot `json:"slots"`
+	Cards int64       `json:"cards"`
+}
+
+// Infraction is an accusation and, once decided, its verdict.
+type Infraction struct {
+	ID         int32     `json:"id"`
+	AccusedID  int32     `json:"accused_id"`
+	AccuserID  int32     `json:"accuser_id"`
+	GameCardID int32     `json:"game_card_id"`
+	Active     bool      `json:"active"`
+	Affirmed   *bool     `json:"affirmed"` // nil until decided
+	Created    time.Time `json:"created"`
+}
+
+type infractions struct {
+	Infractions []Infraction `json:"infractions"`
+}
+
+// Event is one game log entry.
+type Event struct {
+	ID          int32  `json:"id"`
+	Type        string `json:"type"`
+	Actor       string `json:"actor,omitempty"`
+	Target      string `json:"target,omitempty"`
+	PointsDelta *int32 `json:"points_delta,omitempty"`
+	Affirmed    *bool  `json:"affirmed,omitempty"`
+	Card        string `json:"card,omitempty"`
+	CardType    string `json:"card_type,omitempty"`
+	Undone      string `json:"undone,omitempty"` // an undo's: the type of event it reversed
+}
+
+// Events is a page of the game log. Pass NextSince to Events for the next
+// page; More says whether one is already waiting.
+type Events struct {
+	Events    []Event `json:"events"`
+	NextSince int32   `json:"next_since"`
+	More      bool    `json:"more"`
+}
+
+// Summary is the game in numbers: each player's tallies, the awards they
+// earned, and totals for the table.
+type Summary struct {
+	Final       bool            `json:"final"` // false while the game still runs
+	Players     []SummaryPlayer `json:"players"`
+	Awards      []Award         `json:"awards"`
+	Turns       int             `json:"turns"`
+	Spins       int             `json:"spins"`
+	Accusations int             `json:"accusations"`
+	Affirmed    int             `json:"affirmed"`
+	PromptsWon  int             `json:"prompts_won"`
+}
+
+// SummaryPlayer is one non-host player's tallies, departed players included.
+type SummaryPlayer struct {
+	PlayerID     int32  `json:"player_id"`
+	Name         string `json:"name"`
+	Seated       bool   `json:"seated"`
+	Points       int32  `json:"points"`
+	RulesHeld    int    `json:"rules_held"`
+	Spins        int    `json:"spins"`
+	Accusations  int    `json:"accusations"`
+	Affirmed     int    `json:"affirmed"`
+	PromptsWon   int    `json:"prompts_won"`
+	PromptPoints int32  `json:"prompt_points"`
+	BiggestSwing int32  `json:"biggest_swing"`
+	LongestTurn  int    `json:"longest_turn_seconds"`
+}
+
+// Award goes to whoever leads one tally, e.g. most_accusations; ties share
+// it.
+type Award struct {
+	Key         string   `json:"key"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	PlayerIDs   []int32  `json:"player_ids"`
+	Players     []string `json:"players"`
+	Value       int64    `json:"value"`
+	Detail      string   `json:"detail"`
+}
+
+// Game reads the game.
+func (c *Client) Game(ctx context.Context) (Game, error) {
+	var g Game
+	err := c.get(ctx, "", &g)
+	return g, err
+}
+
+// Players reads who's seated and who's watching.
+func (c *Client) Players(ctx context.Context) (Players, error) {
+	var p Players
+	err := c.get(ctx, "/players", &p)
+	return p, err
+}
+
+// Hands reads the cards each non-host player holds.
+func (c *Client) Hands(ctx context.Context) ([]Hand, error) {
+	var h hands
+	err := c.get(ctx, "/hands", &h)
+	return h.Hands, err
+}
+
+// Wheel reads how deep each wheel slot runs.
+func (c *Client) Wheel(ctx context.Context) (Wheel, error) {
+	var w Wheel
+	err := c.get(ctx, "/wheel", &w)
+	return w, err
+}
+
+// Infractions reads every accusation and its verdict.
+func (c *Client) Infractions(ctx context.Context) ([]Infraction, error) {
+	var i infractions
+	err := c.get(ctx, "/infractions", &i)
+	return i.Infractions, err
+}
+
+// Summary reads the game's tallies and awards.
+func (c *Client) Summary(ctx context.Context) (Summary, error) {
+	var sum Summary
+	err := c.get(ctx, "/summary", &sum)
+	return sum, err
+}
+
+// Events reads a page of the log after event since; limit 0 takes the
+// server's default page.
+func (c *Client) Events(ctx context.Context, since int32, limit int) (Events, error) {
+	query := url.Values{"since": {fmt.Sprint(since)}}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+	var e Events
+	err := c.get(ctx, "/events?"+query.Encode(), &e)
+	return e, err
+}
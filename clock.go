@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// turnClock enforces each game's initiative_timer until ctx is done: every
+// turnClockInterval it times out the turns that have run past their clock, so
+// a player who walks away can't stall the game, and refreshes the games whose
+// host just went away (hostLapsedTick). Every instance runs one; the games
+// row lock in timeoutTurn keeps them from timing a turn out twice.
+func turnClock(ctx context.Context) {
+	ticker := time.NewTicker(turnClockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			turnClockTick(ctx)
+			// overlap the windows, so a late tick can't skip a game
+			hostLapsedTick(ctx, 2*turnClockInterval.Seconds())
+		}
+	}
+}
+
+// turnClockTick times out every turn that is past its clock right now.
+func turnClockTick(ctx context.Context) {
+	gameIDs, err := queries.GamesTurnExpired(ctx)
+	if err != nil {
+		log.Error("list expired turns", "error", err)
+		return
+	}
+	for _, gameID := range gameIDs {
+		if err := timeoutTurn(ctx, gameID); err != nil {
+			log.Error("time out turn", "error", err, "game_id", gameID)
+		}
+	}
+}
+
+// timeoutTurn moves an expired turn on, the way the player would have: a
+// drawn rule card is acknowledged, an owed modifier choice is forfeited (the
+// modifier spent), and a turn with no spin is skipped. Either way it records
+// a timeout event and passes initiative through advanceTurn. It does nothing
+// if the turn moved on since the caller saw it expire.
+func timeoutTurn(ctx context.Context, gameID string) error {
+	log := log.With("func", "timeoutTurn", "game_id", gameID)
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	txq := queries.WithTx(tx)
+
+	game, err := txq.GameTurnExpiredLock(ctx, gameID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("lock expired turn: %w", err)
+	}
+	var player pgtype.Int4
+	playerID, err := txq.InitiativeCurrentPlayer(ctx, gameID)
+	switch {
+	case err == nil:
+		player = pgInt(playerID)
+	case !errors.Is(err, pgx.ErrNoRows): // a gap in initiative has no player
+		return fmt.Errorf("find current turn player: %w", err)
+	}
+
+	resolved := "skip"
+	switch game.StateID {
+	case statePending:
+		resolved = "forfeit"
+		if player.Valid {
+			_, err := txq.GameCardsShredModifiers(ctx, sqlc.GameCardsShredModifiersParams{
+				GameID:   gameID,
+				PlayerID: player,
+			})
+			if err != nil {
+				return fmt.Errorf("spend forfeited modifier: %w", err)
+			}
+		}
+		err := txq.GameUpdate(ctx, sqlc.GameUpdateParams{
+			ID:                gameID,
+			StateID:           stateTurn,
+			InitiativeCurrent: game.InitiativeCurrent,
+		})
+		if err != nil {
+			return fmt.Errorf("transition to turn: %w", err)
+		}
+	case stateTurn:
+		spin, err := txq.SpinPendingModifier(ctx, gameID)
+		switch {
+		case err == nil && player.Valid &&
+			spin.PlayerID.Int32 == player.Int32 && !spin.ModifierEffect.Valid:
+			resolved = "acknowledge"
+		case err != nil && !errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("check spin to acknowledge: %w", err)
+		}
+	}
+
+	if err := recordEvent(ctx, log, txq, sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "timeout",
+		TargetID:  player,
+	}); err != nil {
+		return err
+	}
+	if err := advanceTurn(ctx, log, txq, gameID); err != nil {
+		return fmt.Errorf("advance timed out turn: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	log.Info("turn timed out", "player_id", player.Int32, "resolved", resolved)
+	invalidate(ctx, gameID)
+	return nil
+}
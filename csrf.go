@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	csrfCookieName = "csrf"
+	csrfHeader     = "X-CSRF-Token" // htmx and fetch posts
+	csrfField      = "csrf_token"   // plain form posts
+)
+
+// csrfToken returns the token the caller's pages must send back with every
+// post: their csrf cookie, signed with the session secret, so a page on
+// another site can neither read nor make one. "" when they have no cookie.
+func csrfToken(r *http.Request) string {
+	c, err := r.Cookie(csrfCookieName)
+	if err != nil || c.Value == "" {
+		return ""
+	}
+	return signSession("csrf:" + c.Value)
+}
+
+// validCSRF reports whether a post carries the caller's csrf token, in the
+// X-CSRF-Token header or the csrf_token form field.
+func validCSRF(r *http.Request) bool {
+	want := csrfToken(r)
+	if want == "" {
+		return false
+	}
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		got = r.PostFormValue(csrfField)
+	}
+	return hmac.Equal([]byte(got), []byte(want))
+}
+
+// csrfMW gives a caller without one a csrf cookie, for the page being served
+// to carry the token, and turns away any post or delete without the token. A request
+// with a bearer token passes untouched: a browser never attaches one on
+// another site's behalf.
+func csrfMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := r.Cookie(csrfCookieName); err != nil {
+			b := make([]byte, secretLength)
+			if _, err := rand.Read(b); err != nil {
+				log.Error("make csrf cookie", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			c := &http.Cookie{
+				Name:     csrfCookieName,
+				Value:    hex.EncodeToString(b),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   secureRequest(r),
+				SameSite: http.SameSiteLaxMode,
+			}
+			http.SetCookie(w, c)
+			r.AddCookie(c)
+		}
+		if (r.Method == http.MethodPost || r.Method == http.MethodDelete) && !validCSRF(r) {
+			log.Warn("csrf token missing or wrong", "path", r.URL.Path)
+			http.Error(w, "invalid csrf token, reload the page", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	initLogger(nil)
+	var token string
+	h := csrfMW(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = csrfToken(r)
+	}))
+
+	// a first visit gets a cookie, and the page its token
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc123", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var jar *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == csrfCookieName {
+			jar = c
+		}
+	}
+	require.NotNil(t, jar)
+	require.True(t, jar.HttpOnly)
+	require.NotEmpty(t, token)
+	require.NotEqual(t, jar.Value, token)
+
+	post := func(c *http.Cookie, header string, form url.Values) int {
+		r := httptest.NewRequest(http.MethodPost, "/abc123/action/end", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c != nil {
+			r.AddCookie(c)
+		}
+		if header != "" {
+			r.Header.Set(csrfHeader, header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	other := &http.Cookie{Name: csrfCookieName, Value: "someone-else"}
+	require.Equal(t, http.StatusOK, post(jar, token, nil), "htmx header")
+	require.Equal(t, http.StatusOK, post(jar, "", url.Values{csrfField: {token}}), "form field")
+	require.Equal(t, http.StatusForbidden, post(jar, "", nil), "no token")
+	require.Equal(t, http.StatusForbidden, post(jar, "forged", nil), "wrong token")
+	require.Equal(t, http.StatusForbidden, post(other, token, nil), "another browser's token")
+	require.Equal(t, http.StatusForbidden, post(nil, token, nil), "no cookie")
+
+	del := httptest.NewRequest(http.MethodDelete, "/library/decks/1/cards/2", nil)
+	del.AddCookie(jar)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, del)
+	require.Equal(t, http.StatusForbidden, w.Code, "delete without a token")
+
+	// a bearer token isn't something a browser sends on another site's behalf
+	r := httptest.NewRequest(http.MethodPost, "/abc123/action/end", nil)
+	r.Header.Set("Authorization", "Bearer rlt_abc")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, "bearer token")
+	require.Empty(t, w.Result().Cookies(), "no csrf cookie for a script")
+}
@@ -0,0 +1,27 @@
+-- seats a bot at the end of the table, on the game's starting points; its
+-- session key, hashed, is never handed to anyone
+-- name: GameBotCreate :exec
+INSERT INTO game_players (game_id, player_id, session_key, initiative, points, bot)
+VALUES (
+    $1, $2, $3,
+    (SELECT COALESCE(MAX(initiative), 0) + 1 FROM game_players WHERE game_id = $1),
+    (SELECT starting_points FROM games WHERE games.id = $1),
+    TRUE
+);
+
+-- keeps other instances' bot clocks off the game until the transaction ends;
+-- false when another already holds it
+-- name: GameBotLock :one
+SELECT pg_try_advisory_xact_lock(hashtext('rulette_bots'), hashtext(sqlc.arg(game_id)::text)) AS locked;
+
+-- games whose turn is a bot's to play, with the bot playing it
+-- name: GamesBotTurn :many
+SELECT
+    games.id AS game_id,
+    game_players.player_id,
+    game_players.session_key
+FROM games
+JOIN game_players ON game_players.game_id = games.id
+    AND game_players.initiative = games.initiative_current
+WHERE games.state_id IN (3, 4)
+    AND game_players.bot;
@@ -1,15 +1,41 @@
--- name: CacheSet :exec
-INSERT INTO game_cache (game_id, value, expires)
-VALUES ($1, $2, CURRENT_TIMESTAMP + INTERVAL '1 second') -- TODO: make var?
+-- name: CacheSet :execrows
+-- Stores a game's serialized state for ttl_ms milliseconds, as read at
+-- version. An invalidation since bumped the version, and then nothing is
+-- stored: the state is already stale.
+INSERT INTO game_cache (game_id, value, version, expires)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP + (sqlc.arg(ttl_ms)::int * INTERVAL '1 millisecond'))
 ON CONFLICT (game_id) DO UPDATE SET 
     value = EXCLUDED.value, 
-    expires = EXCLUDED.expires;
+    expires = EXCLUDED.expires
+WHERE game_cache.version = EXCLUDED.version;
 
 -- name: CacheGet :one
 SELECT value FROM game_cache
-WHERE game_id = $1 AND expires > CURRENT_TIMESTAMP;
+WHERE game_id = $1
+    AND value IS NOT NULL
+    AND expires > CURRENT_TIMESTAMP;
+
+-- name: CacheVersion :one
+-- A game's cache version, to read before fetching its state and store it at.
+SELECT COALESCE((SELECT version FROM game_cache WHERE game_id = $1), 0)::bigint AS version;
+
+-- name: CacheClear :exec
+-- Drops a game's cached state and bumps its version, so a fetch that started
+-- before can't store what it read. The emptied row outlives any fetch, then
+-- CacheClean sweeps it.
+INSERT INTO game_cache (game_id, value, version, expires)
+VALUES ($1, NULL, 1, CURRENT_TIMESTAMP + INTERVAL '1 minute')
+ON CONFLICT (game_id) DO UPDATE SET
+    value = NULL,
+    version = game_cache.version + 1,
+    expires = EXCLUDED.expires;
 
 -- name: CacheClean :exec
 DELETE FROM game_cache
 WHERE expires <= CURRENT_TIMESTAMP;
 
+-- name: CacheInvalidate :exec
+-- Broadcasts a game's cache invalidation to every instance LISTENing on the
+-- rulette_cache channel. payload is "{instance_id}:{game_id}". Inside a
+-- transaction, Postgres holds the notification until commit.
+SELECT pg_notify('rulette_cache', sqlc.arg(payload)::text);
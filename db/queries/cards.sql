@@ -9,3 +9,34 @@ SELECT * FROM cards WHERE id = $1;
 -- name: CardDelete :exec
 DELETE FROM cards WHERE id = $1;
 
+-- queues an imported card for moderation, unless the same front is already
+-- queued or in a live pack (no row: a duplicate)
+-- name: CardImport :one
+INSERT INTO cards (type, front, back, generic, modifier_effect, pending, source)
+SELECT
+    sqlc.arg(type)::text,
+    sqlc.arg(front)::text,
+    sqlc.narg(back)::text,
+    FALSE,
+    sqlc.narg(modifier_effect)::text,
+    TRUE,
+    sqlc.arg(source)::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM cards
+    WHERE front = sqlc.arg(front)::text
+        AND (pending IS TRUE OR (generic IS TRUE AND retired IS FALSE))
+)
+RETURNING id;
+
+-- the moderation queue, oldest first
+-- name: CardsPending :many
+SELECT id, type, front, back, modifier_effect, source
+FROM cards
+WHERE pending IS TRUE
+ORDER BY id;
+
+-- drops rejected cards from the moderation queue; ids no longer queued are
+-- left alone
+-- name: CardsReject :execrows
+DELETE FROM cards
+WHERE id = ANY(sqlc.arg(ids)::int[]) AND pending IS TRUE;
@@ -0,0 +1,45 @@
+-- the turn clock starts at a game's latest turn, spin, resume, or decide
+-- event: a new turn, a drawn card to act on, or play picking up after a pause
+-- or a challenge. it runs while the game is mid-turn (3) or owes a modifier
+-- choice (4); an initiative_timer of 0 turns it off.
+-- name: GameTurnSecondsLeft :one
+SELECT COALESCE(EXTRACT(EPOCH FROM (
+    (
+        SELECT MAX(ts)
+        FROM event_log
+        WHERE event_log.game_id = games.id
+            AND event_type IN ('turn', 'spin', 'resume', 'decide')
+    )
+    + initiative_timer * INTERVAL '1 second'
+    - LOCALTIMESTAMP
+)), 0)::float8 AS seconds_left
+FROM games
+WHERE id = $1;
+
+-- name: GamesTurnExpired :many
+SELECT id
+FROM games
+WHERE state_id IN (3, 4)
+    AND initiative_timer > 0
+    AND (
+        SELECT MAX(ts)
+        FROM event_log
+        WHERE event_log.game_id = games.id
+            AND event_type IN ('turn', 'spin', 'resume', 'decide')
+    ) + initiative_timer * INTERVAL '1 second' < LOCALTIMESTAMP;
+
+-- GamesTurnExpired for one game, locking it until the transaction ends so
+-- only one instance times the turn out. no row: the turn moved on.
+-- name: GameTurnExpiredLock :one
+SELECT id, state_id, initiative_current
+FROM games
+WHERE id = $1
+    AND state_id IN (3, 4)
+    AND initiative_timer > 0
+    AND (
+        SELECT MAX(ts)
+        FROM event_log
+        WHERE event_log.game_id = games.id
+            AND event_type IN ('turn', 'spin', 'resume', 'decide')
+    ) + initiative_timer * INTERVAL '1 second' < LOCALTIMESTAMP
+FOR UPDATE;
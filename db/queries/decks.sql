@@ -0,0 +1,69 @@
+-- name: DeckCreate :one
+INSERT INTO decks (name, edit_key)
+VALUES ($1, $2)
+RETURNING id;
+
+-- name: DeckRename :execrows
+UPDATE decks
+SET name = $3
+WHERE id = $1 AND edit_key = $2;
+
+-- editing a deck requires its key, stored hashed; false = wrong key or no
+-- such deck
+-- name: DeckAuthorized :one
+SELECT EXISTS (
+    SELECT 1 FROM decks WHERE id = $1 AND edit_key = $2
+) AS authorized;
+
+-- name: Deck :one
+SELECT
+    id,
+    name,
+    created,
+    (
+        SELECT COUNT(card_id)
+        FROM deck_cards
+        WHERE deck_cards.deck_id = decks.id
+    ) AS card_count
+FROM decks
+WHERE id = $1;
+
+-- every deck with at least one card, for the host's picker and the library
+-- name: Decks :many
+SELECT
+    decks.id,
+    decks.name,
+    COUNT(deck_cards.card_id) AS card_count
+FROM decks
+JOIN deck_cards ON deck_cards.deck_id = decks.id
+GROUP BY decks.id, decks.name
+ORDER BY decks.name, decks.id;
+
+-- name: DeckCards :many
+SELECT
+    cards.id,
+    cards.type,
+    cards.front,
+    cards.back
+FROM deck_cards
+JOIN cards ON cards.id = deck_cards.card_id
+WHERE deck_cards.deck_id = $1
+ORDER BY deck_cards.added, cards.id;
+
+-- a deck card joins the card library (not generic, no creator player) and
+-- the deck in one go.
+-- name: DeckCardCreate :one
+WITH card AS (
+    INSERT INTO cards (type, front, back, generic)
+    VALUES ($2, $3, $4, FALSE)
+    RETURNING id
+)
+INSERT INTO deck_cards (deck_id, card_id)
+SELECT $1, id FROM card
+RETURNING card_id;
+
+-- removes a card from the deck; the card stays in the library for any game
+-- already holding it.
+-- name: DeckCardDelete :execrows
+DELETE FROM deck_cards
+WHERE deck_id = $1 AND card_id = $2;
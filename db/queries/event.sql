@@ -1,16 +1,41 @@
 -- name: EventCreate :one
 -- Appends one event to the log. Detail FKs (spin_id, infraction_id,
--- game_card_id, point_change_id) are set per event_type; the rest are NULL.
+-- game_card_id, point_change_id, undo_of) are set per event_type; the rest
+-- are NULL.
 INSERT INTO event_log (
     game_id, event_type, actor_id, target_id,
-    spin_id, infraction_id, game_card_id, point_change_id
+    spin_id, infraction_id, game_card_id, point_change_id, undo_of
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id;
 
+-- name: EventLastUndoable :one
+-- The game's newest event the host can still undo: a verdict, a prompt's
+-- points, a transfer or a clone that no undo event has reversed yet. Undoing
+-- again walks further back. The points change it made, if any, comes along.
+SELECT
+    e.id,
+    e.event_type,
+    e.actor_id,
+    e.target_id,
+    e.infraction_id,
+    e.game_card_id,
+    pc.player_id AS points_player_id,
+    pc.delta AS points_delta
+FROM event_log e
+LEFT JOIN point_changes pc ON pc.id = e.point_change_id
+WHERE e.game_id = $1
+    AND (e.event_type IN ('decide', 'transfer', 'clone')
+        OR (e.event_type = 'prompt' AND e.point_change_id IS NOT NULL))
+    AND NOT EXISTS (SELECT 1 FROM event_log u WHERE u.undo_of = e.id)
+ORDER BY e.id DESC
+LIMIT 1;
+
 -- name: EventListSince :many
 -- Events for a game newer than a given id, oldest first, with the names and
 -- details the feed text and sounds need. Clients track the highest id seen.
+-- undone_type is what an undo event reversed, empty for other events.
+-- max_events caps how many come back; NULL for all of them.
 SELECT
     e.id,
     e.event_type,
@@ -25,7 +50,8 @@ SELECT
     COALESCE(c.front, '')::text AS card_front,
     COALESCE(c.back, '')::text AS card_back,
     COALESCE(c.type, '')::text AS card_type,
-    COALESCE(gc.flipped, inf_gc.flipped, FALSE) AS card_flipped
+    COALESCE(gc.flipped, inf_gc.flipped, FALSE) AS card_flipped,
+    COALESCE(undone.event_type, '')::text AS undone_type
 FROM event_log e
 LEFT JOIN players actor ON actor.id = e.actor_id
 LEFT JOIN players target ON target.id = e.target_id
@@ -35,6 +61,13 @@ LEFT JOIN game_cards gc ON gc.id = e.game_card_id
 LEFT JOIN spins sp ON sp.id = e.spin_id
 LEFT JOIN game_cards inf_gc ON inf_gc.id = inf.game_card_id
 LEFT JOIN cards c ON c.id = COALESCE(gc.card_id, sp.card_id, inf_gc.card_id)
+LEFT JOIN event_log undone ON undone.id = e.undo_of
 WHERE e.game_id = $1
     AND e.id > $2
-ORDER BY e.id;
+ORDER BY e.id
+LIMIT sqlc.narg(max_events);
+
+-- name: EventUndoLock :exec
+-- Holds the game's row until the transaction ends, so undos run one at a time
+-- and each finds the event the last one left.
+SELECT id FROM games WHERE id = $1 FOR UPDATE;
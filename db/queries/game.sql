@@ -8,6 +8,40 @@ UPDATE games
 SET state_id = $1, initiative_current = $2
 WHERE id = $3;
 
+-- moves a game from one state to another only if it is still in the first,
+-- so concurrent requests can't both make the same transition. 0 rows = lost.
+-- name: GameStateTransition :execrows
+UPDATE games
+SET state_id = sqlc.arg(to_state)
+WHERE id = sqlc.arg(id) AND state_id = sqlc.arg(from_state);
+
+-- name: GameDeckSet :exec
+UPDATE games
+SET deck_id = $2
+WHERE id = $1;
+
+-- saves the host's settings, only before the start (no row: it started).
+-- players who already joined take the new starting points, since nobody has
+-- scored yet.
+-- name: GameSettingsSet :execrows
+WITH players AS (
+    UPDATE game_players
+    SET points = sqlc.arg(starting_points)
+    WHERE game_players.game_id = sqlc.arg(id) AND EXISTS (
+        SELECT 1 FROM games WHERE games.id = sqlc.arg(id) AND state_id IN (0, 1)
+    )
+)
+UPDATE games
+SET wheel_slots = sqlc.arg(wheel_slots),
+    card_count = sqlc.arg(card_count),
+    initiative_timer = sqlc.arg(initiative_timer),
+    cards_per_player = sqlc.arg(cards_per_player),
+    starting_points = sqlc.arg(starting_points),
+    prompt_seconds = sqlc.arg(prompt_seconds),
+    recommended_players = sqlc.arg(recommended_players),
+    host_timeout = sqlc.arg(host_timeout)
+WHERE games.id = sqlc.arg(id) AND state_id IN (0, 1);
+
 -- name: Games :many
 SELECT * FROM games WHERE id = (
 	SELECT game_id 
@@ -24,6 +58,20 @@ SELECT
     owner_id,
     state_id,
     initiative_current,
+    initiative_timer,
+    cards_per_player,
+    wheel_slots,
+    card_count,
+    starting_points,
+    prompt_seconds,
+    recommended_players,
+    host_timeout,
+    deck_id,
+    (
+        SELECT name
+        FROM decks
+        WHERE decks.id = deck_id
+    ) AS deck_name,
     (
         SELECT name
         FROM game_states
@@ -38,5 +86,12 @@ SELECT
         SELECT COUNT(player_id)
         FROM game_players
         WHERE game_players.game_id = games.id
-    ) AS player_count
+            AND game_players.initiative IS NOT NULL
+    ) AS player_count,
+    -- the host has gone unseen past host_timeout, so players may vote anew
+    COALESCE((
+        SELECT last_seen + host_timeout * INTERVAL '1 second' < LOCALTIMESTAMP
+        FROM game_players
+        WHERE game_players.game_id = games.id AND game_players.initiative = 0
+    ), FALSE)::bool AS host_away
 FROM games WHERE games.id = $1;
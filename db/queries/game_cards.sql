@@ -1,4 +1,7 @@
--- name: GameCardsInitGeneric :exec
+-- fills the deck from the game's chosen deck (the cards of its enabled packs
+-- when none is chosen, or it has since been emptied), up to card_count less
+-- any cards the players already wrote
+-- name: GameCardsInitDeck :exec
 INSERT INTO game_cards (
     game_id, 
     card_id, 
@@ -12,7 +15,44 @@ INSERT INTO game_cards (
     NULL, -- unshuffled
     NULL -- unrevealed
 FROM cards 
-WHERE generic IS TRUE LIMIT (SELECT card_count FROM games WHERE games.id = $1);
+WHERE CASE
+    WHEN EXISTS (
+        SELECT 1 FROM deck_cards
+        WHERE deck_cards.deck_id = (SELECT deck_id FROM games WHERE games.id = $1)
+    ) THEN id IN (
+        SELECT card_id FROM deck_cards
+        WHERE deck_cards.deck_id = (SELECT deck_id FROM games WHERE games.id = $1)
+    )
+    ELSE generic IS TRUE AND retired IS FALSE AND pack IN (
+        SELECT card_packs.slug
+        FROM card_packs
+        LEFT JOIN game_packs
+            ON game_packs.pack = card_packs.slug AND game_packs.game_id = $1
+        WHERE card_packs.retired IS FALSE
+            AND COALESCE(game_packs.enabled, card_packs.enabled)
+    )
+END
+ORDER BY RANDOM()
+LIMIT GREATEST(0,
+    (SELECT card_count FROM games WHERE games.id = $1)
+    - (SELECT COUNT(id) FROM game_cards WHERE game_cards.game_id = $1)
+);
+
+-- deals every unrevealed card round-robin onto the wheel in random order, so
+-- player-written cards mix in with the generic fill. stacks are left for
+-- GameCardsShuffle.
+-- name: GameCardsDeal :exec
+WITH dealt AS (
+    SELECT
+        id,
+        ((ROW_NUMBER() OVER (ORDER BY RANDOM())) % (SELECT wheel_slots FROM games WHERE games.id = $1)) + 1 AS slot
+    FROM game_cards
+    WHERE game_id = $1 AND player_id IS NULL
+)
+UPDATE game_cards
+SET slot = dealt.slot
+FROM dealt
+WHERE game_cards.id = dealt.id;
 
 -- shuffles stacks within a slot, leaving slot assignments unchanged
 -- name: GameCardsShuffle :exec
@@ -89,7 +129,7 @@ SELECT
         )
     ) AS top_card_type
 FROM game_cards
-WHERE game_id = $1 AND player_id IS NULL
+WHERE game_id = $1 AND player_id IS NULL AND slot IS NOT NULL
 ORDER BY slot ASC;
 
 -- sql.ErrNoRows = end of game
@@ -125,14 +165,38 @@ SET player_id = $2, slot = NULL, stack = NULL, updated = CURRENT_TIMESTAMP
 WHERE game_cards.id = (SELECT id FROM resultant_card)
 RETURNING id;
 
--- GameCardCreate :exec
--- TODO: after MVP, implement card creation phase
+-- a player writes a card during the writing phase: it joins the card library
+-- (not generic, the player as creator) and the game's deck, unslotted until
+-- GameCardsDeal puts it on the wheel at start.
+-- name: GameCardCreate :one
+WITH card AS (
+    INSERT INTO cards (type, front, back, creator, generic)
+    VALUES ($2, $3, $4, $5, FALSE)
+    RETURNING id
+)
+INSERT INTO game_cards (game_id, card_id, slot, stack, player_id)
+SELECT $1, id, NULL, NULL, NULL FROM card
+RETURNING id;
+
+-- how many cards each player has written for this game
+-- name: GameCardsWritten :many
+SELECT
+    cards.creator,
+    COUNT(game_cards.id) AS written
+FROM game_cards
+JOIN cards ON cards.id = game_cards.card_id
+WHERE game_cards.game_id = $1
+    AND cards.generic IS FALSE
+    AND game_cards.from_clone IS FALSE
+GROUP BY cards.creator;
 
--- name: GameCardMove :exec
+-- moves a card from holder to player_id, if holder still has it
+-- name: GameCardMove :execrows
 UPDATE game_cards
-SET player_id = $3
-WHERE id = $1
-  AND game_id = $2;
+SET player_id = sqlc.arg(player_id)
+WHERE id = sqlc.arg(id)
+  AND game_id = sqlc.arg(game_id)
+  AND player_id = sqlc.arg(holder);
 
 -- name: GameCardFlip :exec
 UPDATE game_cards
@@ -149,8 +213,104 @@ WHERE game_cards.id = $1
     AND player_id IS NOT NULL
     AND shredded = FALSE;
 
+-- the newest clone of a card that a player still holds, for undoing the
+-- clone that gave it to them
+-- name: GameCardCloneHeld :one
+SELECT clone.id
+FROM game_cards clone
+JOIN game_cards orig ON orig.card_id = clone.card_id
+    AND orig.game_id = clone.game_id
+WHERE orig.id = $1
+    AND clone.game_id = $2
+    AND clone.player_id = $3
+    AND clone.from_clone = TRUE
+    AND clone.shredded = FALSE
+ORDER BY clone.id DESC
+LIMIT 1;
+
 -- name: GameCardShred :exec
 UPDATE game_cards
 SET shredded = TRUE
 WHERE id = $1
   AND game_id = $2;
+
+-- spends every modifier a player holds, as using one does; for a modifier
+-- choice that ran out of time
+-- name: GameCardsShredModifiers :execrows
+UPDATE game_cards
+SET shredded = TRUE
+WHERE game_id = $1
+  AND player_id = $2
+  AND shredded IS NOT TRUE
+  AND card_id IN (SELECT id FROM cards WHERE type = 'modifier');
+
+-- a departing player's cards go back on the wheel, face up, each atop a
+-- random slot; clones have no place on the wheel and are shredded instead
+-- name: GameCardsReturn :execrows
+WITH clones AS (
+    UPDATE game_cards
+    SET shredded = TRUE, updated = CURRENT_TIMESTAMP
+    WHERE game_id = $1
+      AND player_id = $2
+      AND shredded IS NOT TRUE
+      AND from_clone IS TRUE
+),
+picked AS (
+    SELECT game_cards.id, floor(random() * games.wheel_slots)::int + 1 AS slot
+    FROM game_cards
+    JOIN games ON games.id = game_cards.game_id
+    WHERE game_cards.game_id = $1
+      AND game_cards.player_id = $2
+      AND game_cards.shredded IS NOT TRUE
+      AND game_cards.from_clone IS NOT TRUE
+),
+returned AS (
+    SELECT id, slot, ROW_NUMBER() OVER (PARTITION BY slot ORDER BY random()) AS above
+    FROM picked
+)
+UPDATE game_cards
+SET player_id = NULL,
+    flipped = FALSE,
+    slot = returned.slot,
+    stack = returned.above + COALESCE((
+        SELECT MAX(wheel.stack)
+        FROM game_cards wheel
+        WHERE wheel.game_id = $1
+          AND wheel.slot = returned.slot
+    ), 0),
+    updated = CURRENT_TIMESTAMP
+FROM returned
+WHERE game_cards.id = returned.id;
+
+-- shreds every card a departing player holds
+-- name: GameCardsShredHeld :execrows
+UPDATE game_cards
+SET shredded = TRUE, updated = CURRENT_TIMESTAMP
+WHERE game_id = $1
+  AND player_id = $2
+  AND shredded IS NOT TRUE;
+
+-- deals a departing player's cards round the remaining non-host players, in
+-- random order; moves nothing when there's nobody left to take them
+-- name: GameCardsShare :execrows
+WITH heirs AS (
+    SELECT player_id,
+        ROW_NUMBER() OVER (ORDER BY random()) - 1 AS n,
+        COUNT(*) OVER () AS heirs
+    FROM game_players
+    WHERE game_id = $1
+      AND player_id <> $2
+      AND initiative > 0
+),
+held AS (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY random()) - 1 AS n
+    FROM game_cards
+    WHERE game_id = $1
+      AND player_id = $2
+      AND shredded IS NOT TRUE
+)
+UPDATE game_cards
+SET player_id = heirs.player_id, updated = CURRENT_TIMESTAMP
+FROM held
+JOIN heirs ON heirs.n = held.n % heirs.heirs
+WHERE game_cards.id = held.id;
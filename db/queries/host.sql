@@ -0,0 +1,69 @@
+-- hands the host seat (initiative 0) to a player, who gives up their seat to
+-- the old host: a swap, so initiative stays 1..n with no gap for
+-- InitiativeAdvance and the turn order is unchanged. 2 rows = handed off;
+-- fewer means the player left or already holds the seat.
+-- name: HostTransfer :execrows
+WITH seat AS (
+    SELECT initiative
+    FROM game_players
+    WHERE game_id = sqlc.arg(game_id)
+        AND player_id = sqlc.arg(player_id)
+        AND initiative > 0
+)
+UPDATE game_players
+SET initiative = CASE
+    WHEN game_players.initiative = 0 THEN seat.initiative
+    ELSE 0
+END
+FROM seat
+WHERE game_players.game_id = sqlc.arg(game_id)
+    AND (game_players.initiative = 0 OR game_players.player_id = sqlc.arg(player_id));
+
+-- records a player's pick for a new host, replacing any earlier pick
+-- name: HostVoteCast :exec
+INSERT INTO host_votes (game_id, voter_id, candidate_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (game_id, voter_id) DO UPDATE
+SET candidate_id = EXCLUDED.candidate_id, cast_at = LOCALTIMESTAMP;
+
+-- the votes for each candidate, most first. only votes cast since the host
+-- was last seen count, and only between players still seated.
+-- name: HostVotes :many
+SELECT candidate_id, COUNT(voter_id) AS votes
+FROM host_votes
+WHERE host_votes.game_id = $1
+    AND cast_at > COALESCE((
+        SELECT last_seen
+        FROM game_players
+        WHERE game_players.game_id = $1 AND initiative = 0
+    ), '-infinity')
+    AND voter_id IN (
+        SELECT player_id FROM game_players
+        WHERE game_players.game_id = $1 AND initiative > 0
+    )
+    AND candidate_id IN (
+        SELECT player_id FROM game_players
+        WHERE game_players.game_id = $1 AND initiative > 0
+    )
+GROUP BY candidate_id
+ORDER BY votes DESC, candidate_id;
+
+-- name: HostVotesClear :exec
+DELETE FROM host_votes WHERE game_id = $1;
+
+-- games whose host went unseen past host_timeout within the last window
+-- seconds: their players only now may vote a new host
+-- name: GamesHostLapsed :many
+SELECT games.id
+FROM games
+JOIN game_players
+    ON game_players.game_id = games.id AND game_players.initiative = 0
+WHERE games.state_id <> 8
+    AND game_players.last_seen + games.host_timeout * INTERVAL '1 second'
+        BETWEEN LOCALTIMESTAMP - sqlc.arg(window_seconds)::float8 * INTERVAL '1 second'
+        AND LOCALTIMESTAMP;
+
+-- holds the game's row until the transaction ends, so concurrent votes tally
+-- one at a time
+-- name: HostVotesLock :exec
+SELECT id FROM games WHERE id = $1 FOR UPDATE;
@@ -15,6 +15,16 @@ RETURNING id;
 SELECT * FROM infractions
 WHERE id = $1;
 
+-- name: InfractionUndecide :execrows
+-- Reopens a decided infraction, as undoing its verdict does, for the host to
+-- decide again.
+UPDATE infractions
+SET active = TRUE,
+    affirmed = FALSE
+WHERE id = $1
+    AND game_id = $2
+    AND active = FALSE;
+
 -- name: InfractionsByGame :many
 SELECT id, game_id, game_card_id, accused, accuser, created, active, affirmed
 FROM infractions
@@ -25,3 +35,22 @@ ORDER BY created DESC;
 SELECT COUNT(*) FROM infractions
 WHERE game_id = $1
     AND active = TRUE;
+
+-- name: InfractionsTranscript :many
+-- A game's accusations, oldest first, with who made them, against whom, and
+-- the rule at issue as it read (its back, once flipped).
+SELECT
+    inf.id,
+    accuser.name AS accuser_name,
+    accused.name AS accused_name,
+    (CASE WHEN gc.flipped AND c.back IS NOT NULL THEN c.back ELSE c.front END)::text AS rule,
+    inf.active,
+    inf.affirmed,
+    inf.created
+FROM infractions inf
+JOIN players accuser ON accuser.id = inf.accuser
+JOIN players accused ON accused.id = inf.accused
+JOIN game_cards gc ON gc.id = inf.game_card_id
+JOIN cards c ON c.id = gc.card_id
+WHERE inf.game_id = $1
+ORDER BY inf.id;
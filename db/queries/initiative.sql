@@ -19,6 +19,22 @@ SET initiative_current = (
 FROM initiative_max
 WHERE games.id = $1;
 
+-- advances as InitiativeAdvance does, but only from seat: no row when the
+-- turn already moved on
+-- name: InitiativeAdvanceFrom :execrows
+WITH initiative_max AS (
+  SELECT MAX(game_players.initiative) AS highest
+  FROM game_players
+  WHERE game_players.game_id = sqlc.arg(game_id)
+)
+UPDATE games
+SET initiative_current = (
+  games.initiative_current % initiative_max.highest
+) + 1
+FROM initiative_max
+WHERE games.id = sqlc.arg(game_id)
+  AND games.initiative_current = sqlc.arg(seat)::int;
+
 -- name: InitiativeCurrentPlayer :one
 -- The player whose turn it is now (initiative matches the game's current).
 SELECT game_players.player_id
@@ -26,3 +42,27 @@ FROM game_players
 JOIN games ON games.id = game_players.game_id
 WHERE game_players.game_id = $1
     AND game_players.initiative = games.initiative_current;
+
+-- closes the gap a departed player's seat left: later seats move down one and
+-- the turn moves with them, wrapping to the first seat when the last seat
+-- held it, so the turn never lands on an empty seat. Run after the player's
+-- game_players row is gone.
+-- name: InitiativeCompact :exec
+WITH seats AS (
+    UPDATE game_players
+    SET initiative = initiative - 1
+    WHERE game_players.game_id = sqlc.arg(game_id)
+      AND initiative > sqlc.arg(seat)::int
+)
+UPDATE games
+SET initiative_current = CASE
+    WHEN initiative_current > sqlc.arg(seat)::int THEN initiative_current - 1
+    WHEN initiative_current = sqlc.arg(seat)::int AND sqlc.arg(seat)::int > (
+        SELECT COUNT(*)
+        FROM game_players
+        WHERE game_players.game_id = sqlc.arg(game_id)
+          AND initiative > 0
+    ) THEN 1
+    ELSE initiative_current
+END
+WHERE games.id = sqlc.arg(game_id);
@@ -0,0 +1,114 @@
+-- serializes pack syncs between instances that start at the same time; the
+-- lock is released when the transaction ends
+-- name: PacksLock :exec
+SELECT pg_advisory_xact_lock(hashtext('rulette_card_packs'));
+
+-- name: PackVersion :one
+SELECT version, retired
+FROM card_packs
+WHERE slug = $1;
+
+-- name: PackUpsert :exec
+INSERT INTO card_packs (slug, name, description, version, enabled)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (slug) DO UPDATE SET
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    version = EXCLUDED.version,
+    enabled = EXCLUDED.enabled,
+    retired = FALSE,
+    synced = CURRENT_TIMESTAMP;
+
+-- claims a generic card seeded before packs existed, or a card waiting in the
+-- moderation queue, matched by front, so an older database keeps its card ids
+-- (and the games holding them) rather than growing a duplicate; adding a
+-- queued card to a pack is how it's approved
+-- name: PackCardAdopt :exec
+UPDATE cards
+SET pack = sqlc.arg(pack)::text, slug = sqlc.arg(slug)::text, pending = FALSE
+WHERE id = (
+    SELECT id FROM cards
+    WHERE (generic IS TRUE OR pending IS TRUE) AND pack IS NULL AND front = sqlc.arg(front)
+    ORDER BY id
+    LIMIT 1
+) AND NOT EXISTS (
+    SELECT 1 FROM cards
+    WHERE pack = sqlc.arg(pack)::text AND slug = sqlc.arg(slug)::text
+);
+
+-- a reworded card keeps its id, so games already holding it show the new text
+-- name: PackCardUpsert :exec
+INSERT INTO cards (type, front, back, creator, generic, modifier_effect, pack, slug)
+VALUES (
+    sqlc.arg(type),
+    sqlc.arg(front),
+    sqlc.arg(back),
+    0,
+    TRUE,
+    sqlc.arg(modifier_effect),
+    sqlc.arg(pack)::text,
+    sqlc.arg(slug)::text
+)
+ON CONFLICT (pack, slug) DO UPDATE SET
+    type = EXCLUDED.type,
+    front = EXCLUDED.front,
+    back = EXCLUDED.back,
+    modifier_effect = EXCLUDED.modifier_effect,
+    generic = TRUE,
+    retired = FALSE;
+
+-- retires the pack's cards that its file no longer lists. they stay in cards
+-- for the games that dealt them; no new game deals them.
+-- name: PackCardsRetire :execrows
+UPDATE cards
+SET retired = TRUE
+WHERE pack = sqlc.arg(pack)::text
+    AND retired IS FALSE
+    AND NOT (slug = ANY(sqlc.arg(slugs)::text[]));
+
+-- retires the packs no longer embedded, along with their cards
+-- name: PacksRetire :execrows
+WITH gone AS (
+    UPDATE card_packs
+    SET retired = TRUE
+    WHERE retired IS FALSE
+        AND NOT (slug = ANY(sqlc.arg(slugs)::text[]))
+    RETURNING slug
+)
+UPDATE cards
+SET retired = TRUE
+WHERE pack IN (SELECT slug FROM gone);
+
+-- retires seeded generic cards no pack claimed
+-- name: PackOrphansRetire :execrows
+UPDATE cards
+SET retired = TRUE
+WHERE generic IS TRUE AND pack IS NULL AND retired IS FALSE;
+
+-- every live pack, and whether the game plays it: its own choice if the host
+-- made one, else the pack's default
+-- name: GamePacks :many
+SELECT
+    card_packs.slug,
+    card_packs.name,
+    card_packs.description,
+    COALESCE(game_packs.enabled, card_packs.enabled)::bool AS enabled,
+    (
+        SELECT COUNT(id)
+        FROM cards
+        WHERE cards.pack = card_packs.slug AND cards.retired IS FALSE
+    ) AS card_count
+FROM card_packs
+LEFT JOIN game_packs
+    ON game_packs.pack = card_packs.slug AND game_packs.game_id = $1
+WHERE card_packs.retired IS FALSE
+ORDER BY card_packs.slug;
+
+-- records the host's choice for every live pack: on if listed, off otherwise
+-- name: GamePacksSet :exec
+INSERT INTO game_packs (game_id, pack, enabled)
+SELECT sqlc.arg(game_id)::text, slug, slug = ANY(sqlc.arg(enabled)::text[])
+FROM card_packs
+WHERE retired IS FALSE
+ON CONFLICT (game_id, pack) DO UPDATE SET
+    enabled = EXCLUDED.enabled;
@@ -7,15 +7,29 @@ SELECT * FROM players WHERE id = $1;
 -- name: PlayerDelete :exec
 DELETE FROM players WHERE id = $1;
 
--- session_key is expected to be valid for the duration of the game
+-- session_key, hashed, is valid for the duration of the game; points
+-- start at the game's starting_points
 -- name: GamePlayerCreate :exec
-INSERT INTO game_players (game_id, player_id, session_key, initiative)
-VALUES ($1, $2, $3, $4);
+INSERT INTO game_players (game_id, player_id, session_key, initiative, points)
+VALUES ($1, $2, $3, $4, (SELECT starting_points FROM games WHERE games.id = $1));
 
--- name: GamePlayerDelete :exec
+-- removes a player from a game, returning the seat they leave empty
+-- name: GamePlayerDelete :one
 DELETE FROM game_players 
 WHERE game_id = $1 
-	AND player_id = $2;
+	AND player_id = $2
+RETURNING initiative;
+
+-- spends a legacy session, one the migration hashed from plaintext, once
+-- its unsigned cookie brings the key; sessions hashed from the start, and
+-- legacy ones already spent, match nothing
+-- name: GamePlayerSessionUpgrade :execrows
+UPDATE game_players
+SET session_key_legacy = FALSE
+WHERE game_id = $1
+  AND player_id = $2
+  AND session_key = $3
+  AND session_key_legacy IS TRUE;
 
 -- name: GamePointsAdjust :exec
 UPDATE game_players
@@ -30,7 +44,48 @@ SELECT
     (SELECT name FROM players WHERE players.id=game_players.player_id) AS name, 
     points,
     session_key,
-    initiative
+    initiative,
+    bot
 FROM game_players 
 WHERE game_id = $1
+    AND initiative IS NOT NULL
 ORDER BY initiative ASC;
+
+-- spectators watch a game without a seat (no initiative), listed in the
+-- order they came
+-- name: GameSpectators :many
+SELECT
+    player_id,
+    (SELECT name FROM players WHERE players.id=game_players.player_id) AS name,
+    session_key
+FROM game_players
+WHERE game_id = $1
+    AND initiative IS NULL
+ORDER BY joined, player_id;
+
+-- marks a player seen by a poll, action, or stream heartbeat, skipping the
+-- write while the last mark is under 5 seconds old. host_returned: the host
+-- was away until now. no row: the mark was still fresh.
+-- name: PlayerSeen :one
+WITH seen AS (
+    SELECT
+        game_players.player_id,
+        game_players.initiative,
+        game_players.last_seen,
+        games.host_timeout
+    FROM game_players
+    JOIN games ON games.id = game_players.game_id
+    WHERE game_players.game_id = $1 AND game_players.session_key = $2
+)
+UPDATE game_players
+SET last_seen = LOCALTIMESTAMP
+FROM seen
+WHERE game_players.game_id = $1
+    AND game_players.player_id = seen.player_id
+    AND (seen.last_seen IS NULL OR seen.last_seen < LOCALTIMESTAMP - INTERVAL '5 seconds')
+RETURNING (
+    seen.initiative = 0 AND (
+        seen.last_seen IS NULL
+        OR seen.last_seen + seen.host_timeout * INTERVAL '1 second' < LOCALTIMESTAMP
+    )
+)::bool AS host_returned;
@@ -4,3 +4,28 @@
 INSERT INTO point_changes (game_id, player_id, delta, infraction_id)
 VALUES ($1, $2, $3, $4)
 RETURNING id;
+
+-- name: PointChangesByGame :many
+-- A game's points ledger, oldest first: whose balance changed, by how much,
+-- and the event that recorded why ("points" for an affirmed accusation,
+-- "prompt" for a completed prompt, "undo" for either taken back).
+SELECT
+    pc.id,
+    pc.player_id,
+    COALESCE(p.name, '')::text AS player_name,
+    pc.delta,
+    pc.infraction_id,
+    COALESCE(e.event_type, '')::text AS cause,
+    pc.ts
+FROM point_changes pc
+LEFT JOIN players p ON p.id = pc.player_id
+LEFT JOIN event_log e ON e.point_change_id = pc.id
+WHERE pc.game_id = $1
+ORDER BY pc.id;
+
+-- name: PointChangesInfractionNet :one
+-- What an infraction has cost its accused so far, net of any verdicts undone.
+SELECT COALESCE(SUM(delta), 0)::int AS net
+FROM point_changes
+WHERE game_id = $1
+    AND infraction_id = $2;
@@ -0,0 +1,44 @@
+-- name: ReplayCards :many
+-- Every card dealt into a game, clones included, as it stands now. Replay
+-- starts each from the slot it was dealt to and moves it event by event; the
+-- present only settles where a departed player's cards went.
+SELECT
+    gc.id,
+    gc.card_id,
+    gc.slot,
+    gc.player_id,
+    gc.shredded,
+    gc.from_clone,
+    c.type,
+    c.front,
+    c.back
+FROM game_cards gc
+JOIN cards c ON c.id = gc.card_id
+WHERE gc.game_id = $1
+ORDER BY gc.id;
+
+-- name: ReplayEvents :many
+-- Every event of a game, oldest first, with what replaying it takes: the
+-- spin's slot and card, the points change and whose it was, what an undo
+-- reversed, and the names of those involved, the departed included.
+SELECT
+    e.id,
+    e.event_type,
+    e.actor_id,
+    e.target_id,
+    e.game_card_id,
+    actor.name AS actor_name,
+    target.name AS target_name,
+    sp.slot AS spin_slot,
+    sp.card_id AS spin_card_id,
+    pc.player_id AS points_player_id,
+    pc.delta AS points_delta,
+    COALESCE(undone.event_type, '')::text AS undone_type
+FROM event_log e
+LEFT JOIN players actor ON actor.id = e.actor_id
+LEFT JOIN players target ON target.id = e.target_id
+LEFT JOIN spins sp ON sp.id = e.spin_id
+LEFT JOIN point_changes pc ON pc.id = e.point_change_id
+LEFT JOIN event_log undone ON undone.id = e.undo_of
+WHERE e.game_id = $1
+ORDER BY e.id;
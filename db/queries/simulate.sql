@@ -0,0 +1,4 @@
+-- seeds random() for the rest of the session, so a simulated game on a
+-- single connection draws the same wheel every time
+-- name: RandomSeed :exec
+SELECT setseed(sqlc.arg(seed)::float8);
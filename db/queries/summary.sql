@@ -0,0 +1,30 @@
+-- name: SummaryEvents :many
+-- Every event of a game, oldest first, with what the end-of-game summary
+-- counts: who spun, each accusation's parties and verdict, each points
+-- change and the infraction behind it, what each undo reversed, and when each
+-- event happened, for timing turns.
+SELECT
+    e.id,
+    e.event_type,
+    e.ts,
+    e.actor_id,
+    e.target_id,
+    COALESCE(e.infraction_id, pc.infraction_id) AS infraction_id,
+    e.undo_of,
+    actor.name AS actor_name,
+    target.name AS target_name,
+    sp.player_id AS spin_player_id,
+    inf.accused AS infraction_accused,
+    accused.name AS accused_name,
+    inf.affirmed AS infraction_affirmed,
+    pc.player_id AS points_player_id,
+    pc.delta AS points_delta
+FROM event_log e
+LEFT JOIN players actor ON actor.id = e.actor_id
+LEFT JOIN players target ON target.id = e.target_id
+LEFT JOIN spins sp ON sp.id = e.spin_id
+LEFT JOIN infractions inf ON inf.id = e.infraction_id
+LEFT JOIN players accused ON accused.id = inf.accused
+LEFT JOIN point_changes pc ON pc.id = e.point_change_id
+WHERE e.game_id = $1
+ORDER BY e.id;
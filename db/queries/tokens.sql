@@ -0,0 +1,22 @@
+-- name: TokenCreate :one
+INSERT INTO api_tokens (game_id, player_id, token_hash)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- the game player a bearer token stands for, with the session key it
+-- stands in for
+-- name: TokenIdentity :one
+SELECT api_tokens.game_id, api_tokens.player_id, game_players.session_key
+FROM api_tokens
+JOIN game_players
+    ON game_players.game_id = api_tokens.game_id
+    AND game_players.player_id = api_tokens.player_id
+WHERE api_tokens.token_hash = $1;
+
+-- revokes a player's tokens in a game: the one token_id names, or all of
+-- them when it's NULL
+-- name: TokenRevoke :execrows
+DELETE FROM api_tokens
+WHERE game_id = sqlc.arg(game_id)
+    AND player_id = sqlc.arg(player_id)
+    AND (sqlc.narg(token_id)::int IS NULL OR id = sqlc.narg(token_id));
@@ -20,7 +20,8 @@ VALUES
 (5, 'challenge', 'a points challenge is pending'),
 (6, 'prompt', 'a prompt challenge is pending'),
 (7, 'ending', 'deck exhausted, waiting on host to end the game'),
-(8, 'end', 'game over')
+(8, 'end', 'game over'),
+(9, 'writing', 'players are writing their own cards before the start')
 ON CONFLICT (id) DO UPDATE
 	SET name = EXCLUDED.name, description = EXCLUDED.description;
 
@@ -31,8 +32,13 @@ CREATE TABLE IF NOT EXISTS games (
 	state_id INTEGER NOT NULL DEFAULT 0,
 	wheel_slots INTEGER NOT NULL DEFAULT 10, -- number of wheel slots (width)
 	card_count INTEGER NOT NULL DEFAULT 30, -- total cards in the deck (30 / 10 slots = 3 deep)
-	initiative_timer INTEGER NOT NULL DEFAULT 30, -- seconds per turn before auto-advance
+	initiative_timer INTEGER NOT NULL DEFAULT 30, -- seconds per turn before auto-advance (0=off)
 	initiative_current INTEGER DEFAULT 0, -- TODO: is this used?
+	cards_per_player INTEGER NOT NULL DEFAULT 2, -- cards each player writes before the start (0=skip writing)
+	starting_points INTEGER NOT NULL DEFAULT 20, -- points each player joins with
+	prompt_seconds INTEGER NOT NULL DEFAULT 60, -- seconds the spinner has to complete a prompt
+	recommended_players INTEGER NOT NULL DEFAULT 2, -- non-host players to start without confirming
+	host_timeout INTEGER NOT NULL DEFAULT 120, -- seconds the host may go unseen before players can vote a new one
 	FOREIGN KEY (owner_id) REFERENCES players(id) ON DELETE CASCADE,
 	FOREIGN KEY (state_id) REFERENCES game_states(id)
 );
@@ -46,19 +52,71 @@ ALTER TABLE games DROP COLUMN IF EXISTS name;
 ALTER TABLE games ALTER COLUMN wheel_slots SET DEFAULT 10;
 ALTER TABLE games ALTER COLUMN card_count SET DEFAULT 30;
 
+-- the writing phase arrived after games; add its column to older databases.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS cards_per_player INTEGER NOT NULL DEFAULT 2;
+
+-- host settings arrived after games; add their columns to older databases.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS starting_points INTEGER NOT NULL DEFAULT 20;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS prompt_seconds INTEGER NOT NULL DEFAULT 60;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS recommended_players INTEGER NOT NULL DEFAULT 2;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS host_timeout INTEGER NOT NULL DEFAULT 120;
+
 CREATE TABLE IF NOT EXISTS game_players (
 	game_id VARCHAR(6) NOT NULL,
 	player_id INTEGER NOT NULL,
 	points INTEGER DEFAULT 20,
-	session_key TEXT,
+	session_key TEXT, -- sha256 hex of the cookie's key
+	session_key_legacy BOOLEAN DEFAULT FALSE, -- hashed from plaintext below; its unsigned cookie may still be signed
 	-- is_host BOOLEAN DEFAULT FALSE, -- NOTE: host has initiative zero
 	joined TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	initiative INTEGER,
+	last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- last poll, action, or stream heartbeat
+	bot BOOLEAN NOT NULL DEFAULT FALSE, -- seated by the host, plays its own turns
 	PRIMARY KEY (game_id, player_id),
 	FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
 	FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
 );
 
+-- presence arrived after game_players; add its column to older databases.
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+-- as did bots.
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS bot BOOLEAN NOT NULL DEFAULT FALSE;
+-- session keys were stored in plaintext before they were hashed. older
+-- databases hash theirs once, here: the rows the new column leaves null are
+-- exactly those, and they're marked legacy so the cookies holding their keys
+-- can be swapped for signed ones. rows added since default to not legacy.
+ALTER TABLE game_players ADD COLUMN IF NOT EXISTS session_key_legacy BOOLEAN;
+ALTER TABLE game_players ALTER COLUMN session_key_legacy SET DEFAULT FALSE;
+UPDATE game_players
+SET session_key = encode(sha256(convert_to(session_key, 'UTF8')), 'hex'),
+	session_key_legacy = TRUE
+WHERE session_key_legacy IS NULL;
+
+-- host_votes: each player's pick for a new host while the host is away. a
+-- vote only counts if cast since the host was last seen.
+CREATE TABLE IF NOT EXISTS host_votes (
+	game_id VARCHAR(6) NOT NULL,
+	voter_id INTEGER NOT NULL,
+	candidate_id INTEGER NOT NULL,
+	cast_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (game_id, voter_id),
+	FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+	FOREIGN KEY (voter_id) REFERENCES players(id) ON DELETE CASCADE,
+	FOREIGN KEY (candidate_id) REFERENCES players(id) ON DELETE CASCADE
+);
+
+-- api_tokens: bearer tokens a player (or spectator) mints for bots and
+-- scripts, each standing in for their session. only the hash is kept, and
+-- leaving the game takes the player's tokens with them.
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id SERIAL PRIMARY KEY,
+	game_id VARCHAR(6) NOT NULL,
+	player_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE, -- sha256 hex, like session_key
+	created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (game_id, player_id) REFERENCES game_players(game_id, player_id) ON DELETE CASCADE
+);
+
 -- TODO: should is_host be a card?
 CREATE TABLE IF NOT EXISTS card_types (
 	name TEXT NOT NULL UNIQUE,
@@ -101,158 +159,70 @@ CREATE TABLE IF NOT EXISTS cards (
 		OR (type != 'modifier' AND modifier_effect IS NULL)
 	)
 );
-DELETE FROM cards a USING cards b
-WHERE a.id > b.id AND a.front = b.front;
-CREATE UNIQUE INDEX IF NOT EXISTS cards_front_unique ON cards (front);
+-- card packs: the curated (generic) cards, synced from the embedded
+-- cards/*.json files at startup rather than seeded here. a pack card is keyed
+-- by its pack and a stable slug, so a pack can reword a card in place. cards a
+-- pack drops, and packs that disappear, are retired rather than deleted, so
+-- games that already dealt them keep their history.
+CREATE TABLE IF NOT EXISTS card_packs (
+	slug TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL, -- the last synced file version; older files are ignored
+	enabled BOOLEAN NOT NULL DEFAULT TRUE, -- played by games that haven't chosen
+	retired BOOLEAN NOT NULL DEFAULT FALSE,
+	synced TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pack TEXT
+	REFERENCES card_packs(slug) ON DELETE SET NULL;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS retired BOOLEAN NOT NULL DEFAULT FALSE;
+-- generic cards used to be seeded and deduplicated by front; the pack loader
+-- adopts those rows by front on its first sync, then tracks them by slug.
+DROP INDEX IF EXISTS cards_front_unique;
+DROP INDEX IF EXISTS cards_generic_front_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS cards_pack_slug_unique ON cards (pack, slug);
+-- imported cards (from CSV, or a GitHub "new rule" issue) wait for a moderator
+-- to add them to a pack; pending cards are never dealt. source records where
+-- each came from, like "rules.csv:3".
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS source TEXT;
 
-INSERT INTO cards (type, front, back, creator, created, generic, modifier_effect)
-VALUES
-	('modifier', 'flip any of your own cards', '', 0, CURRENT_TIMESTAMP, TRUE, 'flip'),
-	('modifier', 'shred any of your own cards', '', 0, CURRENT_TIMESTAMP, TRUE, 'shred'),
-	('modifier', 'clone any of your own cards, and give to someone else', '', 0, CURRENT_TIMESTAMP, TRUE, 'clone'),
-	('modifier', 'transfer any of your own cards to another player', '', 0, CURRENT_TIMESTAMP, TRUE, 'transfer'),
-	('prompt', 'name 10 green things', NULL, 0, CURRENT_TIMESTAMP, TRUE, NULL),
-	('prompt', 'name 10 blue things', NULL, 0, CURRENT_TIMESTAMP, TRUE, NULL),
-	('prompt', 'spell your name backwards', NULL, 0, CURRENT_TIMESTAMP, TRUE, NULL),
-	('prompt', 'name 10 animals in alphabetical order', NULL, 0, CURRENT_TIMESTAMP, TRUE, NULL),
-	(
-		'rule',
-		'in a whisper',
-		'a little too loudly',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'only single-syllable words',
-		'only 3+ syllable words',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'doing your best Robert De Nero impersonation',
-		'doing your worst Robin Williams impersonation',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'not using any pronouns',
-		'over-ephasizing every pronoun',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule', 
-		'referring to yourself only in the third person',
-		'referring to yourself only using honorifics',
-		0,
-		CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'in a transatlantic accent',
-		'in a valley girl accent',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'while singing',
-		'in a monotone',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'in your best Shakespearian english',
-		'using all the contemporary slang you can',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'never saying "um"',
-		'saying "um" every other word',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'doing your best Matthew McConaughey impersonation',
-		'doing your worst Jack Nicholson impersonation',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'as if everything is juicy gossip',
-		'as if everything is really boring',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'while trying to incite a revolution',
-		'while trying to calm everyone down',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'like your mouth is full of marshmallows',
-		'like you have horrible cottonmouth',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'name dropping every sentence',
-		'not using any names',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'speaking only in haiku',
-		'rhyming every sentence',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'always starting with a compliment',
-		'always self-aggrandizing',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'starting every sentence with the next letter of the alphabet',
-		'starting every word with the next letter of the alphabet',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'starting with a fun fact',
-		'starting with a slightly upsetting fact',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'end every sentence with a famous full name',
-		'end every sentence with a different zoo animal',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'as if everything is a question',
-		'as if everything is a definite answer',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'like a sychophantic LLM',
-		'like a clerk that hates everyone',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	),
-	(
-		'rule',
-		'with vocal fry',
-		'over-enunciating',
-		0, CURRENT_TIMESTAMP, TRUE, NULL
-	)
-ON CONFLICT (front) DO UPDATE SET
-	back = EXCLUDED.back,
-	type = EXCLUDED.type,
-	generic = EXCLUDED.generic,
-	modifier_effect = EXCLUDED.modifier_effect;
+-- decks: saved sets of cards a host can seed the wheel with instead of the
+-- generic cards (a game with no deck_id plays the generic deck). anyone can
+-- list and play a deck; only the holder of the edit key, handed to its
+-- creator, can change it. edit_key stores the key's sha256, in hex.
+CREATE TABLE IF NOT EXISTS decks (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	edit_key TEXT NOT NULL,
+	created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS deck_cards (
+	deck_id INTEGER NOT NULL,
+	card_id INTEGER NOT NULL,
+	added TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (deck_id, card_id),
+	FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE CASCADE,
+	FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+);
+
+-- the deck a game's wheel is seeded from (NULL=generic). added after decks
+-- exists so the reference resolves, on fresh and older databases alike.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS deck_id INTEGER
+	REFERENCES decks(id) ON DELETE SET NULL;
+
+-- a game's pack choices, overriding card_packs.enabled; a game with no row for
+-- a pack plays it only if the pack is enabled by default.
+CREATE TABLE IF NOT EXISTS game_packs (
+	game_id VARCHAR(6) NOT NULL,
+	pack TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	PRIMARY KEY (game_id, pack),
+	FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+	FOREIGN KEY (pack) REFERENCES card_packs(slug) ON DELETE CASCADE
+);
 
 -- card_id lacks primary key to allow cloning within a game,
 CREATE TABLE IF NOT EXISTS game_cards (
@@ -303,9 +273,12 @@ CREATE TABLE IF NOT EXISTS infractions (
 
 CREATE UNLOGGED TABLE IF NOT EXISTS game_cache (
 	game_id VARCHAR(6) PRIMARY KEY,
-	value JSONB,
-	expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 seconds' -- TODO: make var?
+	value JSONB, -- NULL once invalidated, until the next fetch stores it
+	version BIGINT NOT NULL DEFAULT 0, -- bumped by each invalidation
+	expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 seconds' -- CacheSet supplies sharedCacheAge
 );
+-- the version arrived after game_cache; add its column to older databases.
+ALTER TABLE game_cache ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
 
 CREATE TABLE IF NOT EXISTS event_types (
 	name TEXT PRIMARY KEY,
@@ -328,7 +301,14 @@ VALUES
 	('clone', 'a card was cloned'),
 	('transfer', 'a card was transferred'),
 	('continue', 'host continued the game after deck exhaustion'),
-	('prompt', 'a player completed or failed a prompt challenge')
+	('prompt', 'a player completed or failed a prompt challenge'),
+	('writing', 'host opened the card-writing phase'),
+	('write', 'a player wrote a card'),
+	('timeout', 'a turn ran out of time and was moved on'),
+	('host', 'the host seat passed to another player'),
+	('leave', 'a player left the game'),
+	('kick', 'host removed a player from the game'),
+	('undo', 'host reversed an earlier event')
 ON CONFLICT (name) DO UPDATE
 	SET description = EXCLUDED.description;
 
@@ -361,6 +341,7 @@ CREATE TABLE IF NOT EXISTS event_log (
 	infraction_id INTEGER, -- accuse/decide events
 	game_card_id INTEGER, -- flip/shred/clone/transfer events
 	point_change_id INTEGER, -- points events
+	undo_of INTEGER, -- undo events: the event reversed
 	ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
 	FOREIGN KEY (event_type) REFERENCES event_types(name),
@@ -369,6 +350,12 @@ CREATE TABLE IF NOT EXISTS event_log (
 	FOREIGN KEY (spin_id) REFERENCES spins(id) ON DELETE SET NULL,
 	FOREIGN KEY (infraction_id) REFERENCES infractions(id) ON DELETE SET NULL,
 	FOREIGN KEY (game_card_id) REFERENCES game_cards(id) ON DELETE SET NULL,
-	FOREIGN KEY (point_change_id) REFERENCES point_changes(id) ON DELETE SET NULL
+	FOREIGN KEY (point_change_id) REFERENCES point_changes(id) ON DELETE SET NULL,
+	FOREIGN KEY (undo_of) REFERENCES event_log(id) ON DELETE SET NULL
 );
 CREATE INDEX IF NOT EXISTS event_log_game_id_idx ON event_log (game_id, id);
+-- undo arrived after event_log; add its column to older databases. an event
+-- is undone at most once.
+ALTER TABLE event_log ADD COLUMN IF NOT EXISTS undo_of INTEGER
+	REFERENCES event_log(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS event_log_undo_of_unique ON event_log (undo_of);
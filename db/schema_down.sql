@@ -2,6 +2,7 @@ DROP TABLE IF EXISTS players CASCADE;
 DROP TABLE IF EXISTS games CASCADE;
 DROP TABLE IF EXISTS game_states CASCADE;
 DROP TABLE IF EXISTS game_players CASCADE;
+DROP TABLE IF EXISTS api_tokens CASCADE;
 DROP TABLE IF EXISTS card_types CASCADE;
 DROP TABLE IF EXISTS modifier_effects CASCADE;
 DROP TABLE IF EXISTS cards CASCADE;
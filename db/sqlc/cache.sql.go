@@ -19,9 +19,28 @@ func (q *Queries) CacheClean(ctx context.Context) error {
 	return err
 }
 
+const cacheClear = `-- name: CacheClear :exec
+INSERT INTO game_cache (game_id, value, version, expires)
+VALUES ($1, NULL, 1, CURRENT_TIMESTAMP + INTERVAL '1 minute')
+ON CONFLICT (game_id) DO UPDATE SET
+    value = NULL,
+    version = game_cache.version + 1,
+    expires = EXCLUDED.expires
+`
+
+// Drops a game's cached state and bumps its version, so a fetch that started
+// before can't store what it read. The emptied row outlives any fetch, then
+// CacheClean sweeps it.
+func (q *Queries) CacheClear(ctx context.Context, gameID string) error {
+	_, err := q.db.Exec(ctx, cacheClear, gameID)
+	return err
+}
+
 const cacheGet = `-- name: CacheGet :one
 SELECT value FROM game_cache
-WHERE game_id = $1 AND expires > CURRENT_TIMESTAMP
+WHERE game_id = $1
+    AND value IS NOT NULL
+    AND expires > CURRENT_TIMESTAMP
 `
 
 func (q *Queries) CacheGet(ctx context.Context, gameID string) ([]byte, error) {
@@ -31,20 +50,58 @@ func (q *Queries) CacheGet(ctx context.Context, gameID string) ([]byte, error) {
 	return value, err
 }
 
-const cacheSet = `-- name: CacheSet :exec
-INSERT INTO game_cache (game_id, value, expires)
-VALUES ($1, $2, CURRENT_TIMESTAMP + INTERVAL '1 second') -- TODO: make var?
+const cacheInvalidate = `-- name: CacheInvalidate :exec
+SELECT pg_notify('rulette_cache', $1::text)
+`
+
+// Broadcasts a game's cache invalidation to every instance LISTENing on the
+// rulette_cache channel. payload is "{instance_id}:{game_id}". Inside a
+// transaction, Postgres holds the notification until commit.
+func (q *Queries) CacheInvalidate(ctx context.Context, payload string) error {
+	_, err := q.db.Exec(ctx, cacheInvalidate, payload)
+	return err
+}
+
+const cacheSet = `-- name: CacheSet :execrows
+INSERT INTO game_cache (game_id, value, version, expires)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP + ($4::int * INTERVAL '1 millisecond'))
 ON CONFLICT (game_id) DO UPDATE SET 
     value = EXCLUDED.value, 
     expires = EXCLUDED.expires
+WHERE game_cache.version = EXCLUDED.version
 `
 
 type CacheSetParams struct {
-	GameID string `json:"game_id"`
-	Value  []byte `json:"value"`
+	GameID  string `json:"game_id"`
+	Value   []byte `json:"value"`
+	Version int64  `json:"version"`
+	TtlMs   int32  `json:"ttl_ms"`
 }
 
-func (q *Queries) CacheSet(ctx context.Context, arg CacheSetParams) error {
-	_, err := q.db.Exec(ctx, cacheSet, arg.GameID, arg.Value)
-	return err
+// Stores a game's serialized state for ttl_ms milliseconds, as read at
+// version. An invalidation since bumped the version, and then nothing is
+// stored: the state is already stale.
+func (q *Queries) CacheSet(ctx context.Context, arg CacheSetParams) (int64, error) {
+	result, err := q.db.Exec(ctx, cacheSet,
+		arg.GameID,
+		arg.Value,
+		arg.Version,
+		arg.TtlMs,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cacheVersion = `-- name: CacheVersion :one
+SELECT COALESCE((SELECT version FROM game_cache WHERE game_id = $1), 0)::bigint AS version
+`
+
+// A game's cache version, to read before fetching its state and store it at.
+func (q *Queries) CacheVersion(ctx context.Context, gameID string) (int64, error) {
+	row := q.db.QueryRow(ctx, cacheVersion, gameID)
+	var version int64
+	err := row.Scan(&version)
+	return version, err
 }
@@ -12,7 +12,7 @@ import (
 )
 
 const card = `-- name: Card :one
-SELECT id, type, front, back, creator, created, generic, modifier_effect FROM cards WHERE id = $1
+SELECT id, type, front, back, creator, created, generic, modifier_effect, pack, slug, retired, pending, source FROM cards WHERE id = $1
 `
 
 func (q *Queries) Card(ctx context.Context, id int32) (Cards, error) {
@@ -27,6 +27,11 @@ func (q *Queries) Card(ctx context.Context, id int32) (Cards, error) {
 		&i.Created,
 		&i.Generic,
 		&i.ModifierEffect,
+		&i.Pack,
+		&i.Slug,
+		&i.Retired,
+		&i.Pending,
+		&i.Source,
 	)
 	return i, err
 }
@@ -62,3 +67,103 @@ func (q *Queries) CardDelete(ctx context.Context, id int32) error {
 	_, err := q.db.Exec(ctx, cardDelete, id)
 	return err
 }
+
+const cardImport = `-- name: CardImport :one
+INSERT INTO cards (type, front, back, generic, modifier_effect, pending, source)
+SELECT
+    $1::text,
+    $2::text,
+    $3::text,
+    FALSE,
+    $4::text,
+    TRUE,
+    $5::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM cards
+    WHERE front = $2::text
+        AND (pending IS TRUE OR (generic IS TRUE AND retired IS FALSE))
+)
+RETURNING id
+`
+
+type CardImportParams struct {
+	Type           string      `json:"type"`
+	Front          string      `json:"front"`
+	Back           pgtype.Text `json:"back"`
+	ModifierEffect pgtype.Text `json:"modifier_effect"`
+	Source         string      `json:"source"`
+}
+
+// queues an imported card for moderation, unless the same front is already
+// queued or in a live pack (no row: a duplicate)
+func (q *Queries) CardImport(ctx context.Context, arg CardImportParams) (int32, error) {
+	row := q.db.QueryRow(ctx, cardImport,
+		arg.Type,
+		arg.Front,
+		arg.Back,
+		arg.ModifierEffect,
+		arg.Source,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const cardsPending = `-- name: CardsPending :many
+SELECT id, type, front, back, modifier_effect, source
+FROM cards
+WHERE pending IS TRUE
+ORDER BY id
+`
+
+type CardsPendingRow struct {
+	ID             int32       `json:"id"`
+	Type           string      `json:"type"`
+	Front          string      `json:"front"`
+	Back           pgtype.Text `json:"back"`
+	ModifierEffect pgtype.Text `json:"modifier_effect"`
+	Source         pgtype.Text `json:"source"`
+}
+
+// the moderation queue, oldest first
+func (q *Queries) CardsPending(ctx context.Context) ([]CardsPendingRow, error) {
+	rows, err := q.db.Query(ctx, cardsPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CardsPendingRow
+	for rows.Next() {
+		var i CardsPendingRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Front,
+			&i.Back,
+			&i.ModifierEffect,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const cardsReject = `-- name: CardsReject :execrows
+DELETE FROM cards
+WHERE id = ANY($1::int[]) AND pending IS TRUE
+`
+
+// drops rejected cards from the moderation queue; ids no longer queued are
+// left alone
+func (q *Queries) CardsReject(ctx context.Context, ids []int32) (int64, error) {
+	result, err := q.db.Exec(ctx, cardsReject, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -14,9 +14,9 @@ import (
 const eventCreate = `-- name: EventCreate :one
 INSERT INTO event_log (
     game_id, event_type, actor_id, target_id,
-    spin_id, infraction_id, game_card_id, point_change_id
+    spin_id, infraction_id, game_card_id, point_change_id, undo_of
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id
 `
 
@@ -29,10 +29,12 @@ type EventCreateParams struct {
 	InfractionID  pgtype.Int4 `json:"infraction_id"`
 	GameCardID    pgtype.Int4 `json:"game_card_id"`
 	PointChangeID pgtype.Int4 `json:"point_change_id"`
+	UndoOf        pgtype.Int4 `json:"undo_of"`
 }
 
 // Appends one event to the log. Detail FKs (spin_id, infraction_id,
-// game_card_id, point_change_id) are set per event_type; the rest are NULL.
+// game_card_id, point_change_id, undo_of) are set per event_type; the rest
+// are NULL.
 func (q *Queries) EventCreate(ctx context.Context, arg EventCreateParams) (int32, error) {
 	row := q.db.QueryRow(ctx, eventCreate,
 		arg.GameID,
@@ -43,12 +45,63 @@ func (q *Queries) EventCreate(ctx context.Context, arg EventCreateParams) (int32
 		arg.InfractionID,
 		arg.GameCardID,
 		arg.PointChangeID,
+		arg.UndoOf,
 	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const eventLastUndoable = `-- name: EventLastUndoable :one
+SELECT
+    e.id,
+    e.event_type,
+    e.actor_id,
+    e.target_id,
+    e.infraction_id,
+    e.game_card_id,
+    pc.player_id AS points_player_id,
+    pc.delta AS points_delta
+FROM event_log e
+LEFT JOIN point_changes pc ON pc.id = e.point_change_id
+WHERE e.game_id = $1
+    AND (e.event_type IN ('decide', 'transfer', 'clone')
+        OR (e.event_type = 'prompt' AND e.point_change_id IS NOT NULL))
+    AND NOT EXISTS (SELECT 1 FROM event_log u WHERE u.undo_of = e.id)
+ORDER BY e.id DESC
+LIMIT 1
+`
+
+type EventLastUndoableRow struct {
+	ID             int32       `json:"id"`
+	EventType      string      `json:"event_type"`
+	ActorID        pgtype.Int4 `json:"actor_id"`
+	TargetID       pgtype.Int4 `json:"target_id"`
+	InfractionID   pgtype.Int4 `json:"infraction_id"`
+	GameCardID     pgtype.Int4 `json:"game_card_id"`
+	PointsPlayerID pgtype.Int4 `json:"points_player_id"`
+	PointsDelta    pgtype.Int4 `json:"points_delta"`
+}
+
+// The game's newest event the host can still undo: a verdict, a prompt's
+// points, a transfer or a clone that no undo event has reversed yet. Undoing
+// again walks further back. The points change it made, if any, comes along.
+func (q *Queries) EventLastUndoable(ctx context.Context, gameID string) (EventLastUndoableRow, error) {
+	row := q.db.QueryRow(ctx, eventLastUndoable, gameID)
+	var i EventLastUndoableRow
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.ActorID,
+		&i.TargetID,
+		&i.InfractionID,
+		&i.GameCardID,
+		&i.PointsPlayerID,
+		&i.PointsDelta,
+	)
+	return i, err
+}
+
 const eventListSince = `-- name: EventListSince :many
 SELECT
     e.id,
@@ -64,7 +117,8 @@ SELECT
     COALESCE(c.front, '')::text AS card_front,
     COALESCE(c.back, '')::text AS card_back,
     COALESCE(c.type, '')::text AS card_type,
-    COALESCE(gc.flipped, inf_gc.flipped, FALSE) AS card_flipped
+    COALESCE(gc.flipped, inf_gc.flipped, FALSE) AS card_flipped,
+    COALESCE(undone.event_type, '')::text AS undone_type
 FROM event_log e
 LEFT JOIN players actor ON actor.id = e.actor_id
 LEFT JOIN players target ON target.id = e.target_id
@@ -74,14 +128,17 @@ LEFT JOIN game_cards gc ON gc.id = e.game_card_id
 LEFT JOIN spins sp ON sp.id = e.spin_id
 LEFT JOIN game_cards inf_gc ON inf_gc.id = inf.game_card_id
 LEFT JOIN cards c ON c.id = COALESCE(gc.card_id, sp.card_id, inf_gc.card_id)
+LEFT JOIN event_log undone ON undone.id = e.undo_of
 WHERE e.game_id = $1
     AND e.id > $2
 ORDER BY e.id
+LIMIT $3
 `
 
 type EventListSinceParams struct {
-	GameID string `json:"game_id"`
-	ID     int32  `json:"id"`
+	GameID    string      `json:"game_id"`
+	ID        int32       `json:"id"`
+	MaxEvents pgtype.Int4 `json:"max_events"`
 }
 
 type EventListSinceRow struct {
@@ -95,12 +152,15 @@ type EventListSinceRow struct {
 	CardBack           string      `json:"card_back"`
 	CardType           string      `json:"card_type"`
 	CardFlipped        bool        `json:"card_flipped"`
+	UndoneType         string      `json:"undone_type"`
 }
 
 // Events for a game newer than a given id, oldest first, with the names and
 // details the feed text and sounds need. Clients track the highest id seen.
+// undone_type is what an undo event reversed, empty for other events.
+// max_events caps how many come back; NULL for all of them.
 func (q *Queries) EventListSince(ctx context.Context, arg EventListSinceParams) ([]EventListSinceRow, error) {
-	rows, err := q.db.Query(ctx, eventListSince, arg.GameID, arg.ID)
+	rows, err := q.db.Query(ctx, eventListSince, arg.GameID, arg.ID, arg.MaxEvents)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +179,7 @@ func (q *Queries) EventListSince(ctx context.Context, arg EventListSinceParams)
 			&i.CardBack,
 			&i.CardType,
 			&i.CardFlipped,
+			&i.UndoneType,
 		); err != nil {
 			return nil, err
 		}
@@ -129,3 +190,14 @@ func (q *Queries) EventListSince(ctx context.Context, arg EventListSinceParams)
 	}
 	return items, nil
 }
+
+const eventUndoLock = `-- name: EventUndoLock :exec
+SELECT id FROM games WHERE id = $1 FOR UPDATE
+`
+
+// Holds the game's row until the transaction ends, so undos run one at a time
+// and each finds the event the last one left.
+func (q *Queries) EventUndoLock(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, eventUndoLock, id)
+	return err
+}
@@ -27,6 +27,22 @@ func (q *Queries) GameCreate(ctx context.Context, arg GameCreateParams) error {
 	return err
 }
 
+const gameDeckSet = `-- name: GameDeckSet :exec
+UPDATE games
+SET deck_id = $2
+WHERE id = $1
+`
+
+type GameDeckSetParams struct {
+	ID     string      `json:"id"`
+	DeckID pgtype.Int4 `json:"deck_id"`
+}
+
+func (q *Queries) GameDeckSet(ctx context.Context, arg GameDeckSetParams) error {
+	_, err := q.db.Exec(ctx, gameDeckSet, arg.ID, arg.DeckID)
+	return err
+}
+
 const gameDelete = `-- name: GameDelete :exec
 DELETE FROM games WHERE id = $1
 `
@@ -36,12 +52,79 @@ func (q *Queries) GameDelete(ctx context.Context, id string) error {
 	return err
 }
 
+const gameSettingsSet = `-- name: GameSettingsSet :execrows
+WITH players AS (
+    UPDATE game_players
+    SET points = $1
+    WHERE game_players.game_id = $2 AND EXISTS (
+        SELECT 1 FROM games WHERE games.id = $2 AND state_id IN (0, 1)
+    )
+)
+UPDATE games
+SET wheel_slots = $3,
+    card_count = $4,
+    initiative_timer = $5,
+    cards_per_player = $6,
+    starting_points = $1,
+    prompt_seconds = $7,
+    recommended_players = $8,
+    host_timeout = $9
+WHERE games.id = $2 AND state_id IN (0, 1)
+`
+
+type GameSettingsSetParams struct {
+	StartingPoints     int32  `json:"starting_points"`
+	ID                 string `json:"id"`
+	WheelSlots         int32  `json:"wheel_slots"`
+	CardCount          int32  `json:"card_count"`
+	InitiativeTimer    int32  `json:"initiative_timer"`
+	CardsPerPlayer     int32  `json:"cards_per_player"`
+	PromptSeconds      int32  `json:"prompt_seconds"`
+	RecommendedPlayers int32  `json:"recommended_players"`
+	HostTimeout        int32  `json:"host_timeout"`
+}
+
+// saves the host's settings, only before the start (no row: it started).
+// players who already joined take the new starting points, since nobody has
+// scored yet.
+func (q *Queries) GameSettingsSet(ctx context.Context, arg GameSettingsSetParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameSettingsSet,
+		arg.StartingPoints,
+		arg.ID,
+		arg.WheelSlots,
+		arg.CardCount,
+		arg.InitiativeTimer,
+		arg.CardsPerPlayer,
+		arg.PromptSeconds,
+		arg.RecommendedPlayers,
+		arg.HostTimeout,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const gameState = `-- name: GameState :one
 SELECT
     id,
     owner_id,
     state_id,
     initiative_current,
+    initiative_timer,
+    cards_per_player,
+    wheel_slots,
+    card_count,
+    starting_points,
+    prompt_seconds,
+    recommended_players,
+    host_timeout,
+    deck_id,
+    (
+        SELECT name
+        FROM decks
+        WHERE decks.id = deck_id
+    ) AS deck_name,
     (
         SELECT name
         FROM game_states
@@ -56,18 +139,36 @@ SELECT
         SELECT COUNT(player_id)
         FROM game_players
         WHERE game_players.game_id = games.id
-    ) AS player_count
+            AND game_players.initiative IS NOT NULL
+    ) AS player_count,
+    -- the host has gone unseen past host_timeout, so players may vote anew
+    COALESCE((
+        SELECT last_seen + host_timeout * INTERVAL '1 second' < LOCALTIMESTAMP
+        FROM game_players
+        WHERE game_players.game_id = games.id AND game_players.initiative = 0
+    ), FALSE)::bool AS host_away
 FROM games WHERE games.id = $1
 `
 
 type GameStateRow struct {
-	ID                string      `json:"id"`
-	OwnerID           pgtype.Int4 `json:"owner_id"`
-	StateID           int32       `json:"state_id"`
-	InitiativeCurrent pgtype.Int4 `json:"initiative_current"`
-	StateName         string      `json:"state_name"`
-	StateDescription  pgtype.Text `json:"state_description"`
-	PlayerCount       int64       `json:"player_count"`
+	ID                 string      `json:"id"`
+	OwnerID            pgtype.Int4 `json:"owner_id"`
+	StateID            int32       `json:"state_id"`
+	InitiativeCurrent  pgtype.Int4 `json:"initiative_current"`
+	InitiativeTimer    int32       `json:"initiative_timer"`
+	CardsPerPlayer     int32       `json:"cards_per_player"`
+	WheelSlots         int32       `json:"wheel_slots"`
+	CardCount          int32       `json:"card_count"`
+	StartingPoints     int32       `json:"starting_points"`
+	PromptSeconds      int32       `json:"prompt_seconds"`
+	RecommendedPlayers int32       `json:"recommended_players"`
+	HostTimeout        int32       `json:"host_timeout"`
+	DeckID             pgtype.Int4 `json:"deck_id"`
+	DeckName           pgtype.Text `json:"deck_name"`
+	StateName          string      `json:"state_name"`
+	StateDescription   pgtype.Text `json:"state_description"`
+	PlayerCount        int64       `json:"player_count"`
+	HostAway           bool        `json:"host_away"`
 }
 
 func (q *Queries) GameState(ctx context.Context, id string) (GameStateRow, error) {
@@ -78,13 +179,46 @@ func (q *Queries) GameState(ctx context.Context, id string) (GameStateRow, error
 		&i.OwnerID,
 		&i.StateID,
 		&i.InitiativeCurrent,
+		&i.InitiativeTimer,
+		&i.CardsPerPlayer,
+		&i.WheelSlots,
+		&i.CardCount,
+		&i.StartingPoints,
+		&i.PromptSeconds,
+		&i.RecommendedPlayers,
+		&i.HostTimeout,
+		&i.DeckID,
+		&i.DeckName,
 		&i.StateName,
 		&i.StateDescription,
 		&i.PlayerCount,
+		&i.HostAway,
 	)
 	return i, err
 }
 
+const gameStateTransition = `-- name: GameStateTransition :execrows
+UPDATE games
+SET state_id = $1
+WHERE id = $2 AND state_id = $3
+`
+
+type GameStateTransitionParams struct {
+	ToState   int32  `json:"to_state"`
+	ID        string `json:"id"`
+	FromState int32  `json:"from_state"`
+}
+
+// moves a game from one state to another only if it is still in the first,
+// so concurrent requests can't both make the same transition. 0 rows = lost.
+func (q *Queries) GameStateTransition(ctx context.Context, arg GameStateTransitionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameStateTransition, arg.ToState, arg.ID, arg.FromState)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const gameUpdate = `-- name: GameUpdate :exec
 UPDATE games
 SET state_id = $1, initiative_current = $2
@@ -103,7 +237,7 @@ func (q *Queries) GameUpdate(ctx context.Context, arg GameUpdateParams) error {
 }
 
 const games = `-- name: Games :many
-SELECT id, created, owner_id, state_id, wheel_slots, card_count, initiative_timer, initiative_current FROM games WHERE id = (
+SELECT id, created, owner_id, state_id, wheel_slots, card_count, initiative_timer, initiative_current, cards_per_player, starting_points, prompt_seconds, recommended_players, host_timeout, deck_id FROM games WHERE id = (
 	SELECT game_id 
 	FROM game_players
 	WHERE player_id = $1
@@ -128,6 +262,12 @@ func (q *Queries) Games(ctx context.Context, playerID int32) ([]Games, error) {
 			&i.CardCount,
 			&i.InitiativeTimer,
 			&i.InitiativeCurrent,
+			&i.CardsPerPlayer,
+			&i.StartingPoints,
+			&i.PromptSeconds,
+			&i.RecommendedPlayers,
+			&i.HostTimeout,
+			&i.DeckID,
 		); err != nil {
 			return nil, err
 		}
@@ -32,6 +32,70 @@ func (q *Queries) GameCardClone(ctx context.Context, arg GameCardCloneParams) er
 	return err
 }
 
+const gameCardCloneHeld = `-- name: GameCardCloneHeld :one
+SELECT clone.id
+FROM game_cards clone
+JOIN game_cards orig ON orig.card_id = clone.card_id
+    AND orig.game_id = clone.game_id
+WHERE orig.id = $1
+    AND clone.game_id = $2
+    AND clone.player_id = $3
+    AND clone.from_clone = TRUE
+    AND clone.shredded = FALSE
+ORDER BY clone.id DESC
+LIMIT 1
+`
+
+type GameCardCloneHeldParams struct {
+	ID       int32       `json:"id"`
+	GameID   string      `json:"game_id"`
+	PlayerID pgtype.Int4 `json:"player_id"`
+}
+
+// the newest clone of a card that a player still holds, for undoing the
+// clone that gave it to them
+func (q *Queries) GameCardCloneHeld(ctx context.Context, arg GameCardCloneHeldParams) (int32, error) {
+	row := q.db.QueryRow(ctx, gameCardCloneHeld, arg.ID, arg.GameID, arg.PlayerID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const gameCardCreate = `-- name: GameCardCreate :one
+WITH card AS (
+    INSERT INTO cards (type, front, back, creator, generic)
+    VALUES ($2, $3, $4, $5, FALSE)
+    RETURNING id
+)
+INSERT INTO game_cards (game_id, card_id, slot, stack, player_id)
+SELECT $1, id, NULL, NULL, NULL FROM card
+RETURNING id
+`
+
+type GameCardCreateParams struct {
+	GameID  string      `json:"game_id"`
+	Type    string      `json:"type"`
+	Front   string      `json:"front"`
+	Back    pgtype.Text `json:"back"`
+	Creator pgtype.Int4 `json:"creator"`
+}
+
+// a player writes a card during the writing phase: it joins the card library
+// (not generic, the player as creator) and the game's deck, unslotted until
+// GameCardsDeal puts it on the wheel at start.
+func (q *Queries) GameCardCreate(ctx context.Context, arg GameCardCreateParams) (int32, error) {
+	row := q.db.QueryRow(ctx, gameCardCreate,
+		arg.GameID,
+		arg.Type,
+		arg.Front,
+		arg.Back,
+		arg.Creator,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const gameCardFlip = `-- name: GameCardFlip :exec
 UPDATE game_cards
 SET flipped = NOT flipped
@@ -49,25 +113,33 @@ func (q *Queries) GameCardFlip(ctx context.Context, arg GameCardFlipParams) erro
 	return err
 }
 
-const gameCardMove = `-- name: GameCardMove :exec
-
+const gameCardMove = `-- name: GameCardMove :execrows
 UPDATE game_cards
-SET player_id = $3
-WHERE id = $1
-  AND game_id = $2
+SET player_id = $1
+WHERE id = $2
+  AND game_id = $3
+  AND player_id = $4
 `
 
 type GameCardMoveParams struct {
+	PlayerID pgtype.Int4 `json:"player_id"`
 	ID       int32       `json:"id"`
 	GameID   string      `json:"game_id"`
-	PlayerID pgtype.Int4 `json:"player_id"`
+	Holder   pgtype.Int4 `json:"holder"`
 }
 
-// GameCardCreate :exec
-// TODO: after MVP, implement card creation phase
-func (q *Queries) GameCardMove(ctx context.Context, arg GameCardMoveParams) error {
-	_, err := q.db.Exec(ctx, gameCardMove, arg.ID, arg.GameID, arg.PlayerID)
-	return err
+// moves a card from holder to player_id, if holder still has it
+func (q *Queries) GameCardMove(ctx context.Context, arg GameCardMoveParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameCardMove,
+		arg.PlayerID,
+		arg.ID,
+		arg.GameID,
+		arg.Holder,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const gameCardShred = `-- name: GameCardShred :exec
@@ -87,7 +159,29 @@ func (q *Queries) GameCardShred(ctx context.Context, arg GameCardShredParams) er
 	return err
 }
 
-const gameCardsInitGeneric = `-- name: GameCardsInitGeneric :exec
+const gameCardsDeal = `-- name: GameCardsDeal :exec
+WITH dealt AS (
+    SELECT
+        id,
+        ((ROW_NUMBER() OVER (ORDER BY RANDOM())) % (SELECT wheel_slots FROM games WHERE games.id = $1)) + 1 AS slot
+    FROM game_cards
+    WHERE game_id = $1 AND player_id IS NULL
+)
+UPDATE game_cards
+SET slot = dealt.slot
+FROM dealt
+WHERE game_cards.id = dealt.id
+`
+
+// deals every unrevealed card round-robin onto the wheel in random order, so
+// player-written cards mix in with the generic fill. stacks are left for
+// GameCardsShuffle.
+func (q *Queries) GameCardsDeal(ctx context.Context, gameID string) error {
+	_, err := q.db.Exec(ctx, gameCardsDeal, gameID)
+	return err
+}
+
+const gameCardsInitDeck = `-- name: GameCardsInitDeck :exec
 INSERT INTO game_cards (
     game_id, 
     card_id, 
@@ -101,11 +195,35 @@ INSERT INTO game_cards (
     NULL, -- unshuffled
     NULL -- unrevealed
 FROM cards 
-WHERE generic IS TRUE LIMIT (SELECT card_count FROM games WHERE games.id = $1)
+WHERE CASE
+    WHEN EXISTS (
+        SELECT 1 FROM deck_cards
+        WHERE deck_cards.deck_id = (SELECT deck_id FROM games WHERE games.id = $1)
+    ) THEN id IN (
+        SELECT card_id FROM deck_cards
+        WHERE deck_cards.deck_id = (SELECT deck_id FROM games WHERE games.id = $1)
+    )
+    ELSE generic IS TRUE AND retired IS FALSE AND pack IN (
+        SELECT card_packs.slug
+        FROM card_packs
+        LEFT JOIN game_packs
+            ON game_packs.pack = card_packs.slug AND game_packs.game_id = $1
+        WHERE card_packs.retired IS FALSE
+            AND COALESCE(game_packs.enabled, card_packs.enabled)
+    )
+END
+ORDER BY RANDOM()
+LIMIT GREATEST(0,
+    (SELECT card_count FROM games WHERE games.id = $1)
+    - (SELECT COUNT(id) FROM game_cards WHERE game_cards.game_id = $1)
+)
 `
 
-func (q *Queries) GameCardsInitGeneric(ctx context.Context, dollar_1 string) error {
-	_, err := q.db.Exec(ctx, gameCardsInitGeneric, dollar_1)
+// fills the deck from the game's chosen deck (the cards of its enabled packs
+// when none is chosen, or it has since been emptied), up to card_count less
+// any cards the players already wrote
+func (q *Queries) GameCardsInitDeck(ctx context.Context, dollar_1 string) error {
+	_, err := q.db.Exec(ctx, gameCardsInitDeck, dollar_1)
 	return err
 }
 
@@ -194,6 +312,143 @@ func (q *Queries) GameCardsPlayerView(ctx context.Context, gameID string) ([]Gam
 	return items, nil
 }
 
+const gameCardsReturn = `-- name: GameCardsReturn :execrows
+WITH clones AS (
+    UPDATE game_cards
+    SET shredded = TRUE, updated = CURRENT_TIMESTAMP
+    WHERE game_id = $1
+      AND player_id = $2
+      AND shredded IS NOT TRUE
+      AND from_clone IS TRUE
+),
+picked AS (
+    SELECT game_cards.id, floor(random() * games.wheel_slots)::int + 1 AS slot
+    FROM game_cards
+    JOIN games ON games.id = game_cards.game_id
+    WHERE game_cards.game_id = $1
+      AND game_cards.player_id = $2
+      AND game_cards.shredded IS NOT TRUE
+      AND game_cards.from_clone IS NOT TRUE
+),
+returned AS (
+    SELECT id, slot, ROW_NUMBER() OVER (PARTITION BY slot ORDER BY random()) AS above
+    FROM picked
+)
+UPDATE game_cards
+SET player_id = NULL,
+    flipped = FALSE,
+    slot = returned.slot,
+    stack = returned.above + COALESCE((
+        SELECT MAX(wheel.stack)
+        FROM game_cards wheel
+        WHERE wheel.game_id = $1
+          AND wheel.slot = returned.slot
+    ), 0),
+    updated = CURRENT_TIMESTAMP
+FROM returned
+WHERE game_cards.id = returned.id
+`
+
+type GameCardsReturnParams struct {
+	GameID   string      `json:"game_id"`
+	PlayerID pgtype.Int4 `json:"player_id"`
+}
+
+// a departing player's cards go back on the wheel, face up, each atop a
+// random slot; clones have no place on the wheel and are shredded instead
+func (q *Queries) GameCardsReturn(ctx context.Context, arg GameCardsReturnParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameCardsReturn, arg.GameID, arg.PlayerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const gameCardsShare = `-- name: GameCardsShare :execrows
+WITH heirs AS (
+    SELECT player_id,
+        ROW_NUMBER() OVER (ORDER BY random()) - 1 AS n,
+        COUNT(*) OVER () AS heirs
+    FROM game_players
+    WHERE game_id = $1
+      AND player_id <> $2
+      AND initiative > 0
+),
+held AS (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY random()) - 1 AS n
+    FROM game_cards
+    WHERE game_id = $1
+      AND player_id = $2
+      AND shredded IS NOT TRUE
+)
+UPDATE game_cards
+SET player_id = heirs.player_id, updated = CURRENT_TIMESTAMP
+FROM held
+JOIN heirs ON heirs.n = held.n % heirs.heirs
+WHERE game_cards.id = held.id
+`
+
+type GameCardsShareParams struct {
+	GameID   string `json:"game_id"`
+	PlayerID int32  `json:"player_id"`
+}
+
+// deals a departing player's cards round the remaining non-host players, in
+// random order; moves nothing when there's nobody left to take them
+func (q *Queries) GameCardsShare(ctx context.Context, arg GameCardsShareParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameCardsShare, arg.GameID, arg.PlayerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const gameCardsShredHeld = `-- name: GameCardsShredHeld :execrows
+UPDATE game_cards
+SET shredded = TRUE, updated = CURRENT_TIMESTAMP
+WHERE game_id = $1
+  AND player_id = $2
+  AND shredded IS NOT TRUE
+`
+
+type GameCardsShredHeldParams struct {
+	GameID   string      `json:"game_id"`
+	PlayerID pgtype.Int4 `json:"player_id"`
+}
+
+// shreds every card a departing player holds
+func (q *Queries) GameCardsShredHeld(ctx context.Context, arg GameCardsShredHeldParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameCardsShredHeld, arg.GameID, arg.PlayerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const gameCardsShredModifiers = `-- name: GameCardsShredModifiers :execrows
+UPDATE game_cards
+SET shredded = TRUE
+WHERE game_id = $1
+  AND player_id = $2
+  AND shredded IS NOT TRUE
+  AND card_id IN (SELECT id FROM cards WHERE type = 'modifier')
+`
+
+type GameCardsShredModifiersParams struct {
+	GameID   string      `json:"game_id"`
+	PlayerID pgtype.Int4 `json:"player_id"`
+}
+
+// spends every modifier a player holds, as using one does; for a modifier
+// choice that ran out of time
+func (q *Queries) GameCardsShredModifiers(ctx context.Context, arg GameCardsShredModifiersParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gameCardsShredModifiers, arg.GameID, arg.PlayerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const gameCardsShuffle = `-- name: GameCardsShuffle :exec
 WITH ordered AS (
  SELECT
@@ -284,7 +539,7 @@ SELECT
         )
     ) AS top_card_type
 FROM game_cards
-WHERE game_id = $1 AND player_id IS NULL
+WHERE game_id = $1 AND player_id IS NULL AND slot IS NOT NULL
 ORDER BY slot ASC
 `
 
@@ -314,3 +569,41 @@ func (q *Queries) GameCardsWheelView(ctx context.Context, gameID string) ([]Game
 	}
 	return items, nil
 }
+
+const gameCardsWritten = `-- name: GameCardsWritten :many
+SELECT
+    cards.creator,
+    COUNT(game_cards.id) AS written
+FROM game_cards
+JOIN cards ON cards.id = game_cards.card_id
+WHERE game_cards.game_id = $1
+    AND cards.generic IS FALSE
+    AND game_cards.from_clone IS FALSE
+GROUP BY cards.creator
+`
+
+type GameCardsWrittenRow struct {
+	Creator pgtype.Int4 `json:"creator"`
+	Written int64       `json:"written"`
+}
+
+// how many cards each player has written for this game
+func (q *Queries) GameCardsWritten(ctx context.Context, gameID string) ([]GameCardsWrittenRow, error) {
+	rows, err := q.db.Query(ctx, gameCardsWritten, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GameCardsWrittenRow
+	for rows.Next() {
+		var i GameCardsWrittenRow
+		if err := rows.Scan(&i.Creator, &i.Written); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -78,6 +78,30 @@ func (q *Queries) InfractionGet(ctx context.Context, id int32) (Infractions, err
 	return i, err
 }
 
+const infractionUndecide = `-- name: InfractionUndecide :execrows
+UPDATE infractions
+SET active = TRUE,
+    affirmed = FALSE
+WHERE id = $1
+    AND game_id = $2
+    AND active = FALSE
+`
+
+type InfractionUndecideParams struct {
+	ID     int32  `json:"id"`
+	GameID string `json:"game_id"`
+}
+
+// Reopens a decided infraction, as undoing its verdict does, for the host to
+// decide again.
+func (q *Queries) InfractionUndecide(ctx context.Context, arg InfractionUndecideParams) (int64, error) {
+	result, err := q.db.Exec(ctx, infractionUndecide, arg.ID, arg.GameID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const infractionsActiveCount = `-- name: InfractionsActiveCount :one
 SELECT COUNT(*) FROM infractions
 WHERE game_id = $1
@@ -126,3 +150,61 @@ func (q *Queries) InfractionsByGame(ctx context.Context, gameID string) ([]Infra
 	}
 	return items, nil
 }
+
+const infractionsTranscript = `-- name: InfractionsTranscript :many
+SELECT
+    inf.id,
+    accuser.name AS accuser_name,
+    accused.name AS accused_name,
+    (CASE WHEN gc.flipped AND c.back IS NOT NULL THEN c.back ELSE c.front END)::text AS rule,
+    inf.active,
+    inf.affirmed,
+    inf.created
+FROM infractions inf
+JOIN players accuser ON accuser.id = inf.accuser
+JOIN players accused ON accused.id = inf.accused
+JOIN game_cards gc ON gc.id = inf.game_card_id
+JOIN cards c ON c.id = gc.card_id
+WHERE inf.game_id = $1
+ORDER BY inf.id
+`
+
+type InfractionsTranscriptRow struct {
+	ID          int32            `json:"id"`
+	AccuserName string           `json:"accuser_name"`
+	AccusedName string           `json:"accused_name"`
+	Rule        string           `json:"rule"`
+	Active      pgtype.Bool      `json:"active"`
+	Affirmed    pgtype.Bool      `json:"affirmed"`
+	Created     pgtype.Timestamp `json:"created"`
+}
+
+// A game's accusations, oldest first, with who made them, against whom, and
+// the rule at issue as it read (its back, once flipped).
+func (q *Queries) InfractionsTranscript(ctx context.Context, gameID string) ([]InfractionsTranscriptRow, error) {
+	rows, err := q.db.Query(ctx, infractionsTranscript, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InfractionsTranscriptRow
+	for rows.Next() {
+		var i InfractionsTranscriptRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccuserName,
+			&i.AccusedName,
+			&i.Rule,
+			&i.Active,
+			&i.Affirmed,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
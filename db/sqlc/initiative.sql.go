@@ -30,6 +30,71 @@ func (q *Queries) InitiativeAdvance(ctx context.Context, id string) error {
 	return err
 }
 
+const initiativeAdvanceFrom = `-- name: InitiativeAdvanceFrom :execrows
+WITH initiative_max AS (
+  SELECT MAX(game_players.initiative) AS highest
+  FROM game_players
+  WHERE game_players.game_id = $1
+)
+UPDATE games
+SET initiative_current = (
+  games.initiative_current % initiative_max.highest
+) + 1
+FROM initiative_max
+WHERE games.id = $1
+  AND games.initiative_current = $2::int
+`
+
+type InitiativeAdvanceFromParams struct {
+	GameID string `json:"game_id"`
+	Seat   int32  `json:"seat"`
+}
+
+// advances as InitiativeAdvance does, but only from seat: no row when the
+// turn already moved on
+func (q *Queries) InitiativeAdvanceFrom(ctx context.Context, arg InitiativeAdvanceFromParams) (int64, error) {
+	result, err := q.db.Exec(ctx, initiativeAdvanceFrom, arg.GameID, arg.Seat)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const initiativeCompact = `-- name: InitiativeCompact :exec
+WITH seats AS (
+    UPDATE game_players
+    SET initiative = initiative - 1
+    WHERE game_players.game_id = $1
+      AND initiative > $2::int
+)
+UPDATE games
+SET initiative_current = CASE
+    WHEN initiative_current > $2::int THEN initiative_current - 1
+    WHEN initiative_current = $2::int AND $2::int > (
+        SELECT COUNT(*)
+        FROM game_players
+        WHERE game_players.game_id = $1
+          AND initiative > 0
+    ) THEN 1
+    ELSE initiative_current
+END
+WHERE games.id = $1
+`
+
+type InitiativeCompactParams struct {
+	GameID string `json:"game_id"`
+	Seat   int32  `json:"seat"`
+}
+
+// closes the gap a departed player's seat left: later seats move down one and
+// the turn moves with them, wrapping to the first seat when the last seat
+// held it, so the turn never lands on an empty seat. Run after the player's
+// game_players row is gone.
+func (q *Queries) InitiativeCompact(ctx context.Context, arg InitiativeCompactParams) error {
+	_, err := q.db.Exec(ctx, initiativeCompact, arg.GameID, arg.Seat)
+	return err
+}
+
 const initiativeCurrentPlayer = `-- name: InitiativeCurrentPlayer :one
 SELECT game_players.player_id
 FROM game_players
@@ -8,6 +8,24 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiTokens struct {
+	ID        int32            `json:"id"`
+	GameID    string           `json:"game_id"`
+	PlayerID  int32            `json:"player_id"`
+	TokenHash string           `json:"token_hash"`
+	Created   pgtype.Timestamp `json:"created"`
+}
+
+type CardPacks struct {
+	Slug        string           `json:"slug"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Version     int32            `json:"version"`
+	Enabled     bool             `json:"enabled"`
+	Retired     bool             `json:"retired"`
+	Synced      pgtype.Timestamp `json:"synced"`
+}
+
 type CardTypes struct {
 	Name        string      `json:"name"`
 	Description pgtype.Text `json:"description"`
@@ -22,6 +40,24 @@ type Cards struct {
 	Created        pgtype.Timestamp `json:"created"`
 	Generic        pgtype.Bool      `json:"generic"`
 	ModifierEffect pgtype.Text      `json:"modifier_effect"`
+	Pack           pgtype.Text      `json:"pack"`
+	Slug           pgtype.Text      `json:"slug"`
+	Retired        bool             `json:"retired"`
+	Pending        bool             `json:"pending"`
+	Source         pgtype.Text      `json:"source"`
+}
+
+type DeckCards struct {
+	DeckID int32            `json:"deck_id"`
+	CardID int32            `json:"card_id"`
+	Added  pgtype.Timestamp `json:"added"`
+}
+
+type Decks struct {
+	ID      int32            `json:"id"`
+	Name    string           `json:"name"`
+	EditKey string           `json:"edit_key"`
+	Created pgtype.Timestamp `json:"created"`
 }
 
 type EventLog struct {
@@ -34,6 +70,7 @@ type EventLog struct {
 	InfractionID  pgtype.Int4      `json:"infraction_id"`
 	GameCardID    pgtype.Int4      `json:"game_card_id"`
 	PointChangeID pgtype.Int4      `json:"point_change_id"`
+	UndoOf        pgtype.Int4      `json:"undo_of"`
 	Ts            pgtype.Timestamp `json:"ts"`
 }
 
@@ -45,6 +82,7 @@ type EventTypes struct {
 type GameCache struct {
 	GameID  string           `json:"game_id"`
 	Value   []byte           `json:"value"`
+	Version int64            `json:"version"`
 	Expires pgtype.Timestamp `json:"expires"`
 }
 
@@ -61,13 +99,22 @@ type GameCards struct {
 	Updated   pgtype.Timestamp `json:"updated"`
 }
 
+type GamePacks struct {
+	GameID  string `json:"game_id"`
+	Pack    string `json:"pack"`
+	Enabled bool   `json:"enabled"`
+}
+
 type GamePlayers struct {
-	GameID     string           `json:"game_id"`
-	PlayerID   int32            `json:"player_id"`
-	Points     pgtype.Int4      `json:"points"`
-	SessionKey pgtype.Text      `json:"session_key"`
-	Joined     pgtype.Timestamp `json:"joined"`
-	Initiative pgtype.Int4      `json:"initiative"`
+	GameID           string           `json:"game_id"`
+	PlayerID         int32            `json:"player_id"`
+	Points           pgtype.Int4      `json:"points"`
+	SessionKey       pgtype.Text      `json:"session_key"`
+	SessionKeyLegacy pgtype.Bool      `json:"session_key_legacy"`
+	Joined           pgtype.Timestamp `json:"joined"`
+	Initiative       pgtype.Int4      `json:"initiative"`
+	LastSeen         pgtype.Timestamp `json:"last_seen"`
+	Bot              bool             `json:"bot"`
 }
 
 type GameStates struct {
@@ -77,14 +124,27 @@ type GameStates struct {
 }
 
 type Games struct {
-	ID                string           `json:"id"`
-	Created           pgtype.Timestamp `json:"created"`
-	OwnerID           pgtype.Int4      `json:"owner_id"`
-	StateID           int32            `json:"state_id"`
-	WheelSlots        int32            `json:"wheel_slots"`
-	CardCount         int32            `json:"card_count"`
-	InitiativeTimer   int32            `json:"initiative_timer"`
-	InitiativeCurrent pgtype.Int4      `json:"initiative_current"`
+	ID                 string           `json:"id"`
+	Created            pgtype.Timestamp `json:"created"`
+	OwnerID            pgtype.Int4      `json:"owner_id"`
+	StateID            int32            `json:"state_id"`
+	WheelSlots         int32            `json:"wheel_slots"`
+	CardCount          int32            `json:"card_count"`
+	InitiativeTimer    int32            `json:"initiative_timer"`
+	InitiativeCurrent  pgtype.Int4      `json:"initiative_current"`
+	CardsPerPlayer     int32            `json:"cards_per_player"`
+	StartingPoints     int32            `json:"starting_points"`
+	PromptSeconds      int32            `json:"prompt_seconds"`
+	RecommendedPlayers int32            `json:"recommended_players"`
+	HostTimeout        int32            `json:"host_timeout"`
+	DeckID             pgtype.Int4      `json:"deck_id"`
+}
+
+type HostVotes struct {
+	GameID      string           `json:"game_id"`
+	VoterID     int32            `json:"voter_id"`
+	CandidateID int32            `json:"candidate_id"`
+	CastAt      pgtype.Timestamp `json:"cast_at"`
 }
 
 type Infractions struct {
@@ -12,8 +12,8 @@ import (
 )
 
 const gamePlayerCreate = `-- name: GamePlayerCreate :exec
-INSERT INTO game_players (game_id, player_id, session_key, initiative)
-VALUES ($1, $2, $3, $4)
+INSERT INTO game_players (game_id, player_id, session_key, initiative, points)
+VALUES ($1, $2, $3, $4, (SELECT starting_points FROM games WHERE games.id = $1))
 `
 
 type GamePlayerCreateParams struct {
@@ -23,7 +23,8 @@ type GamePlayerCreateParams struct {
 	Initiative pgtype.Int4 `json:"initiative"`
 }
 
-// session_key is expected to be valid for the duration of the game
+// session_key, hashed, is valid for the duration of the game; points
+// start at the game's starting_points
 func (q *Queries) GamePlayerCreate(ctx context.Context, arg GamePlayerCreateParams) error {
 	_, err := q.db.Exec(ctx, gamePlayerCreate,
 		arg.GameID,
@@ -34,10 +35,11 @@ func (q *Queries) GamePlayerCreate(ctx context.Context, arg GamePlayerCreatePara
 	return err
 }
 
-const gamePlayerDelete = `-- name: GamePlayerDelete :exec
+const gamePlayerDelete = `-- name: GamePlayerDelete :one
 DELETE FROM game_players 
 WHERE game_id = $1 
 	AND player_id = $2
+RETURNING initiative
 `
 
 type GamePlayerDeleteParams struct {
@@ -45,9 +47,12 @@ type GamePlayerDeleteParams struct {
 	PlayerID int32  `json:"player_id"`
 }
 
-func (q *Queries) GamePlayerDelete(ctx context.Context, arg GamePlayerDeleteParams) error {
-	_, err := q.db.Exec(ctx, gamePlayerDelete, arg.GameID, arg.PlayerID)
-	return err
+// removes a player from a game, returning the seat they leave empty
+func (q *Queries) GamePlayerDelete(ctx context.Context, arg GamePlayerDeleteParams) (pgtype.Int4, error) {
+	row := q.db.QueryRow(ctx, gamePlayerDelete, arg.GameID, arg.PlayerID)
+	var initiative pgtype.Int4
+	err := row.Scan(&initiative)
+	return initiative, err
 }
 
 const gamePlayerPoints = `-- name: GamePlayerPoints :many
@@ -56,9 +61,11 @@ SELECT
     (SELECT name FROM players WHERE players.id=game_players.player_id) AS name, 
     points,
     session_key,
-    initiative
+    initiative,
+    bot
 FROM game_players 
 WHERE game_id = $1
+    AND initiative IS NOT NULL
 ORDER BY initiative ASC
 `
 
@@ -68,6 +75,7 @@ type GamePlayerPointsRow struct {
 	Points     pgtype.Int4 `json:"points"`
 	SessionKey pgtype.Text `json:"session_key"`
 	Initiative pgtype.Int4 `json:"initiative"`
+	Bot        bool        `json:"bot"`
 }
 
 // TODO: is id=player_id correct?
@@ -86,6 +94,7 @@ func (q *Queries) GamePlayerPoints(ctx context.Context, gameID string) ([]GamePl
 			&i.Points,
 			&i.SessionKey,
 			&i.Initiative,
+			&i.Bot,
 		); err != nil {
 			return nil, err
 		}
@@ -97,6 +106,32 @@ func (q *Queries) GamePlayerPoints(ctx context.Context, gameID string) ([]GamePl
 	return items, nil
 }
 
+const gamePlayerSessionUpgrade = `-- name: GamePlayerSessionUpgrade :execrows
+UPDATE game_players
+SET session_key_legacy = FALSE
+WHERE game_id = $1
+  AND player_id = $2
+  AND session_key = $3
+  AND session_key_legacy IS TRUE
+`
+
+type GamePlayerSessionUpgradeParams struct {
+	GameID     string      `json:"game_id"`
+	PlayerID   int32       `json:"player_id"`
+	SessionKey pgtype.Text `json:"session_key"`
+}
+
+// spends a legacy session, one the migration hashed from plaintext, once
+// its unsigned cookie brings the key; sessions hashed from the start, and
+// legacy ones already spent, match nothing
+func (q *Queries) GamePlayerSessionUpgrade(ctx context.Context, arg GamePlayerSessionUpgradeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, gamePlayerSessionUpgrade, arg.GameID, arg.PlayerID, arg.SessionKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const gamePointsAdjust = `-- name: GamePointsAdjust :exec
 UPDATE game_players
 SET points = points + $1
@@ -115,6 +150,45 @@ func (q *Queries) GamePointsAdjust(ctx context.Context, arg GamePointsAdjustPara
 	return err
 }
 
+const gameSpectators = `-- name: GameSpectators :many
+SELECT
+    player_id,
+    (SELECT name FROM players WHERE players.id=game_players.player_id) AS name,
+    session_key
+FROM game_players
+WHERE game_id = $1
+    AND initiative IS NULL
+ORDER BY joined, player_id
+`
+
+type GameSpectatorsRow struct {
+	PlayerID   int32       `json:"player_id"`
+	Name       string      `json:"name"`
+	SessionKey pgtype.Text `json:"session_key"`
+}
+
+// spectators watch a game without a seat (no initiative), listed in the
+// order they came
+func (q *Queries) GameSpectators(ctx context.Context, gameID string) ([]GameSpectatorsRow, error) {
+	rows, err := q.db.Query(ctx, gameSpectators, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GameSpectatorsRow
+	for rows.Next() {
+		var i GameSpectatorsRow
+		if err := rows.Scan(&i.PlayerID, &i.Name, &i.SessionKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const player = `-- name: Player :one
 SELECT id, name, created FROM players WHERE id = $1
 `
@@ -145,3 +219,43 @@ func (q *Queries) PlayerDelete(ctx context.Context, id int32) error {
 	_, err := q.db.Exec(ctx, playerDelete, id)
 	return err
 }
+
+const playerSeen = `-- name: PlayerSeen :one
+WITH seen AS (
+    SELECT
+        game_players.player_id,
+        game_players.initiative,
+        game_players.last_seen,
+        games.host_timeout
+    FROM game_players
+    JOIN games ON games.id = game_players.game_id
+    WHERE game_players.game_id = $1 AND game_players.session_key = $2
+)
+UPDATE game_players
+SET last_seen = LOCALTIMESTAMP
+FROM seen
+WHERE game_players.game_id = $1
+    AND game_players.player_id = seen.player_id
+    AND (seen.last_seen IS NULL OR seen.last_seen < LOCALTIMESTAMP - INTERVAL '5 seconds')
+RETURNING (
+    seen.initiative = 0 AND (
+        seen.last_seen IS NULL
+        OR seen.last_seen + seen.host_timeout * INTERVAL '1 second' < LOCALTIMESTAMP
+    )
+)::bool AS host_returned
+`
+
+type PlayerSeenParams struct {
+	GameID     string      `json:"game_id"`
+	SessionKey pgtype.Text `json:"session_key"`
+}
+
+// marks a player seen by a poll, action, or stream heartbeat, skipping the
+// write while the last mark is under 5 seconds old. host_returned: the host
+// was away until now. no row: the mark was still fresh.
+func (q *Queries) PlayerSeen(ctx context.Context, arg PlayerSeenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, playerSeen, arg.GameID, arg.SessionKey)
+	var host_returned bool
+	err := row.Scan(&host_returned)
+	return host_returned, err
+}
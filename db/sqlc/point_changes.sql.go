@@ -37,3 +37,80 @@ func (q *Queries) PointChangeCreate(ctx context.Context, arg PointChangeCreatePa
 	err := row.Scan(&id)
 	return id, err
 }
+
+const pointChangesByGame = `-- name: PointChangesByGame :many
+SELECT
+    pc.id,
+    pc.player_id,
+    COALESCE(p.name, '')::text AS player_name,
+    pc.delta,
+    pc.infraction_id,
+    COALESCE(e.event_type, '')::text AS cause,
+    pc.ts
+FROM point_changes pc
+LEFT JOIN players p ON p.id = pc.player_id
+LEFT JOIN event_log e ON e.point_change_id = pc.id
+WHERE pc.game_id = $1
+ORDER BY pc.id
+`
+
+type PointChangesByGameRow struct {
+	ID           int32            `json:"id"`
+	PlayerID     pgtype.Int4      `json:"player_id"`
+	PlayerName   string           `json:"player_name"`
+	Delta        int32            `json:"delta"`
+	InfractionID pgtype.Int4      `json:"infraction_id"`
+	Cause        string           `json:"cause"`
+	Ts           pgtype.Timestamp `json:"ts"`
+}
+
+// A game's points ledger, oldest first: whose balance changed, by how much,
+// and the event that recorded why ("points" for an affirmed accusation,
+// "prompt" for a completed prompt, "undo" for either taken back).
+func (q *Queries) PointChangesByGame(ctx context.Context, gameID string) ([]PointChangesByGameRow, error) {
+	rows, err := q.db.Query(ctx, pointChangesByGame, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PointChangesByGameRow
+	for rows.Next() {
+		var i PointChangesByGameRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PlayerID,
+			&i.PlayerName,
+			&i.Delta,
+			&i.InfractionID,
+			&i.Cause,
+			&i.Ts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pointChangesInfractionNet = `-- name: PointChangesInfractionNet :one
+SELECT COALESCE(SUM(delta), 0)::int AS net
+FROM point_changes
+WHERE game_id = $1
+    AND infraction_id = $2
+`
+
+type PointChangesInfractionNetParams struct {
+	GameID       string      `json:"game_id"`
+	InfractionID pgtype.Int4 `json:"infraction_id"`
+}
+
+// What an infraction has cost its accused so far, net of any verdicts undone.
+func (q *Queries) PointChangesInfractionNet(ctx context.Context, arg PointChangesInfractionNetParams) (int32, error) {
+	row := q.db.QueryRow(ctx, pointChangesInfractionNet, arg.GameID, arg.InfractionID)
+	var net int32
+	err := row.Scan(&net)
+	return net, err
+}
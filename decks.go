@@ -0,0 +1,312 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// deckCookieName holds a deck's edit key, scoped by path to that deck.
+	deckCookieName = "deck"
+	// maxDeckNameLength caps a deck's name, in characters.
+	maxDeckNameLength = 60
+)
+
+// decksView is the data for tmpl.decks.html: every playable deck, plus an
+// optional popup message.
+type decksView struct {
+	Decks     []sqlc.DecksRow
+	Alert     string
+	CSRFToken string
+}
+
+// deckView is the data for tmpl.deck.html: one deck and its cards. The edit
+// forms only render for whoever holds the deck's key.
+type deckView struct {
+	Deck      sqlc.DeckRow
+	Cards     []sqlc.DeckCardsRow
+	CanEdit   bool
+	Alert     string
+	CSRFToken string
+}
+
+// deckPath is the page for a saved deck.
+func deckPath(deckID int32) string {
+	return fmt.Sprintf("/library/decks/%d", deckID)
+}
+
+// deckID parses the {deck_id} path value. The error is safe to show.
+func deckID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(r.PathValue("deck_id"), 10, 32)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid deck id")
+	}
+	trace.SpanFromContext(r.Context()).SetAttributes(attrDeckID.Int64(id))
+	return int32(id), nil
+}
+
+// deckCookie returns the cookie holding the edit key for deck id, kept from
+// scripts, plain http and cross-site posts as the session cookie is.
+func deckCookie(r *http.Request, id int32, key string) *http.Cookie {
+	return &http.Cookie{
+		Name:     deckCookieName,
+		Value:    key,
+		Path:     deckPath(id),
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// deckKeyHash returns the hash of the request's deck edit key, as decks
+// stores it, or "" when it carries none.
+func deckKeyHash(r *http.Request) string {
+	c, err := r.Cookie(deckCookieName)
+	if err != nil || c.Value == "" {
+		return ""
+	}
+	return hashSessionKey(c.Value)
+}
+
+// deckEditable reports whether the request carries the edit key for the deck.
+func deckEditable(r *http.Request, id int32) (bool, error) {
+	keyHash := deckKeyHash(r)
+	if keyHash == "" {
+		return false, nil
+	}
+	return queries.DeckAuthorized(r.Context(), sqlc.DeckAuthorizedParams{
+		ID:      id,
+		EditKey: keyHash,
+	})
+}
+
+// decksHandler handles the '/library/decks' endpoint.
+// - GET: list every playable deck
+// - POST: create a new, empty deck and hand its creator the edit key
+func decksHandler(w http.ResponseWriter, r *http.Request) {
+	log := log.With("handler", "decksHandler", "method", r.Method)
+	switch r.Method {
+	case http.MethodGet:
+		decks, err := queries.Decks(r.Context())
+		if err != nil {
+			log.Error("list decks", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		filepath := path.Join("static", "html", "tmpl.decks.html")
+		data := decksView{
+			Decks:     decks,
+			Alert:     alertMessages[r.URL.Query().Get("alert")],
+			CSRFToken: csrfToken(r),
+		}
+		if err := renderPage(r.Context(), w, filepath, baseURL(r), true, data); err != nil {
+			log.Error("render template", "error", err, "template", filepath)
+			http.Error(w, "server error", http.StatusInternalServerError)
+		}
+	case http.MethodPost:
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" || utf8.RuneCountInString(name) > maxDeckNameLength {
+			http.Redirect(w, r, "/library/decks?alert="+alertDeckName, http.StatusSeeOther)
+			return
+		}
+		key := make([]byte, secretLength)
+		if _, err := rand.Read(key); err != nil {
+			log.Error("make deck key", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		keyStr := hex.EncodeToString(key)
+		id, err := queries.DeckCreate(r.Context(), sqlc.DeckCreateParams{
+			Name:    name,
+			EditKey: hashSessionKey(keyStr),
+		})
+		if err != nil {
+			log.Error("create deck", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, deckCookie(r, id, keyStr))
+		log.Info("deck created", "deck_id", id, "name", name)
+		http.Redirect(w, r, deckPath(id), http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deckHandler handles the '/library/decks/{deck_id}' endpoint.
+// - GET: show the deck's cards, with edit forms for the key holder
+// - POST: rename the deck (key holder only)
+func deckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := deckID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log := log.With("handler", "deckHandler", "deck_id", id, "method", r.Method)
+	switch r.Method {
+	case http.MethodGet:
+		deck, err := queries.Deck(r.Context(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Warn("deck not found")
+			http.Redirect(w, r, "/library/decks?alert="+alertDeckNotFound, http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Error("fetch deck", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		cards, err := queries.DeckCards(r.Context(), id)
+		if err != nil {
+			log.Error("fetch deck cards", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		editable, err := deckEditable(r, id)
+		if err != nil {
+			log.Error("check deck key", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		filepath := path.Join("static", "html", "tmpl.deck.html")
+		data := deckView{
+			Deck:      deck,
+			Cards:     cards,
+			CanEdit:   editable,
+			Alert:     alertMessages[r.URL.Query().Get("alert")],
+			CSRFToken: csrfToken(r),
+		}
+		if err := renderPage(r.Context(), w, filepath, baseURL(r), true, data); err != nil {
+			log.Error("render template", "error", err, "template", filepath)
+			http.Error(w, "server error", http.StatusInternalServerError)
+		}
+	case http.MethodPost:
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" || utf8.RuneCountInString(name) > maxDeckNameLength {
+			http.Redirect(w, r, deckPath(id)+"?alert="+alertDeckName, http.StatusSeeOther)
+			return
+		}
+		n, err := queries.DeckRename(r.Context(), sqlc.DeckRenameParams{
+			ID:      id,
+			EditKey: deckKeyHash(r),
+			Name:    name,
+		})
+		if err != nil {
+			log.Error("rename deck", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if n == 0 {
+			log.Warn("rename without deck key")
+			http.Error(w, "not allowed to edit this deck", http.StatusForbidden)
+			return
+		}
+		log.Info("deck renamed", "name", name)
+		http.Redirect(w, r, deckPath(id), http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deckCardsHandler handles the '/library/decks/{deck_id}/cards' endpoint.
+// - POST: write a new card into the deck (key holder only)
+func deckCardsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := deckID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log := log.With("handler", "deckCardsHandler", "deck_id", id)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	editable, err := deckEditable(r, id)
+	if err != nil {
+		log.Error("check deck key", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if !editable {
+		log.Warn("card added without deck key")
+		http.Error(w, "not allowed to edit this deck", http.StatusForbidden)
+		return
+	}
+	card, err := cardFromForm(r)
+	if err != nil {
+		log.Debug("invalid card", "error", err)
+		http.Redirect(w, r, deckPath(id)+"?alert="+alertCardInvalid, http.StatusSeeOther)
+		return
+	}
+	cardID, err := queries.DeckCardCreate(r.Context(), sqlc.DeckCardCreateParams{
+		DeckID: id,
+		Type:   card.Type,
+		Front:  card.Front,
+		Back:   card.Back,
+	})
+	if err != nil {
+		log.Error("add deck card", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("deck card added", "card_id", cardID, "type", card.Type)
+	http.Redirect(w, r, deckPath(id), http.StatusSeeOther)
+}
+
+// deckCardHandler handles the '/library/decks/{deck_id}/cards/{card_id}'
+// endpoint.
+// - DELETE: take the card out of the deck (key holder only)
+func deckCardHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := deckID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	cardID, err := strconv.ParseInt(r.PathValue("card_id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid card id", http.StatusNotFound)
+		return
+	}
+	log := log.With("handler", "deckCardHandler", "deck_id", id, "card_id", cardID)
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	editable, err := deckEditable(r, id)
+	if err != nil {
+		log.Error("check deck key", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if !editable {
+		log.Warn("card removed without deck key")
+		http.Error(w, "not allowed to edit this deck", http.StatusForbidden)
+		return
+	}
+	n, err := queries.DeckCardDelete(r.Context(), sqlc.DeckCardDeleteParams{
+		DeckID: id,
+		CardID: int32(cardID),
+	})
+	if err != nil {
+		log.Error("remove deck card", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "card not in deck", http.StatusNotFound)
+		return
+	}
+	log.Info("deck card removed")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the formats a transcript exports as, by the name ?format= and -format take
+const (
+	formatJSON     = "json"
+	formatMarkdown = "md"
+	formatCSV      = "csv"
+)
+
+// transcript is one game written out whole: who played, what they ended
+// holding, every points change and accusation, and the game log worded as
+// the feed words it.
+type transcript struct {
+	GameID         string                 `json:"game_id"`
+	State          string                 `json:"state"`
+	StartingPoints int32                  `json:"starting_points"`
+	Players        []transcriptPlayer     `json:"players"` // in seat order, the host first
+	Points         []transcriptPoints     `json:"points"`
+	Accusations    []transcriptAccusation `json:"accusations"`
+	Events         []transcriptEvent      `json:"events"`
+}
+
+type transcriptPlayer struct {
+	Name   string           `json:"name"`
+	Seat   int32            `json:"seat"`
+	Host   bool             `json:"host"`
+	Bot    bool             `json:"bot"`
+	Points int32            `json:"points"`
+	Hand   []transcriptCard `json:"hand"`
+}
+
+type transcriptCard struct {
+	Type string `json:"type"`
+	Text string `json:"text"` // the face showing
+}
+
+type transcriptPoints struct {
+	Player string    `json:"player"`
+	Delta  int32     `json:"delta"`
+	Cause  string    `json:"cause"` // accusation, prompt, undo, or host
+	At     time.Time `json:"at"`
+}
+
+type transcriptAccusation struct {
+	Accuser string    `json:"accuser"`
+	Accused string    `json:"accused"`
+	Rule    string    `json:"rule"`
+	Verdict string    `json:"verdict"` // guilty, not guilty, or pending
+	At      time.Time `json:"at"`
+}
+
+type transcriptEvent struct {
+	ID     int32  `json:"id"`
+	Type   string `json:"type"`
+	Actor  string `json:"actor,omitempty"`
+	Target string `json:"target,omitempty"`
+	Delta  *int32 `json:"delta,omitempty"`
+	Text   string `json:"text"`
+	Card   string `json:"card,omitempty"`
+}
+
+// gameTranscript reads gameID out whole.
+func gameTranscript(ctx context.Context, gameID string) (transcript, error) {
+	s, err := stateFromCacheOrDB(ctx, &cache, gameID)
+	if err != nil {
+		return transcript{}, fmt.Errorf("get state: %w", err)
+	}
+	t := transcript{
+		GameID:         gameID,
+		State:          s.Game.StateName,
+		StartingPoints: s.Game.StartingPoints,
+	}
+	for _, p := range s.Players {
+		player := transcriptPlayer{
+			Name:   p.Name,
+			Seat:   p.Initiative.Int32,
+			Host:   p.Initiative.Int32 == 0,
+			Bot:    p.Bot,
+			Points: p.Points.Int32,
+			Hand:   []transcriptCard{},
+		}
+		for _, c := range s.CardsPlayers {
+			if c.PlayerID.Int32 == p.PlayerID {
+				player.Hand = append(player.Hand, transcriptCard{Type: c.Type, Text: fmt.Sprint(c.Content)})
+			}
+		}
+		t.Players = append(t.Players, player)
+	}
+
+	changes, err := queries.PointChangesByGame(ctx, gameID)
+	if err != nil {
+		return transcript{}, fmt.Errorf("list point changes: %w", err)
+	}
+	t.Points = make([]transcriptPoints, 0, len(changes))
+	for _, pc := range changes {
+		t.Points = append(t.Points, transcriptPoints{Player: pc.PlayerName, Delta: pc.Delta, Cause: pointsCause(pc), At: pc.Ts.Time})
+	}
+
+	infractions, err := queries.InfractionsTranscript(ctx, gameID)
+	if err != nil {
+		return transcript{}, fmt.Errorf("list infractions: %w", err)
+	}
+	t.Accusations = make([]transcriptAccusation, 0, len(infractions))
+	for _, inf := range infractions {
+		verdict := "pending"
+		if !inf.Active.Bool {
+			verdict = "not guilty"
+			if inf.Affirmed.Bool {
+				verdict = "guilty"
+			}
+		}
+		t.Accusations = append(t.Accusations, transcriptAccusation{
+			Accuser: inf.AccuserName,
+			Accused: inf.AccusedName,
+			Rule:    inf.Rule,
+			Verdict: verdict,
+			At:      inf.Created.Time,
+		})
+	}
+
+	events, err := queries.EventListSince(ctx, sqlc.EventListSinceParams{GameID: gameID})
+	if err != nil {
+		return transcript{}, fmt.Errorf("list events: %w", err)
+	}
+	texts, err := eventTexts(events)
+	if err != nil {
+		return transcript{}, err
+	}
+	t.Events = make([]transcriptEvent, 0, len(events))
+	for i, e := range events {
+		event := transcriptEvent{
+			ID:     e.ID,
+			Type:   e.EventType,
+			Actor:  e.ActorName.String,
+			Target: e.TargetName.String,
+			Text:   texts[i],
+			Card:   e.CardFront,
+		}
+		if e.CardFlipped && e.CardBack != "" {
+			event.Card = e.CardBack
+		}
+		if e.PointsDelta.Valid {
+			event.Delta = &e.PointsDelta.Int32
+		}
+		t.Events = append(t.Events, event)
+	}
+	return t, nil
+}
+
+// eventTexts words each event as the feed does, in plain text.
+func eventTexts(events []sqlc.EventListSinceRow) ([]string, error) {
+	tmpl, err := readParse(static, path.Join("static", "html", "tmpl.event_text.html"), "", false)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, 0, len(events))
+	var b bytes.Buffer
+	for _, e := range events {
+		b.Reset()
+		if err := tmpl.ExecuteTemplate(&b, "eventText", e); err != nil {
+			return nil, fmt.Errorf("word event %d: %w", e.ID, err)
+		}
+		texts = append(texts, strings.Join(strings.Fields(html.UnescapeString(b.String())), " "))
+	}
+	return texts, nil
+}
+
+// write writes the transcript in format.
+func (t transcript) write(w io.Writer, format string) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	case formatMarkdown:
+		return t.writeMarkdown(w)
+	case formatCSV:
+		return t.writeCSV(w)
+	}
+	return fmt.Errorf("unknown format %q (have json, md, csv)", format)
+}
+
+// mdEscape keeps player-written text from reading as Markdown, or breaking
+// out of a table cell.
+var mdEscape = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	"<", "&lt;", "|", `\|`, "\n", " ",
+)
+
+// writeMarkdown writes the transcript for pasting into a chat: standings,
+// then the ledger, the accusations and the log.
+func (t transcript) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# rulette game %s\n\n", t.GameID)
+	var players []transcriptPlayer
+	for _, p := range t.Players {
+		if p.Host {
+			fmt.Fprintf(&b, "%s · hosted by %s · starting points %d\n", t.State, mdEscape.Replace(p.Name), t.StartingPoints)
+			continue
+		}
+		players = append(players, p)
+	}
+	sort.SliceStable(players, func(i, j int) bool { return players[i].Points > players[j].Points })
+
+	b.WriteString("\n## standings\n\n")
+	if len(players) == 0 {
+		b.WriteString("nobody played.\n")
+	} else {
+		b.WriteString("| | player | points | held |\n| ---: | --- | ---: | --- |\n")
+	}
+	for i, p := range players {
+		name := mdEscape.Replace(p.Name)
+		if p.Bot {
+			name += " (bot)"
+		}
+		var hand []string
+		for _, c := range p.Hand {
+			hand = append(hand, mdEscape.Replace(c.Text))
+		}
+		fmt.Fprintf(&b, "| %d | %s | %d | %s |\n", i+1, name, p.Points, strings.Join(hand, "; "))
+	}
+
+	b.WriteString("\n## points\n\n")
+	if len(t.Points) == 0 {
+		b.WriteString("no points changed hands.\n")
+	} else {
+		b.WriteString("| player | change | cause |\n| --- | ---: | --- |\n")
+	}
+	for _, pc := range t.Points {
+		fmt.Fprintf(&b, "| %s | %+d | %s |\n", mdEscape.Replace(pc.Player), pc.Delta, pc.Cause)
+	}
+
+	b.WriteString("\n## accusations\n\n")
+	if len(t.Accusations) == 0 {
+		b.WriteString("no accusations.\n")
+	} else {
+		b.WriteString("| accuser | accused | rule | verdict |\n| --- | --- | --- | --- |\n")
+	}
+	for _, a := range t.Accusations {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", mdEscape.Replace(a.Accuser), mdEscape.Replace(a.Accused), mdEscape.Replace(a.Rule), a.Verdict)
+	}
+
+	b.WriteString("\n## log\n\n")
+	for i, e := range t.Events {
+		fmt.Fprintf(&b, "%d. %s", i+1, mdEscape.Replace(e.Text))
+		if e.Card != "" {
+			fmt.Fprintf(&b, ": *%s*", mdEscape.Replace(e.Card))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// csvHeader names the transcript's CSV columns: one table for every section,
+// told apart by the first column, so a spreadsheet can filter on it.
+var csvHeader = []string{"section", "seq", "player", "target", "points", "text", "card"}
+
+// csvCell keeps a spreadsheet from reading text players wrote, a name or a
+// card, as a formula: text starting with =, +, -, @, a tab or a carriage
+// return gets a leading quote.
+func csvCell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// writeCSV writes the transcript as one CSV table: a row per player, per
+// card held, per points change, per accusation and per event. Every text cell
+// goes through csvCell; the numbers are written as they are.
+func (t transcript) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{csvHeader}
+	row := func(section string, seq int, player, target string, points *int32, text, card string) {
+		var pts string
+		if points != nil {
+			pts = strconv.Itoa(int(*points))
+		}
+		rows = append(rows, []string{section, strconv.Itoa(seq), csvCell(player), csvCell(target), pts, csvCell(text), csvCell(card)})
+	}
+	for _, p := range t.Players {
+		role := "player"
+		switch {
+		case p.Host:
+			role = "host"
+		case p.Bot:
+			role = "bot"
+		}
+		row("player", int(p.Seat), p.Name, "", &p.Points, role, "")
+	}
+	for _, p := range t.Players {
+		for i, c := range p.Hand {
+			row("hand", i+1, p.Name, "", nil, c.Type, c.Text)
+		}
+	}
+	for i, pc := range t.Points {
+		row("points", i+1, pc.Player, "", &pc.Delta, pc.Cause, "")
+	}
+	for i, a := range t.Accusations {
+		row("accusation", i+1, a.Accuser, a.Accused, nil, a.Verdict, a.Rule)
+	}
+	for i, e := range t.Events {
+		row("event", i+1, e.Actor, e.Target, e.Delta, e.Text, e.Card)
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
+
+// exportContentTypes are the Content-Type each format is served as.
+var exportContentTypes = map[string]string{
+	formatJSON:     "application/json",
+	formatMarkdown: "text/markdown; charset=utf-8",
+	formatCSV:      "text/csv; charset=utf-8",
+}
+
+// exportHandler handles the '/{game_id}/export' endpoint: a finished game's
+// transcript, as ?format=json (the default), md or csv, to download. Anyone
+// who may watch the game may export it.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("game_id")
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(attrGameID.String(gameID))
+	log := log.With("handler", "exportHandler", "game_id", gameID)
+
+	if r.Method != http.MethodGet {
+		log.Debug("unsupported method", "method", r.Method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		http.Error(w, "format must be json, md or csv", http.StatusBadRequest)
+		return
+	}
+	cookieID, cookieKey, err := cookie(r)
+	if err != nil {
+		setCookieErr(w, err)
+		return
+	}
+	span.SetAttributes(attrPlayerID.String(cookieID))
+	log = log.With("cookie_id", cookieID, "format", format)
+
+	s, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
+	if err != nil {
+		if errors.Is(err, ErrStateNoGame) {
+			http.Error(w, "game not found", http.StatusNotFound)
+			return
+		}
+		log.Error("get state", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if !s.canWatch(cookieKey) {
+		log.Warn("prohibiting unauthorized export")
+		http.Error(w, "not in this game", http.StatusForbidden)
+		return
+	}
+	if s.Game.StateID != stateOver {
+		http.Error(w, "the game isn't over yet", http.StatusTooEarly)
+		return
+	}
+
+	t, err := gameTranscript(r.Context(), gameID)
+	if err != nil {
+		log.Error("read transcript", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	var b bytes.Buffer
+	if err := t.write(&b, format); err != nil {
+		log.Error("write transcript", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rulette-%s.%s"`, gameID, format))
+	w.Write(b.Bytes())
+}
+
+// exportCommand runs `rulette export [-format f] game_id`, writing the game's
+// transcript to stdout. Unlike the endpoint it exports a game in any state,
+// for an operator rescuing one that never finished.
+func exportCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", formatJSON, "json, md or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: rulette export [-format json|md|csv] game_id")
+	}
+	if _, ok := exportContentTypes[*format]; !ok {
+		return fmt.Errorf("unknown format %q (have json, md, csv)", *format)
+	}
+	t, err := gameTranscript(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return t.write(stdout, *format)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTexts(t *testing.T) {
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: true} }
+	texts, err := eventTexts([]sqlc.EventListSinceRow{
+		{EventType: "start"},
+		{EventType: "spin", ActorName: text("Ann & Bo")},
+		{EventType: "points", TargetName: text("Bo"), PointsDelta: pgInt(-3)},
+		{EventType: "decide", InfractionAffirmed: pgtype.Bool{Bool: true, Valid: true}},
+		{EventType: "prompt", TargetName: text("Ann"), PointsDelta: pgInt(3)},
+		{EventType: "host", TargetName: text("Bo")},
+		{EventType: "undo", ActorName: text("Host"), TargetName: text("Bo"), UndoneType: "decide"},
+		{EventType: "undo", ActorName: text("Host"), TargetName: text("Ann"), UndoneType: "transfer"},
+		{EventType: "mystery"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"game started",
+		"Ann & Bo spun the wheel",
+		"Bo lost 3 points",
+		"verdict: guilty",
+		"Ann succeeded at the prompt, earning 3 points (1+2 rules held)",
+		"Bo was voted the host",
+		"Host reopened the verdict on Bo",
+		"Host gave a card back to Ann",
+		"mystery",
+	}, texts)
+}
+
+// exportTestTranscript is a short finished game, with names and rules that
+// need escaping.
+func exportTestTranscript() transcript {
+	delta := int32(-3)
+	return transcript{
+		GameID:         "abc123",
+		State:          "over",
+		StartingPoints: 20,
+		Players: []transcriptPlayer{
+			{Name: "Host", Seat: 0, Host: true, Points: 20, Hand: []transcriptCard{}},
+			{Name: "Ann", Seat: 1, Points: 18, Hand: []transcriptCard{{Type: "rule", Text: "say *please*"}}},
+			{Name: "Bo|b", Seat: 2, Bot: true, Points: 23, Hand: []transcriptCard{{Type: "rule", Text: "no\nnames"}, {Type: "rule", Text: "hum"}}},
+		},
+		Points: []transcriptPoints{{Player: "Ann", Delta: -3, Cause: "accusation"}},
+		Accusations: []transcriptAccusation{
+			{Accuser: "Bo|b", Accused: "Ann", Rule: "say *please*", Verdict: "guilty"},
+		},
+		Events: []transcriptEvent{
+			{ID: 1, Type: "start", Text: "game started"},
+			{ID: 2, Type: "spin", Actor: "Ann", Text: "Ann spun the wheel", Card: "say *please*"},
+			{ID: 3, Type: "points", Target: "Ann", Delta: &delta, Text: "Ann lost 3 points"},
+		},
+	}
+}
+
+func TestTranscriptMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, exportTestTranscript().write(&b, formatMarkdown))
+	md := b.String()
+
+	require.True(t, strings.HasPrefix(md, "# rulette game abc123\n"))
+	require.Contains(t, md, "over · hosted by Host · starting points 20")
+	require.Contains(t, md, "| 1 | Bo\\|b (bot) | 23 | no names; hum |\n", "ranked by points, cells escaped")
+	require.Contains(t, md, "| 2 | Ann | 18 | say \\*please\\* |\n")
+	require.NotContains(t, md, "| Host |", "the host isn't ranked")
+	require.Contains(t, md, "| Ann | -3 | accusation |\n")
+	require.Contains(t, md, "| Bo\\|b | Ann | say \\*please\\* | guilty |\n")
+	require.Contains(t, md, "2. Ann spun the wheel: *say \\*please\\**\n")
+
+	b.Reset()
+	require.NoError(t, transcript{GameID: "empty"}.write(&b, formatMarkdown))
+	require.Contains(t, b.String(), "nobody played.")
+	require.Contains(t, b.String(), "no accusations.")
+}
+
+func TestTranscriptCSV(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, exportTestTranscript().write(&b, formatCSV))
+	rows, err := csv.NewReader(&b).ReadAll()
+	require.NoError(t, err, "multi-line rules are quoted")
+	require.Equal(t, csvHeader, rows[0])
+
+	sections := make(map[string]int)
+	for _, row := range rows[1:] {
+		require.Len(t, row, len(csvHeader))
+		sections[row[0]]++
+	}
+	require.Equal(t, map[string]int{"player": 3, "hand": 3, "points": 1, "accusation": 1, "event": 3}, sections)
+	require.Contains(t, rows, []string{"hand", "1", "Bo|b", "", "", "rule", "no\nnames"})
+	require.Contains(t, rows, []string{"player", "2", "Bo|b", "", "23", "bot", ""})
+	require.Contains(t, rows, []string{"event", "3", "", "Ann", "-3", "Ann lost 3 points", ""})
+}
+
+func TestCSVCell(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 "",
+		"Ann":              "Ann",
+		"=HYPERLINK(1)":    "'=HYPERLINK(1)",
+		"+1":               "'+1",
+		"-2+3":             "'-2+3",
+		"@SUM(A1)":         "'@SUM(A1)",
+		"\t=1":             "'\t=1",
+		"\r=1":             "'\r=1",
+		"speak in = signs": "speak in = signs",
+	} {
+		require.Equal(t, want, csvCell(in), "%q", in)
+	}
+
+	tr := exportTestTranscript()
+	tr.Players[0].Name = "=cmd"
+	var b bytes.Buffer
+	require.NoError(t, tr.write(&b, formatCSV))
+	rows, err := csv.NewReader(&b).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, "'=cmd", rows[1][2])
+}
+
+func TestTranscriptJSON(t *testing.T) {
+	var b bytes.Buffer
+	want := exportTestTranscript()
+	require.NoError(t, want.write(&b, formatJSON))
+	var got transcript
+	require.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	require.Equal(t, want, got)
+
+	require.Error(t, want.write(&b, "xlsx"))
+}
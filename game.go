@@ -95,25 +95,21 @@ func gameHandler(w http.ResponseWriter, r *http.Request) {
 		redirectAlert(w, r, alertError)
 		return
 	}
-	if !state.isPlayerInGame(cookieKey) {
+	if !state.canWatch(cookieKey) {
 		log.Warn("prohibiting unauthorized player access")
 		span.SetAttributes(attrAlert.String(alertNotMember))
 		http.Redirect(w, r, fmt.Sprintf("/%s/join", gameID), http.StatusSeeOther)
 		return
 	}
-	err = state.callerInfo(cookieKey)
-	if err != nil {
-		log.Error("populate caller info", "error", err)
-		redirectAlert(w, r, alertError)
-		return
-	}
+	v := state.viewFor(cookieKey)
+	v.CSRFToken = csrfToken(r)
 	span.SetAttributes(
-		attrStateID.Int(int(state.Game.StateID)),
-		attrCallerName.String(state.CallerName),
+		attrStateID.Int(int(v.Game.StateID)),
+		attrCallerName.String(v.CallerName),
 	)
 
 	filepath := path.Join("static", "html", "tmpl.game.html")
-	if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+	if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 		log.Error("render template",
 			"error", err,
 			"template", filepath,
@@ -164,23 +160,32 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !state.isPlayerInGame(cookieKey) {
+	if !state.canWatch(cookieKey) {
 		log.Warn("prohibiting unauthorized player access")
 		http.Error(w, "player not in game", http.StatusForbidden)
 		return
 	}
-	err = state.callerInfo(cookieKey)
-	if err != nil {
-		log.Error("populate caller info", "error", err)
-		http.Error(w, "server error", http.StatusInternalServerError)
-		return
-	}
+	v := state.viewFor(cookieKey)
+	v.CSRFToken = csrfToken(r)
 	span.SetAttributes(
-		attrStateID.Int(int(state.Game.StateID)),
-		attrCallerName.String(state.CallerName),
+		attrStateID.Int(int(v.Game.StateID)),
+		attrCallerName.String(v.CallerName),
 	)
+	// presence only matters for those with a seat
+	if v.Game.StateID != stateOver && v.Role != roleSpectator {
+		markSeen(r.Context(), log, gameID, cookieKey)
+	}
+	// spectators watch the table; the dialogs for acting on it aren't theirs
+	if v.Role == roleSpectator {
+		switch topic {
+		case "modifier", "accuse":
+			log.Debug("spectator asked for an action dialog")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
 
-	switch state.Game.StateID {
+	switch v.Game.StateID {
 	case stateOver: // game over
 		// htmx stops a polling trigger when it sees status 286, so the
 		// polled sections settle instead of erroring forever. The table
@@ -188,40 +193,52 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 		const stopPolling = 286
 		switch topic {
 		case "players":
+			// the awards and chart are a garnish: without them, the
+			// standings still show
+			if summary, err := summarizeGame(r.Context(), v); err != nil {
+				log.Error("summarize game", "error", err)
+			} else {
+				v.Summary = &summary
+			}
+			if chart, err := pointsChartFor(r.Context(), v); err != nil {
+				log.Error("chart points", "error", err)
+			} else {
+				v.Chart = chart
+			}
 			w.WriteHeader(stopPolling)
 			filepath := path.Join("static", "html", "tmpl.gameover.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render gameover", "error", err, "template", filepath)
 			}
 		case "events":
 			// still serve the log so the final events and the history
 			// modal work, but with 286 so the feed stops polling
 			renderEvents(w, r, gameID, stopPolling)
-		case "status", "table", "infraction", "prompt":
+		case "status", "table", "infraction", "prompt", "points":
 			w.WriteHeader(stopPolling)
 		default:
 			http.Error(w, "game over", http.StatusGone)
 		}
 		return
-	case stateEnding, stateChallenge, statePrompt, statePending, stateTurn, stateReady, stateInviting, stateCreated: // in progress (7 = deck spent, host to end)
+	case stateEnding, stateChallenge, statePrompt, statePending, stateTurn, stateReady, stateWriting, stateInviting, stateCreated: // in progress (7 = deck spent, host to end)
 		switch topic {
 		case "players":
 			filepath := path.Join("static", "html", "tmpl.players.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
 			return
 		case "table":
 			filepath := path.Join("static", "html", "tmpl.table.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
 			return
 		case "status":
 			filepath := path.Join("static", "html", "tmpl.status.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
@@ -230,32 +247,39 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 			// the feed and the sound engine both read this
 			renderEvents(w, r, gameID, http.StatusOK)
 			return
-		case "state": // NOTE: debug endpoint
+		case "state": // the caller's view, as JSON
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			err := json.NewEncoder(w).Encode(state)
+			err := json.NewEncoder(w).Encode(v)
 			if err != nil {
 				log.Error("encode state response", "error", err)
 				http.Error(w, "server error", http.StatusInternalServerError)
 				return
 			}
 		case "points":
+			chart, err := pointsChartFor(r.Context(), v)
+			if err != nil {
+				log.Error("chart points", "error", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			v.Chart = chart
 			filepath := path.Join("static", "html", "tmpl.points.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
 			return
 		case "modifier":
 			filepath := path.Join("static", "html", "tmpl.modifier.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
 			return
 		case "accuse":
 			filepath := path.Join("static", "html", "tmpl.accuse_dialog.html")
-			if err := renderTemplate(r.Context(), w, filepath, state); err != nil {
+			if err := renderTemplate(r.Context(), w, filepath, v); err != nil {
 				log.Error("render template", "error", err, "template", filepath)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
@@ -265,11 +289,11 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 			// the spinner's name, the prompt text, and how many seconds have
 			// already elapsed so their popup can sync its countdown and enable
 			// the "fail" choice on time.
-			if state.Game.StateID != statePrompt {
+			if v.Game.StateID != statePrompt {
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
-			if !state.isHost(cookieKey) {
+			if v.Role != roleHost {
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
@@ -287,7 +311,7 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			spinnerName := ""
-			for _, p := range state.Players {
+			for _, p := range v.Players {
 				if p.PlayerID == spin.PlayerID.Int32 {
 					spinnerName = p.Name
 					break
@@ -299,32 +323,32 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 				"spinner": spinnerName,
 				"prompt":  spin.Front,
 				"elapsed": elapsed,
-				"window":  promptSeconds,
+				"window":  v.Game.PromptSeconds,
 			})
 			return
 		case "infraction":
-			if state.Game.StateID != stateChallenge {
-				log.Debug("infraction poll outside challenge state", "state_id", state.Game.StateID)
+			if v.Game.StateID != stateChallenge {
+				log.Debug("infraction poll outside challenge state", "state_id", v.Game.StateID)
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
-			if !state.isHost(cookieKey) {
+			if v.Role != roleHost {
 				log.Debug("infraction poll by non-host")
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
-			for _, inf := range state.Infractions {
+			for _, inf := range v.Infractions {
 				if inf.Active.Bool {
 					log.Debug("serving infraction to host", "infraction_id", inf.ID)
 					accusedName := ""
-					for _, p := range state.Players {
+					for _, p := range v.Players {
 						if p.PlayerID == inf.Accused {
 							accusedName = p.Name
 							break
 						}
 					}
 					ruleContent := ""
-					for _, c := range state.CardsPlayers {
+					for _, c := range v.CardsPlayers {
 						if c.ID == inf.GameCardID {
 							if s, ok := c.Content.(string); ok {
 								ruleContent = s
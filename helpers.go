@@ -2,17 +2,92 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"unicode/utf8"
 
 	sqlc "github.com/grackleclub/rulette/db/sqlc"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// maxCardLength caps each side of a player-written card, in characters.
+const maxCardLength = 200
+
+// cardForm is a rule or prompt card as written by a player, in the writing
+// phase or a saved deck.
+type cardForm struct {
+	Type  string
+	Front string
+	Back  pgtype.Text // NULL for prompts, which are one-sided
+}
+
+// cardFromForm reads and validates a written card from the request's type,
+// front, and back form values. The error is safe to show the player.
+func cardFromForm(r *http.Request) (cardForm, error) {
+	card := cardForm{
+		Type:  r.FormValue("type"),
+		Front: strings.TrimSpace(r.FormValue("front")),
+	}
+	back := strings.TrimSpace(r.FormValue("back"))
+	switch card.Type {
+	case "rule":
+		card.Back = pgtype.Text{String: back, Valid: true}
+	case "prompt":
+		back = "" // prompts are one-sided; ignore any back sent
+	default:
+		return cardForm{}, errors.New("type must be rule or prompt")
+	}
+	if err := validateCard(card.Type, card.Front, back, ""); err != nil {
+		return cardForm{}, err
+	}
+	return card, nil
+}
+
+// validateCard checks a card against the cards table's constraints (a known
+// type, and a known effect on modifiers only) and the game's own: a front, a
+// back on rules only, since every rule can be flipped, and the length cap.
+// The error is safe to show.
+func validateCard(typ, front, back, effect string) error {
+	if front == "" {
+		return errors.New("a card needs a front")
+	}
+	if utf8.RuneCountInString(front) > maxCardLength ||
+		utf8.RuneCountInString(back) > maxCardLength {
+		return fmt.Errorf("card text is limited to %d characters", maxCardLength)
+	}
+	switch typ {
+	case "rule":
+		if back == "" {
+			return errors.New("a rule needs a back")
+		}
+	case "prompt", "modifier":
+		if back != "" {
+			return fmt.Errorf("a %s is one-sided, so has no back", typ)
+		}
+	default:
+		return fmt.Errorf("unknown card type %q", typ)
+	}
+	if typ != "modifier" {
+		if effect != "" {
+			return fmt.Errorf("a %s has no modifier effect", typ)
+		}
+		return nil
+	}
+	switch effect {
+	case modFlip, modShred, modClone, modTransfer:
+		return nil
+	default:
+		return fmt.Errorf("unknown modifier effect %q", effect)
+	}
+}
+
 // envRequired returns the value of the environment variable named by the key,
 // or panics.
 // WARNING: should only be used for required startup vars.
@@ -24,20 +99,32 @@ func envRequired(key string) string {
 	panic(err)
 }
 
+// newInstanceID returns a random hex id for this process, used to recognize
+// (and skip) our own cache invalidation broadcasts.
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("generate instance id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
 // pgInt wraps a player or row id as a non-NULL pgtype.Int4.
 func pgInt(v int32) pgtype.Int4 {
 	return pgtype.Int4{Int32: v, Valid: true}
 }
 
-// recordEvent adds one row to the event log. Detail references that don't
-// apply to the event type are left as the zero pgtype.Int4, which stores NULL.
-// On failure it logs (with the event type) and returns a wrapped error, so
-// callers only need to handle the error, not log it.
+// recordEvent adds one row to the event log and notifies the game's open
+// streams. Detail references that don't apply to the event type are left as
+// the zero pgtype.Int4, which stores NULL. On failure it logs (with the event
+// type) and returns a wrapped error, so callers only need to handle the
+// error, not log it.
 func recordEvent(ctx context.Context, log *slog.Logger, q *sqlc.Queries, p sqlc.EventCreateParams) error {
 	if _, err := q.EventCreate(ctx, p); err != nil {
 		log.Error("record event", "error", err, "event_type", p.EventType)
 		return fmt.Errorf("record %s event: %w", p.EventType, err)
 	}
+	streams.publish(p.GameID)
 	return nil
 }
 
@@ -57,10 +144,34 @@ func advanceTurn(ctx context.Context, log *slog.Logger, q *sqlc.Queries, gameID
 	if err := q.InitiativeAdvance(ctx, gameID); err != nil {
 		return fmt.Errorf("advance initiative: %w", err)
 	}
+	return recordTurn(ctx, log, q, gameID)
+}
+
+// advanceTurnFrom is advanceTurn only while initiative is still at seat,
+// reporting false, doing nothing, once it has moved on: the same turn ended
+// twice, by a double click or two instances' bot clocks, mustn't skip the
+// next player's.
+func advanceTurnFrom(ctx context.Context, log *slog.Logger, q *sqlc.Queries, gameID string, seat int32) (bool, error) {
+	n, err := q.InitiativeAdvanceFrom(ctx, sqlc.InitiativeAdvanceFromParams{
+		GameID: gameID,
+		Seat:   seat,
+	})
+	if err != nil {
+		return false, fmt.Errorf("advance initiative: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	return true, recordTurn(ctx, log, q, gameID)
+}
+
+// recordTurn adds a turn event for whoever holds initiative now.
+func recordTurn(ctx context.Context, log *slog.Logger, q *sqlc.Queries, gameID string) error {
 	playerID, err := q.InitiativeCurrentPlayer(ctx, gameID)
 	if errors.Is(err, pgx.ErrNoRows) {
-		// initiative landed on a gap (e.g. a player left); the turn still
-		// advanced, so just skip the turn event rather than failing
+		// initiative landed on a gap (seats close up when a player leaves,
+		// so this shouldn't happen); the turn still advanced, so just skip
+		// the turn event rather than failing
 		log.Warn("no player at current initiative", "game_id", gameID)
 		return nil
 	}
@@ -73,3 +184,78 @@ func advanceTurn(ctx context.Context, log *slog.Logger, q *sqlc.Queries, gameID
 		TargetID:  pgInt(playerID),
 	})
 }
+
+// startGame closes the pregame (inviting, or the writing phase) and begins
+// play: the deck is filled from the chosen deck around any the players wrote,
+// dealt onto the wheel and shuffled, and the first turn goes to initiative 1.
+// The claim and the setup share one transaction, so a failure partway leaves
+// the pregame as it was. It reports false, doing nothing, when the game
+// already left from, e.g. the last two cards of the writing phase landing at
+// once.
+func startGame(ctx context.Context, log *slog.Logger, gameID string, from int32) (bool, error) {
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	txq := queries.WithTx(tx)
+
+	n, err := txq.GameStateTransition(ctx, sqlc.GameStateTransitionParams{
+		ID:        gameID,
+		FromState: from,
+		ToState:   stateReady,
+	})
+	if err != nil {
+		return false, fmt.Errorf("claim start: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	// populate and shuffle the deck
+	if err := txq.GameCardsInitDeck(ctx, gameID); err != nil {
+		return false, fmt.Errorf("init deck: %w", err)
+	}
+	if err := txq.GameCardsDeal(ctx, gameID); err != nil {
+		return false, fmt.Errorf("deal deck: %w", err)
+	}
+	if err := txq.GameCardsShuffle(ctx, gameID); err != nil {
+		return false, fmt.Errorf("shuffle deck: %w", err)
+	}
+	log.Info("deck initialized and shuffled", "game_id", gameID)
+
+	// start initiative with first non-host player
+	err = txq.GameUpdate(ctx, sqlc.GameUpdateParams{
+		ID:                gameID,
+		StateID:           stateTurn,
+		InitiativeCurrent: pgInt(1),
+	})
+	if err != nil {
+		return false, fmt.Errorf("update initiative: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit start: %w", err)
+	}
+	log.Info("game started", "game_id", gameID, "initiative", 1)
+
+	// log the start, and the first player's turn so they hear the ding.
+	// state is already committed, so these are best-effort: a failure
+	// shouldn't fail a game that has already started.
+	if err := recordEvent(ctx, log, queries, sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "start",
+	}); err != nil {
+		log.Error("log start event", "error", err, "game_id", gameID)
+	}
+	firstPlayer, err := queries.InitiativeCurrentPlayer(ctx, gameID)
+	if err != nil {
+		log.Error("find first turn player", "error", err, "game_id", gameID)
+	} else if err := recordEvent(ctx, log, queries, sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "turn",
+		TargetID:  pgInt(firstPlayer),
+	}); err != nil {
+		log.Error("log first turn event", "error", err, "game_id", gameID)
+	}
+	return true, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// markSeen records that the player holding cookieKey is still at the table,
+// so the host's away check (Game.HostAway) stays current. A host coming back
+// invalidates the game, taking the vote away from the players. Failures are
+// logged and otherwise ignored: presence is best effort.
+func markSeen(ctx context.Context, log *slog.Logger, gameID, cookieKey string) {
+	returned, err := queries.PlayerSeen(ctx, sqlc.PlayerSeenParams{
+		GameID:     gameID,
+		SessionKey: pgtype.Text{String: cookieKey, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) { // seen moments ago
+		return
+	}
+	if err != nil {
+		log.Warn("mark player seen", "error", err, "game_id", gameID)
+		return
+	}
+	if returned {
+		log.Info("host returned", "game_id", gameID)
+		invalidate(ctx, gameID)
+	}
+}
+
+// playerSeat returns playerID's initiative, and false if they aren't in the
+// game.
+func (s *state) playerSeat(playerID int32) (int32, bool) {
+	for _, p := range s.Players {
+		if p.PlayerID == playerID {
+			return p.Initiative.Int32, true
+		}
+	}
+	return 0, false
+}
+
+// hostCandidate reports why playerID, a player in the game, can't take the
+// host seat right now, or nil if they can. The seat swap hands the old host
+// the candidate's seat, so a candidate mid-turn (a card drawn and not yet
+// acknowledged, pending or prompted) must finish first, and nobody may rule
+// on a challenge they're part of. A bot can't judge, so can't host.
+// The error is safe to show.
+func (s *state) hostCandidate(playerID int32) error {
+	if s.isBot(playerID) {
+		return errors.New("A bot can't host.")
+	}
+	seat, _ := s.playerSeat(playerID)
+	if seat == 0 {
+		return errors.New("They're already the host.")
+	}
+	switch s.Game.StateID {
+	case stateTurn:
+		if seat == s.Game.InitiativeCurrent.Int32 && s.AwaitingAck {
+			return errors.New("They're mid-turn; try again once their turn moves on.")
+		}
+	case statePending, statePrompt:
+		if seat == s.Game.InitiativeCurrent.Int32 {
+			return errors.New("They're mid-turn; try again once their turn moves on.")
+		}
+	case stateChallenge:
+		for _, inf := range s.Infractions {
+			if inf.Active.Bool && (inf.Accused == playerID || inf.Accuser == playerID) {
+				return errors.New("They're part of the open challenge; try again once it's decided.")
+			}
+		}
+	}
+	return nil
+}
+
+// handOffHost swaps the host seat to playerID, clears the host votes, and
+// records a host event, with actor the old host handing it over (NULL for a
+// vote). It reports false, doing nothing, when the swap found no seat to take:
+// the player left or already holds it.
+func handOffHost(ctx context.Context, log *slog.Logger, q *sqlc.Queries, gameID string, actor pgtype.Int4, playerID int32) (bool, error) {
+	n, err := q.HostTransfer(ctx, sqlc.HostTransferParams{
+		GameID:   gameID,
+		PlayerID: playerID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("swap host seat: %w", err)
+	}
+	if n != 2 {
+		return false, nil
+	}
+	if err := q.HostVotesClear(ctx, gameID); err != nil {
+		return false, fmt.Errorf("clear host votes: %w", err)
+	}
+	if err := recordEvent(ctx, log, q, sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "host",
+		ActorID:   actor,
+		TargetID:  pgInt(playerID),
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// hostAction handles the host handoff actions, in any state short of game
+// over. "transfer-host": the host hands the seat to player_id. "vote-host":
+// once the host has gone unseen past the game's host_timeout, a player votes
+// player_id the new host, who takes the seat on a majority of the non-host
+// players.
+func hostAction(w http.ResponseWriter, r *http.Request, log *slog.Logger, s *state, action, cookieKey string) {
+	gameID := s.Game.ID
+	candidate, err := strconv.ParseInt(r.FormValue("player_id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid player id", http.StatusBadRequest)
+		return
+	}
+	playerID := int32(candidate)
+	if _, ok := s.playerSeat(playerID); !ok {
+		http.Error(w, "player not in game", http.StatusBadRequest)
+		return
+	}
+	switch action {
+	case "transfer-host":
+		if !s.isHost(cookieKey) {
+			log.Warn("non-host attempted to transfer host", "game_id", gameID)
+			http.Error(w, "only the host can hand off the host seat", http.StatusForbidden)
+			return
+		}
+	case "vote-host":
+		if s.isHost(cookieKey) {
+			http.Error(w, "the host hands off with transfer-host", http.StatusForbidden)
+			return
+		}
+		if !s.Game.HostAway {
+			log.Debug("host vote while the host is here", "game_id", gameID)
+			http.Error(w, "the host is still here", http.StatusConflict)
+			return
+		}
+	}
+	if err := s.hostCandidate(playerID); err != nil {
+		log.Info("host candidate refused", "player_id", playerID, "reason", err)
+		w.Header().Set("HX-Trigger", `{"notice":`+strconv.Quote(err.Error())+`}`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tx, err := dbPool.Begin(r.Context())
+	if err != nil {
+		log.Error("begin transaction", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+	txq := queries.WithTx(tx)
+
+	var handedOff bool
+	switch action {
+	case "transfer-host":
+		handedOff, err = handOffHost(r.Context(), log, txq, gameID, pgInt(int32(s.CallerID)), playerID)
+		if err != nil {
+			log.Error("transfer host", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if !handedOff {
+			http.Error(w, "host seat already moved", http.StatusConflict)
+			return
+		}
+	case "vote-host":
+		if err := txq.HostVotesLock(r.Context(), gameID); err != nil {
+			log.Error("lock host votes", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if err := txq.HostVoteCast(r.Context(), sqlc.HostVoteCastParams{
+			GameID:      gameID,
+			VoterID:     int32(s.CallerID),
+			CandidateID: playerID,
+		}); err != nil {
+			log.Error("cast host vote", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("host vote cast", "candidate_id", playerID)
+		votes, err := txq.HostVotes(r.Context(), gameID)
+		if err != nil {
+			log.Error("tally host votes", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		// the leader takes the seat on a strict majority, unless they've
+		// since become unable to (then the vote just stands)
+		if len(votes) > 0 && votes[0].Votes*2 > int64(s.nonHostPlayers()) &&
+			s.hostCandidate(votes[0].CandidateID) == nil {
+			handedOff, err = handOffHost(r.Context(), log, txq, gameID, pgtype.Int4{}, votes[0].CandidateID)
+			if err != nil {
+				log.Error("hand off host by vote", "error", err)
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			playerID = votes[0].CandidateID
+		}
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Error("commit", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if handedOff {
+		log.Info("host handed off", "player_id", playerID, "by", action)
+	}
+	invalidate(r.Context(), gameID)
+	w.Header().Set("HX-Trigger", "refreshTable")
+	w.WriteHeader(http.StatusOK)
+}
+
+// hostLapsedTick refreshes the games whose host went away within the last
+// window, so players on an event stream see the vote without waiting on the
+// next change.
+func hostLapsedTick(ctx context.Context, window float64) {
+	gameIDs, err := queries.GamesHostLapsed(ctx, window)
+	if err != nil {
+		log.Error("list games whose host went away", "error", err)
+		return
+	}
+	for _, gameID := range gameIDs {
+		log.Info("host went away", "game_id", gameID)
+		invalidate(ctx, gameID)
+	}
+}
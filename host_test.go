@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostCandidate(t *testing.T) {
+	s := state{
+		Game: sqlc.GameStateRow{StateID: stateTurn, InitiativeCurrent: pgInt(1)},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 10, Initiative: pgInt(0)},
+			{PlayerID: 11, Initiative: pgInt(1)},
+			{PlayerID: 12, Initiative: pgInt(2)},
+		},
+		Infractions: []sqlc.Infractions{
+			{Accused: 12, Accuser: 11, Active: pgtype.Bool{Bool: false, Valid: true}},
+		},
+	}
+	require.Error(t, s.hostCandidate(10), "already the host")
+	require.NoError(t, s.hostCandidate(11), "a turn with no spin yet")
+	require.NoError(t, s.hostCandidate(12))
+	s.AwaitingAck = true
+	require.Error(t, s.hostCandidate(11), "a drawn card not yet acknowledged")
+	require.NoError(t, s.hostCandidate(12), "only the turn's player is mid-turn")
+	s.AwaitingAck = false
+
+	s.Game.StateID = statePending
+	require.Error(t, s.hostCandidate(11), "mid-turn")
+	require.NoError(t, s.hostCandidate(12))
+
+	s.Game.StateID = stateChallenge
+	require.NoError(t, s.hostCandidate(12), "a decided challenge doesn't count")
+	s.Infractions = append(s.Infractions, sqlc.Infractions{
+		Accused: 12, Accuser: 11, Active: pgtype.Bool{Bool: true, Valid: true},
+	})
+	require.Error(t, s.hostCandidate(11), "the accuser")
+	require.Error(t, s.hostCandidate(12), "the accused")
+
+	s.Players = append(s.Players, sqlc.GamePlayerPointsRow{PlayerID: 13, Initiative: pgInt(3), Bot: true})
+	require.Error(t, s.hostCandidate(13), "a bot")
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// maxImportBytes caps an uploaded import file.
+	maxImportBytes = 1 << 20
+	// issueNoResponse is what GitHub renders for an issue form field left
+	// empty.
+	issueNoResponse = "_No response_"
+)
+
+// adminToken guards the import endpoint; set from RULETTE_ADMIN_TOKEN, and
+// empty disables the endpoint.
+var adminToken string
+
+// importCard is one card read from an import file, before moderation.
+type importCard struct {
+	Type           string
+	Front          string
+	Back           string
+	ModifierEffect string
+	Source         string // where it came from, like "rules.csv:3"
+}
+
+// cardsFromCSV reads cards from CSV with a header row naming its columns:
+// front and back, optionally type (default rule) and modifier_effect, in any
+// order. Every invalid row is reported, by line.
+func cardsFromCSV(r io.Reader, source string) ([]importCard, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty csv")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "type", "front", "back", "modifier_effect":
+		default:
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate csv column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["front"]; !ok {
+		return nil, errors.New("csv needs a front column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var cards []importCard
+	var errs []error
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// csv.ParseError carries its own line number
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		card := importCard{
+			Type:           field(record, "type"),
+			Front:          field(record, "front"),
+			Back:           field(record, "back"),
+			ModifierEffect: field(record, "modifier_effect"),
+			Source:         fmt.Sprintf("%s:%d", source, line),
+		}
+		if card.Type == "" {
+			card.Type = "rule"
+		}
+		if err := validateCard(card.Type, card.Front, card.Back, card.ModifierEffect); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", card.Source, err))
+			continue
+		}
+		cards = append(cards, card)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(cards) == 0 {
+		return nil, errors.New("csv has no cards")
+	}
+	return cards, nil
+}
+
+// cardsFromIssue reads the rule card from the markdown body GitHub renders
+// for a .github/ISSUE_TEMPLATE/new-rule.yml issue: a "### Front text" and a
+// "### Back text" section.
+func cardsFromIssue(r io.Reader, source string) ([]importCard, error) {
+	sections := make(map[string][]string)
+	var current string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if heading, ok := strings.CutPrefix(line, "### "); ok {
+			current = strings.ToLower(strings.TrimSpace(heading))
+			continue
+		}
+		if current != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read issue: %w", err)
+	}
+	text := func(heading string) (string, bool) {
+		lines, ok := sections[heading]
+		v := strings.TrimSpace(strings.Join(lines, "\n"))
+		if v == issueNoResponse {
+			v = ""
+		}
+		return v, ok
+	}
+	front, ok := text("front text")
+	if !ok {
+		return nil, fmt.Errorf("%s: not a new rule issue, no front text section", source)
+	}
+	back, _ := text("back text")
+	card := importCard{Type: "rule", Front: front, Back: back, Source: source}
+	if err := validateCard(card.Type, card.Front, card.Back, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return []importCard{card}, nil
+}
+
+// parseImport reads cards in format, "csv" or "github" (an issue body).
+func parseImport(format string, r io.Reader, source string) ([]importCard, error) {
+	switch format {
+	case "csv":
+		return cardsFromCSV(r, source)
+	case "github":
+		return cardsFromIssue(r, source)
+	default:
+		return nil, fmt.Errorf("unknown import format %q: want csv or github", format)
+	}
+}
+
+// importCards queues cards for moderation in one transaction. Cards whose
+// front is already queued or in a live pack are skipped as duplicates.
+func importCards(ctx context.Context, cards []importCard) (queued, duplicates int, err error) {
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	txq := queries.WithTx(tx)
+	for _, c := range cards {
+		_, err := txq.CardImport(ctx, sqlc.CardImportParams{
+			Type:           c.Type,
+			Front:          c.Front,
+			Back:           pgtype.Text{String: c.Back, Valid: c.Back != ""},
+			ModifierEffect: pgtype.Text{String: c.ModifierEffect, Valid: c.ModifierEffect != ""},
+			Source:         c.Source,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			duplicates++
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("import %s: %w", c.Source, err)
+		}
+		queued++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("commit: %w", err)
+	}
+	return queued, duplicates, nil
+}
+
+// importCommand runs `rulette import [-format csv|github] [-dry-run] file...`,
+// queueing every card in the files for moderation. A file's format follows
+// its extension (.csv, or .md for an issue body) unless -format says
+// otherwise; "-" reads stdin, which needs -format. Nothing is queued unless
+// every file is valid. connect opens the database, once the files are read; a
+// dry run never calls it.
+func importCommand(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, connect func(context.Context) (func(), error)) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "csv, or github for a new rule issue body (default: by file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate the files without importing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: rulette import [-format csv|github] [-dry-run] file...")
+	}
+
+	var cards []importCard
+	var errs []error
+	for _, name := range fs.Args() {
+		f := *format
+		if f == "" {
+			switch strings.ToLower(filepath.Ext(name)) {
+			case ".csv":
+				f = "csv"
+			case ".md":
+				f = "github"
+			default:
+				errs = append(errs, fmt.Errorf("%s: can't tell the format, pass -format", name))
+				continue
+			}
+		}
+		var r io.Reader = stdin
+		if name != "-" {
+			file, err := os.Open(name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			defer file.Close()
+			r = file
+		}
+		parsed, err := parseImport(f, r, filepath.Base(name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cards = append(cards, parsed...)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "%d valid cards, nothing imported (dry run)\n", len(cards))
+		return nil
+	}
+	closeDB, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	queued, duplicates, err := importCards(ctx, cards)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "queued %d cards for moderation, skipped %d duplicates\n", queued, duplicates)
+	return nil
+}
+
+// moderateCommand runs `rulette moderate [-approve id,...] [-reject id,...]`,
+// listing the moderation queue by default. A card is approved by adding it to
+// a pack in cards/: -approve prints the queued cards as pack entries to paste
+// in, and the pack's next sync adopts each by its front, out of the queue.
+// -reject deletes queued cards.
+func moderateCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("moderate", flag.ContinueOnError)
+	approve := fs.String("approve", "", "queued card ids to print as pack entries, comma separated")
+	reject := fs.String("reject", "", "queued card ids to delete, comma separated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (*approve != "" && *reject != "") {
+		return errors.New("usage: rulette moderate [-approve id,...] [-reject id,...]")
+	}
+	if *reject != "" {
+		ids, err := cardIDs(*reject)
+		if err != nil {
+			return err
+		}
+		n, err := queries.CardsReject(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("reject cards: %w", err)
+		}
+		fmt.Fprintf(stdout, "rejected %d of %d cards\n", n, len(ids))
+		return nil
+	}
+
+	queued, err := queries.CardsPending(ctx)
+	if err != nil {
+		return fmt.Errorf("list queued cards: %w", err)
+	}
+	if *approve != "" {
+		ids, err := cardIDs(*approve)
+		if err != nil {
+			return err
+		}
+		var entries []packCard
+		for _, c := range queued {
+			if !slices.Contains(ids, c.ID) {
+				continue
+			}
+			entries = append(entries, packCard{
+				Slug:           suggestSlug(c.Front),
+				Type:           c.Type,
+				Front:          c.Front,
+				Back:           c.Back.String,
+				ModifierEffect: c.ModifierEffect.String,
+			})
+		}
+		if len(entries) < len(ids) {
+			return fmt.Errorf("only %d of %d ids are queued", len(entries), len(ids))
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(queued) == 0 {
+		fmt.Fprintln(stdout, "nothing queued")
+		return nil
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tSOURCE\tFRONT\tBACK")
+	for _, c := range queued {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", c.ID, c.Type, c.Source.String, c.Front, c.Back.String)
+	}
+	return tw.Flush()
+}
+
+// cardIDs parses a comma separated list of card ids.
+func cardIDs(list string) ([]int32, error) {
+	var ids []int32
+	for _, v := range strings.Split(list, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid card id %q", v)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// suggestSlug makes a pack card slug from the first few words of front, to
+// edit as needed.
+func suggestSlug(front string) string {
+	words := strings.FieldsFunc(strings.ToLower(front), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	return strings.Join(words[:min(len(words), 4)], "-")
+}
+
+// importHandler handles the '/library/import' endpoint.
+// - POST: queue the cards in the body for moderation. The body is CSV or a
+// new rule issue body, per ?format= or else the Content-Type (text/csv or
+// text/markdown). Needs "Authorization: Bearer <RULETTE_ADMIN_TOKEN>".
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	log := log.With("handler", "importHandler")
+	if adminToken == "" {
+		http.Error(w, "import disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		log.Warn("import without admin token")
+		http.Error(w, "admin token required", http.StatusUnauthorized)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = "csv"
+		case "text/markdown":
+			format = "github"
+		default:
+			http.Error(w, "set ?format=csv|github or a text/csv or text/markdown body",
+				http.StatusUnsupportedMediaType,
+			)
+			return
+		}
+	}
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	cards, err := parseImport(format, body, "upload")
+	if err != nil {
+		log.Info("import rejected", "format", format, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	queued, duplicates, err := importCards(r.Context(), cards)
+	if err != nil {
+		log.Error("import cards", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("cards imported", "format", format, "queued", queued, "duplicates", duplicates)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{
+		"queued":     queued,
+		"duplicates": duplicates,
+	})
+}
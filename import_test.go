@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardsFromCSV(t *testing.T) {
+	cards, err := cardsFromCSV(strings.NewReader(
+		"front,back,type\n"+
+			"in a whisper,a little too loudly,\n"+
+			"\"count to 10, in french\",,prompt\n",
+	), "rules.csv")
+	require.NoError(t, err)
+	require.Equal(t, []importCard{
+		{Type: "rule", Front: "in a whisper", Back: "a little too loudly", Source: "rules.csv:2"},
+		{Type: "prompt", Front: "count to 10, in french", Source: "rules.csv:3"},
+	}, cards)
+
+	// every bad row is reported, with its line
+	_, err = cardsFromCSV(strings.NewReader(
+		"type,front,back\n"+
+			"rule,no back,\n"+
+			"rule,fine,enif\n"+
+			"spell,a spell,\n",
+	), "rules.csv")
+	require.ErrorContains(t, err, "rules.csv:2: a rule needs a back")
+	require.ErrorContains(t, err, `rules.csv:4: unknown card type "spell"`)
+	require.NotContains(t, err.Error(), "rules.csv:3")
+
+	for name, input := range map[string]string{
+		"empty":          "",
+		"header only":    "front,back\n",
+		"unknown column": "front,back,points\nx,y,1\n",
+		"no front":       "back\nx\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := cardsFromCSV(strings.NewReader(input), "rules.csv")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCardsFromIssue(t *testing.T) {
+	body := "### Front text\r\n\r\nno filler words\r\n\r\n### Back text\r\n\r\nmostly filler words\r\n"
+	cards, err := cardsFromIssue(strings.NewReader(body), "issue-12")
+	require.NoError(t, err)
+	require.Equal(t, []importCard{{
+		Type: "rule", Front: "no filler words", Back: "mostly filler words", Source: "issue-12",
+	}}, cards)
+
+	_, err = cardsFromIssue(strings.NewReader(
+		"### Front text\n\nno filler words\n\n### Back text\n\n_No response_\n",
+	), "issue-13")
+	require.ErrorContains(t, err, "a rule needs a back")
+
+	_, err = cardsFromIssue(strings.NewReader("### Game URL\n\nhttps://example.com\n"), "issue-14")
+	require.ErrorContains(t, err, "not a new rule issue")
+}
+
+func TestImportCommandDryRun(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "rules.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("front,back\nin a whisper,loudly\n"), 0o600))
+	issuePath := filepath.Join(dir, "issue.md")
+	require.NoError(t, os.WriteFile(issuePath,
+		[]byte("### Front text\n\nno filler words\n\n### Back text\n\nall filler\n"), 0o600,
+	))
+
+	var out bytes.Buffer
+	err := importCommand(t.Context(), []string{"-dry-run", csvPath, issuePath}, nil, &out, nil)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "2 valid cards")
+
+	// stdin has no extension to go by
+	err = importCommand(t.Context(), []string{"-dry-run", "-"}, strings.NewReader(""), &out, nil)
+	require.ErrorContains(t, err, "pass -format")
+	err = importCommand(t.Context(), []string{"-dry-run", "-format", "github", "-"},
+		strings.NewReader("### Front text\n\nhush\n\n### Back text\n\nshout\n"), &out, nil,
+	)
+	require.NoError(t, err)
+}
+
+func TestSuggestSlug(t *testing.T) {
+	require.Equal(t, "in-a-hushed-tone", suggestSlug("In a hushed tone, always"))
+	require.Equal(t, "say-it-twice", suggestSlug("  say it... twice!"))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+// What becomes of a departing player's cards: back on the wheel, shredded,
+// or dealt round the players who remain.
+const (
+	cardsWheel = "wheel"
+	cardsShred = "shred"
+	cardsShare = "share"
+)
+
+// departure reports why playerID can't leave the game (or be kicked) right
+// now, or nil if they can. The host must hand off the seat first, and a
+// challenge or prompt must finish, since both resume the turn they
+// interrupted. The error is safe to show.
+func (s *state) departure(playerID int32) error {
+	seat, ok := s.playerSeat(playerID)
+	if !ok {
+		return errors.New("They've already left.")
+	}
+	if seat == 0 {
+		return errors.New("The host can't leave; hand the host seat to another player first.")
+	}
+	switch s.Game.StateID {
+	case stateChallenge:
+		return errors.New("Wait for the challenge to be decided.")
+	case statePrompt:
+		return errors.New("Wait for the prompt to finish.")
+	}
+	return nil
+}
+
+// removePlayer takes playerID out of the game: their cards go where cards
+// says, their seat is closed up, and event is recorded. Should they hold the
+// turn, it passes to the next seat; should nobody be left to take turns, the
+// game ends, which removePlayer reports. pgx.ErrNoRows means they already
+// left.
+func removePlayer(ctx context.Context, log *slog.Logger, q *sqlc.Queries, s *state, playerID int32, cards string, event sqlc.EventCreateParams) (bool, error) {
+	gameID := s.Game.ID
+	held := pgInt(playerID)
+	var moved int64
+	var err error
+	switch cards {
+	case cardsShred:
+		moved, err = q.GameCardsShredHeld(ctx, sqlc.GameCardsShredHeldParams{
+			GameID:   gameID,
+			PlayerID: held,
+		})
+	case cardsShare:
+		// a drawn modifier is spent with the turn, not inherited
+		if _, err := q.GameCardsShredModifiers(ctx, sqlc.GameCardsShredModifiersParams{
+			GameID:   gameID,
+			PlayerID: held,
+		}); err != nil {
+			return false, fmt.Errorf("shred modifiers: %w", err)
+		}
+		moved, err = q.GameCardsShare(ctx, sqlc.GameCardsShareParams{
+			GameID:   gameID,
+			PlayerID: playerID,
+		})
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s held cards: %w", cards, err)
+	}
+	// back on the wheel goes whatever's left: everything by default, or
+	// the hand nobody remained to share
+	returned, err := q.GameCardsReturn(ctx, sqlc.GameCardsReturnParams{
+		GameID:   gameID,
+		PlayerID: held,
+	})
+	if err != nil {
+		return false, fmt.Errorf("return held cards: %w", err)
+	}
+	log.Info("held cards disposed", "player_id", playerID, "cards", cards,
+		"moved", moved, "returned", returned)
+
+	seat, err := q.GamePlayerDelete(ctx, sqlc.GamePlayerDeleteParams{
+		GameID:   gameID,
+		PlayerID: playerID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete game player: %w", err)
+	}
+	if err := q.InitiativeCompact(ctx, sqlc.InitiativeCompactParams{
+		GameID: gameID,
+		Seat:   seat.Int32,
+	}); err != nil {
+		return false, fmt.Errorf("compact initiative: %w", err)
+	}
+	if err := recordEvent(ctx, log, q, event); err != nil {
+		return false, err
+	}
+
+	switch s.Game.StateID {
+	case stateCreated, stateInviting, stateWriting:
+		return false, nil // nobody's taking turns yet
+	}
+	if s.nonHostPlayers() <= 1 {
+		if err := q.GameUpdate(ctx, sqlc.GameUpdateParams{
+			ID:                gameID,
+			StateID:           stateOver,
+			InitiativeCurrent: pgInt(0),
+		}); err != nil {
+			return false, fmt.Errorf("end game: %w", err)
+		}
+		return true, recordEvent(ctx, log, q, sqlc.EventCreateParams{
+			GameID:    gameID,
+			EventType: "end",
+		})
+	}
+	if seat.Int32 != s.Game.InitiativeCurrent.Int32 {
+		return false, nil
+	}
+	switch s.Game.StateID {
+	case statePending:
+		// the modifier choice left with them
+		if _, err := q.GameStateTransition(ctx, sqlc.GameStateTransitionParams{
+			ID:        gameID,
+			FromState: statePending,
+			ToState:   stateTurn,
+		}); err != nil {
+			return false, fmt.Errorf("end pending turn: %w", err)
+		}
+	case stateTurn:
+	default:
+		return false, nil
+	}
+	next, err := q.InitiativeCurrentPlayer(ctx, gameID)
+	if err != nil {
+		return false, fmt.Errorf("find next turn player: %w", err)
+	}
+	return false, recordEvent(ctx, log, q, sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "turn",
+		TargetID:  pgInt(next),
+	})
+}
+
+// leaveAction handles a player leaving, in any state short of game over.
+// "leave": the caller gives up their seat. "kick": the host removes
+// player_id. Either way the form's cards (wheel, shred or share; wheel by
+// default) says what becomes of the departing player's cards.
+func leaveAction(w http.ResponseWriter, r *http.Request, log *slog.Logger, s *state, action, cookieKey string) {
+	gameID := s.Game.ID
+	playerID := int32(s.CallerID)
+	event := sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: action,
+		ActorID:   pgInt(playerID),
+	}
+	if action == "kick" {
+		if !s.isHost(cookieKey) {
+			log.Warn("non-host attempted to kick", "game_id", gameID)
+			http.Error(w, "only the host can kick players", http.StatusForbidden)
+			return
+		}
+		kicked, err := strconv.ParseInt(r.FormValue("player_id"), 10, 32)
+		if err != nil {
+			http.Error(w, "invalid player id", http.StatusBadRequest)
+			return
+		}
+		playerID = int32(kicked)
+		if _, ok := s.playerSeat(playerID); !ok {
+			http.Error(w, "player not in game", http.StatusBadRequest)
+			return
+		}
+		event.TargetID = pgInt(playerID)
+	}
+	cards := r.FormValue("cards")
+	switch cards {
+	case "":
+		cards = cardsWheel
+	case cardsWheel, cardsShred, cardsShare:
+	default:
+		http.Error(w, "cards must be wheel, shred or share", http.StatusBadRequest)
+		return
+	}
+	if err := s.departure(playerID); err != nil {
+		log.Info("departure refused", "player_id", playerID, "reason", err)
+		w.Header().Set("HX-Trigger", `{"notice":`+strconv.Quote(err.Error())+`}`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tx, err := dbPool.Begin(r.Context())
+	if err != nil {
+		log.Error("begin transaction", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+	ended, err := removePlayer(r.Context(), log, queries.WithTx(tx), s, playerID, cards, event)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "player already left", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error("remove player", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Error("commit", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("player removed", "player_id", playerID, "by", action, "game_ended", ended)
+	invalidate(r.Context(), gameID)
+	if action == "leave" {
+		w.Header().Set("HX-Redirect", "/")
+	} else {
+		w.Header().Set("HX-Trigger", "refreshTable")
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeparture(t *testing.T) {
+	s := state{
+		Game: sqlc.GameStateRow{StateID: stateTurn, InitiativeCurrent: pgInt(1)},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 10, Initiative: pgInt(0)},
+			{PlayerID: 11, Initiative: pgInt(1)},
+			{PlayerID: 12, Initiative: pgInt(2)},
+		},
+	}
+	require.Error(t, s.departure(10), "the host")
+	require.Error(t, s.departure(13), "not in the game")
+	require.NoError(t, s.departure(11), "mid-turn")
+	require.NoError(t, s.departure(12))
+
+	for _, stateID := range []int32{stateChallenge, statePrompt} {
+		s.Game.StateID = stateID
+		require.Error(t, s.departure(12), "state %d", stateID)
+	}
+}
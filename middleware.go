@@ -65,7 +65,9 @@ func rateMW(next http.Handler) http.Handler {
 			ip = host
 		}
 		var limiter *rate.Limiter
-		if c, err := r.Cookie(sessionCookieName); err == nil && c.Value != "" {
+		if token, ok := bearerToken(r); ok {
+			limiter = getSessionLimiter(token)
+		} else if c, err := r.Cookie(sessionCookieName); err == nil && c.Value != "" {
 			limiter = getSessionLimiter(c.Value)
 		} else {
 			limiter = getIPLimiter(ip)
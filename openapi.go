@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// openAPIDoc is the OpenAPI document for the API, built once from apiRoutes,
+// apiActions and the types they name, so it can't drift from what's served.
+var openAPIDoc = sync.OnceValue(func() []byte {
+	doc, err := json.Marshal(buildOpenAPI())
+	if err != nil {
+		panic("marshal openapi document: " + err.Error())
+	}
+	return doc
+})
+
+// openAPIHandler serves the API's OpenAPI document.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDoc())
+}
+
+// openAPI collects the document's schemas as the paths name them.
+type openAPI struct {
+	schemas map[string]any
+}
+
+func buildOpenAPI() map[string]any {
+	o := &openAPI{schemas: make(map[string]any)}
+	errorResponse := map[string]any{
+		"description": "error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": o.schema(reflect.TypeFor[apiError]())},
+		},
+	}
+	gameIDParam := map[string]any{
+		"name": "game_id", "in": "path", "required": true,
+		"schema": map[string]any{"type": "string"},
+	}
+
+	paths := make(map[string]any)
+	for _, route := range apiRoutes {
+		params := []any{}
+		if strings.Contains(route.Path, "{game_id}") {
+			params = append(params, gameIDParam)
+		}
+		for _, q := range route.Query {
+			params = append(params, map[string]any{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": map[string]any{"type": "integer"},
+			})
+		}
+		op := map[string]any{
+			"summary":    route.Summary,
+			"parameters": params,
+			"responses": map[string]any{
+				"200":     o.jsonResponse("ok", reflect.TypeOf(route.Response)),
+				"default": errorResponse,
+			},
+		}
+		if route.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": reflect.TypeOf(route.Body).NumField() > 0,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": o.schema(reflect.TypeOf(route.Body))},
+				},
+			}
+		}
+		paths[apiPrefix+route.Path] = map[string]any{strings.ToLower(route.Method): op}
+	}
+	for _, action := range apiActions {
+		path := apiPrefix + strings.Replace(apiActionsPath, "{action}", action.Name, 1)
+		paths[path] = map[string]any{
+			"post": map[string]any{
+				"summary":    action.Summary,
+				"parameters": []any{gameIDParam},
+				"requestBody": map[string]any{
+					"required": false,
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": o.schema(reflect.TypeOf(action.Params))},
+					},
+				},
+				"responses": map[string]any{
+					"200":     o.jsonResponse("done", reflect.TypeFor[apiActionResult]()),
+					"default": errorResponse,
+				},
+			},
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "rulette",
+			"version":     version,
+			"description": "Games as JSON. Reads take any seated player's or spectator's session, or a bearer token standing in for it; actions take a seated player's. Joining answers with a token.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": o.schemas,
+			"securitySchemes": map[string]any{
+				"session": map[string]any{"type": "apiKey", "in": "cookie", "name": sessionCookieName},
+				"bearer":  map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"session": []any{}}, map[string]any{"bearer": []any{}}},
+	}
+}
+
+func (o *openAPI) jsonResponse(description string, t reflect.Type) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": o.schema(t)},
+		},
+	}
+}
+
+// schema returns the schema for t. A struct is filed under components by its
+// name, less the api prefix, and referred to.
+func (o *openAPI) schema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[json.RawMessage]():
+		return map[string]any{} // any JSON
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := o.schema(t.Elem())
+		if _, ref := s["$ref"]; ref {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": o.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": o.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Struct:
+		name := schemaName(t)
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, done := o.schemas[name]; done {
+			return ref
+		}
+		o.schemas[name] = nil // claimed, in case t refers to itself
+		properties := make(map[string]any)
+		var required []string
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if tag == "-" || !field.IsExported() {
+				continue
+			}
+			properties[tag] = o.schema(field.Type)
+			if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+				required = append(required, tag)
+			}
+		}
+		s := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		o.schemas[name] = s
+		return ref
+	}
+	panic("no openapi schema for " + t.String())
+}
+
+// schemaName names a struct's schema: apiGame is Game.
+func schemaName(t reflect.Type) string {
+	name := []rune(strings.TrimPrefix(t.Name(), "api"))
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
@@ -35,6 +35,7 @@ var (
 	attrStateID    = attribute.Key("game.state_id")
 	attrCallerName = attribute.Key("game.caller_name")
 	attrAlert      = attribute.Key("game.alert")
+	attrDeckID     = attribute.Key("deck.id")
 )
 
 var (
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// cardPackFiles holds the curated card packs, one JSON file per pack.
+//
+//go:embed cards/*.json
+var cardPackFiles embed.FS
+
+// packSlug is the shape of pack and card slugs: lowercase words joined by
+// single hyphens.
+var packSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// cardPack is one cards/*.json file. Bump Version with every edit: a pack only
+// syncs into the database when its version is newer than the one there, so a
+// stale instance mid-deploy can't roll a pack back.
+type cardPack struct {
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Version     int32      `json:"version"`
+	Enabled     bool       `json:"enabled"` // on by default in new games
+	Cards       []packCard `json:"cards"`
+}
+
+// packCard is one card in a pack. Its slug is its identity: change the front
+// or back freely, but a new slug makes a new card (and retires the old one).
+type packCard struct {
+	Slug           string `json:"slug"`
+	Type           string `json:"type"`
+	Front          string `json:"front"`
+	Back           string `json:"back,omitempty"`
+	ModifierEffect string `json:"modifier_effect,omitempty"`
+}
+
+// validate checks a pack against the card rules the schema and the game rely
+// on, so a bad file fails at startup rather than mid-game.
+func (p cardPack) validate() error {
+	if !packSlug.MatchString(p.Slug) {
+		return fmt.Errorf("invalid pack slug %q", p.Slug)
+	}
+	if p.Name == "" {
+		return errors.New("pack needs a name")
+	}
+	if p.Version < 1 {
+		return fmt.Errorf("pack version must be at least 1, got %d", p.Version)
+	}
+	if len(p.Cards) == 0 {
+		return errors.New("pack has no cards")
+	}
+	seen := make(map[string]bool, len(p.Cards))
+	for _, c := range p.Cards {
+		if !packSlug.MatchString(c.Slug) {
+			return fmt.Errorf("invalid card slug %q", c.Slug)
+		}
+		if seen[c.Slug] {
+			return fmt.Errorf("duplicate card slug %q", c.Slug)
+		}
+		seen[c.Slug] = true
+		if err := validateCard(c.Type, c.Front, c.Back, c.ModifierEffect); err != nil {
+			return fmt.Errorf("card %q: %w", c.Slug, err)
+		}
+	}
+	return nil
+}
+
+// loadCardPacks reads and validates every *.json pack at the root of fsys,
+// sorted by slug.
+func loadCardPacks(fsys fs.FS) ([]cardPack, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("list packs: %w", err)
+	}
+	packs := make([]cardPack, 0, len(names))
+	slugs := make(map[string]string, len(names))
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read pack %s: %w", name, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		var p cardPack
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("parse pack %s: %w", name, err)
+		}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("pack %s: %w", name, err)
+		}
+		if other, ok := slugs[p.Slug]; ok {
+			return nil, fmt.Errorf("packs %s and %s share slug %q", other, name, p.Slug)
+		}
+		slugs[p.Slug] = name
+		packs = append(packs, p)
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Slug < packs[j].Slug })
+	return packs, nil
+}
+
+// embeddedCardPacks loads the packs built into the binary.
+func embeddedCardPacks() ([]cardPack, error) {
+	fsys, err := fs.Sub(cardPackFiles, "cards")
+	if err != nil {
+		return nil, err
+	}
+	return loadCardPacks(fsys)
+}
+
+// syncCardPacks brings the cards table in line with packs, in one transaction:
+// packs newer than the database are upserted card by card (keyed by slug, so
+// rewording keeps the card's id), cards a pack dropped are retired, and packs
+// missing from the list are retired with their cards. Retired cards are never
+// deleted, so games that dealt them keep their history.
+func syncCardPacks(ctx context.Context, packs []cardPack) error {
+	log := log.With("func", "syncCardPacks")
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	txq := queries.WithTx(tx)
+	if err := txq.PacksLock(ctx); err != nil {
+		return fmt.Errorf("lock packs: %w", err)
+	}
+
+	slugs := make([]string, 0, len(packs))
+	for _, p := range packs {
+		slugs = append(slugs, p.Slug)
+		current, err := txq.PackVersion(ctx, p.Slug)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("fetch pack %s version: %w", p.Slug, err)
+		case current.Version > p.Version:
+			log.Warn("database has a newer pack, skipping",
+				"pack", p.Slug, "version", p.Version, "database_version", current.Version,
+			)
+			continue
+		case current.Version == p.Version && !current.Retired:
+			log.Debug("pack up to date", "pack", p.Slug, "version", p.Version)
+			continue
+		}
+		if err := txq.PackUpsert(ctx, sqlc.PackUpsertParams{
+			Slug:        p.Slug,
+			Name:        p.Name,
+			Description: p.Description,
+			Version:     p.Version,
+			Enabled:     p.Enabled,
+		}); err != nil {
+			return fmt.Errorf("upsert pack %s: %w", p.Slug, err)
+		}
+		cardSlugs := make([]string, 0, len(p.Cards))
+		for _, c := range p.Cards {
+			cardSlugs = append(cardSlugs, c.Slug)
+			if err := txq.PackCardAdopt(ctx, sqlc.PackCardAdoptParams{
+				Pack:  p.Slug,
+				Slug:  c.Slug,
+				Front: c.Front,
+			}); err != nil {
+				return fmt.Errorf("adopt card %s/%s: %w", p.Slug, c.Slug, err)
+			}
+			if err := txq.PackCardUpsert(ctx, sqlc.PackCardUpsertParams{
+				Type:           c.Type,
+				Front:          c.Front,
+				Back:           pgtype.Text{String: c.Back, Valid: c.Back != ""},
+				ModifierEffect: pgtype.Text{String: c.ModifierEffect, Valid: c.ModifierEffect != ""},
+				Pack:           p.Slug,
+				Slug:           c.Slug,
+			}); err != nil {
+				return fmt.Errorf("upsert card %s/%s: %w", p.Slug, c.Slug, err)
+			}
+		}
+		retired, err := txq.PackCardsRetire(ctx, sqlc.PackCardsRetireParams{
+			Pack:  p.Slug,
+			Slugs: cardSlugs,
+		})
+		if err != nil {
+			return fmt.Errorf("retire cards of pack %s: %w", p.Slug, err)
+		}
+		log.Info("pack synced",
+			"pack", p.Slug, "version", p.Version,
+			"cards", len(p.Cards), "cards_retired", retired,
+		)
+	}
+
+	retired, err := txq.PacksRetire(ctx, slugs)
+	if err != nil {
+		return fmt.Errorf("retire removed packs: %w", err)
+	}
+	if retired > 0 {
+		log.Info("removed packs retired", "cards_retired", retired)
+	}
+	orphans, err := txq.PackOrphansRetire(ctx)
+	if err != nil {
+		return fmt.Errorf("retire unclaimed generic cards: %w", err)
+	}
+	if orphans > 0 {
+		log.Info("unclaimed generic cards retired", "cards_retired", orphans)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedCardPacks(t *testing.T) {
+	packs, err := embeddedCardPacks()
+	require.NoError(t, err)
+	require.NotEmpty(t, packs)
+
+	// every modifier effect must be dealt by some pack
+	effects := map[string]bool{}
+	for _, p := range packs {
+		for _, c := range p.Cards {
+			if c.Type == "modifier" {
+				effects[c.ModifierEffect] = true
+			}
+		}
+	}
+	for _, effect := range []string{modFlip, modShred, modClone, modTransfer} {
+		require.True(t, effects[effect], "no card has the %s effect", effect)
+	}
+}
+
+func TestLoadCardPacks(t *testing.T) {
+	pack := func(cards string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(
+			`{"slug":"test","name":"test","version":1,"enabled":true,"cards":[` + cards + `]}`,
+		)}
+	}
+	valid := `{"slug":"hush","type":"rule","front":"in a whisper","back":"loudly"}`
+
+	packs, err := loadCardPacks(fstest.MapFS{"test.json": pack(valid)})
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	require.Equal(t, "hush", packs[0].Cards[0].Slug)
+
+	tests := map[string]fstest.MapFS{
+		"rule without back": {"test.json": pack(
+			`{"slug":"hush","type":"rule","front":"in a whisper"}`,
+		)},
+		"prompt with back": {"test.json": pack(
+			`{"slug":"count","type":"prompt","front":"count to 10","back":"count down"}`,
+		)},
+		"unknown modifier effect": {"test.json": pack(
+			`{"slug":"swap","type":"modifier","front":"swap cards","modifier_effect":"swap"}`,
+		)},
+		"duplicate card slug": {"test.json": pack(valid + "," + valid)},
+		"bad card slug":       {"test.json": pack(`{"slug":"Hush!","type":"prompt","front":"hush"}`)},
+		"unknown field":       {"test.json": pack(`{"slug":"hush","type":"prompt","front":"hush","extra":1}`)},
+		"no cards":            {"test.json": pack(``)},
+		"duplicate pack slug": {"a.json": pack(valid), "b.json": pack(valid)},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadCardPacks(fsys)
+			require.Error(t, err)
+		})
+	}
+}
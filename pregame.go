@@ -20,8 +20,6 @@ const (
 	secretLength      = 32 // crypto/rand used for session key
 )
 
-// TODO: implement card selection stage of the game between invitation and spin.
-
 // setCookieErr make logs messages and sets HTTP status responses appropriately.
 func setCookieErr(w http.ResponseWriter, err error) {
 	switch err {
@@ -31,6 +29,10 @@ func setCookieErr(w http.ResponseWriter, err error) {
 	case ErrCookieInvalid:
 		log.Debug(ErrCookieInvalid.Error())
 		http.Error(w, "invalid session cookie", http.StatusForbidden)
+	case ErrTokenInvalid:
+		log.Debug(ErrTokenInvalid.Error())
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
 	default:
 		log.Error("unexpected error getting cookie", "error", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -48,6 +50,10 @@ const (
 	alertNotFound   = "not-found"
 	alertNameTaken  = "name-taken"
 	alertError      = "error"
+	// deck library codes, shown on the deck pages.
+	alertDeckNotFound = "deck-not-found"
+	alertDeckName     = "deck-name"
+	alertCardInvalid  = "card-invalid"
 	// session codes only tag the trace and redirect to join, no popup copy.
 	alertNoSession = "no-session"
 	alertNotMember = "not-member"
@@ -57,24 +63,35 @@ const (
 // copy shown on the destination page. Unknown or empty codes render no
 // popup. Shared by rootHandler and joinHandler's GET render.
 var alertMessages = map[string]string{
-	alertInProgress: "Game in progress cannot be joined.",
-	alertOver:       "Game over.",
-	alertNotFound:   "Game does not exist.",
-	alertNameTaken:  "Name taken, choose another.",
-	alertError:      "Server error, please try again.",
+	alertInProgress:   "Game in progress cannot be joined, only watched.",
+	alertOver:         "Game over.",
+	alertNotFound:     "Game does not exist.",
+	alertNameTaken:    "Name taken, choose another.",
+	alertError:        "Server error, please try again.",
+	alertDeckNotFound: "Deck does not exist.",
+	alertDeckName:     fmt.Sprintf("A deck needs a name of at most %d characters.", maxDeckNameLength),
+	alertCardInvalid: fmt.Sprintf(
+		"A card needs a front, and a rule a back too (at most %d characters each).",
+		maxCardLength,
+	),
 }
 
-// indexView is the data for index.html: just an optional popup message.
+// indexView is the data for index.html: an optional popup message, and the
+// create form's csrf token.
 type indexView struct {
-	Alert string
+	Alert     string
+	CSRFToken string
 }
 
 // joinView is the data for tmpl.join.html. The game state row is embedded so
 // the template's promoted fields (.ID, .StateName, ...) keep working, plus an
-// optional popup message.
+// optional popup message and the join form's csrf token. Started games offer
+// only to watch.
 type joinView struct {
 	sqlc.GameStateRow
-	Alert string
+	Alert     string
+	CSRFToken string
+	Started   bool
 }
 
 // redirectAlert bounces a full-page visitor home with a popup. code is a
@@ -95,7 +112,10 @@ func redirectAlert(w http.ResponseWriter, r *http.Request, code string) {
 // from which a user can start a new game with a POST to /create.
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	indexPath := path.Join("static", "html", "index.html")
-	data := indexView{Alert: alertMessages[r.URL.Query().Get("alert")]}
+	data := indexView{
+		Alert:     alertMessages[r.URL.Query().Get("alert")],
+		CSRFToken: csrfToken(r),
+	}
 	if err := renderPage(r.Context(), w, indexPath, baseURL(r), true, data); err != nil {
 		http.Error(w, "server error", http.StatusInternalServerError)
 		return
@@ -164,7 +184,7 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 				redirectAlert(w, r, alertError)
 				return
 			}
-			if s.isPlayerInGame(cookieKey) {
+			if s.canWatch(cookieKey) {
 				log.Warn("player attempted to rejoin, redirecting to game",
 					"game_id", gameID,
 				)
@@ -172,9 +192,10 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		// only a game still gathering players is joinable; for any other state
-		// bounce home with a popup instead of rendering a dead form, so an
-		// invite link to a started or over game says so on load.
+		// only a game still gathering players is joinable, and only a game
+		// not yet over is watchable; an over game bounces home with a popup
+		// instead of rendering a dead form, so an invite link says so on load.
+		var started bool
 		switch game.StateID {
 		case stateCreated, stateInviting:
 			// joinable: fall through to render the form below.
@@ -182,13 +203,9 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 			log.Warn("redirecting visitor home, game over")
 			redirectAlert(w, r, alertOver)
 			return
-		case stateReady, stateTurn, statePending, stateChallenge, stateEnding:
-			log.Warn("redirecting visitor home, game in progress",
-				"state_id", game.StateID,
-				"state_name", game.StateName,
-			)
-			redirectAlert(w, r, alertInProgress)
-			return
+		case stateWriting, stateReady, stateTurn, statePending, stateChallenge, stateEnding, statePrompt:
+			// watchable: render the form with only the watch button.
+			started = true
 		default:
 			// every defined state is handled above; reaching here means an
 			// unknown state id, which is a bug.
@@ -201,7 +218,12 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		w.Header().Set("Content-Type", "text/html")
 		templateFilepath := path.Join("static", "html", "tmpl.join.html")
-		data := joinView{GameStateRow: game, Alert: alertMessages[r.URL.Query().Get("alert")]}
+		data := joinView{
+			GameStateRow: game,
+			Alert:        alertMessages[r.URL.Query().Get("alert")],
+			CSRFToken:    csrfToken(r),
+			Started:      started,
+		}
 		if err := renderPage(r.Context(), w, templateFilepath, baseURL(r), true, data); err != nil {
 			log.Error("render template",
 				"error", err,
@@ -219,9 +241,13 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// spectate: watch without a seat, possible in any state short of over
+		spectate := r.FormValue("spectate") != ""
+
 		// reject rejoin: if the caller already has a session for this game,
-		// bounce to the game page rather than minting a new player/session
-		// (which would orphan the original identity and host initiative).
+		// seated or watching, bounce to the game page rather than minting a
+		// new player/session (which would orphan the original identity and
+		// host initiative).
 		if _, cookieKey, err := cookie(r); err == nil {
 			s, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
 			if err != nil {
@@ -232,7 +258,7 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 				redirectAlert(w, r, alertError)
 				return
 			}
-			if s.isPlayerInGame(cookieKey) {
+			if s.canWatch(cookieKey) {
 				log.Warn("POST rejoin blocked, redirecting to game",
 					"game_id", gameID,
 				)
@@ -246,18 +272,22 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 			log.Warn("join attempt to closed game")
 			redirectAlert(w, r, alertOver)
 			return
-		case stateReady, stateTurn, statePending, stateChallenge, stateEnding:
-			log.Warn("join attempt to game in progress",
-				"state_id", game.StateID,
-				"state_name", game.StateName,
-			)
-			redirectAlert(w, r, alertInProgress)
-			return
+		case stateWriting, stateReady, stateTurn, statePending, stateChallenge, stateEnding, statePrompt:
+			if !spectate {
+				log.Warn("join attempt to game in progress",
+					"state_id", game.StateID,
+					"state_name", game.StateName,
+				)
+				redirectAlert(w, r, alertInProgress)
+				return
+			}
+			fallthrough
 		case stateInviting, stateCreated:
 			// first join updates state from 'created' to 'inviting'
-			// NOTE: first to join is automatically set as host (initiative 0)
+			// NOTE: first to join is automatically set as host (initiative 0);
+			// a spectator takes no seat, so leaves the game waiting on its host
 			var firstJoin bool
-			if game.StateID == stateCreated {
+			if game.StateID == stateCreated && !spectate {
 				firstJoin = true
 				log.Debug("created game has first join, updating state to inviting")
 				err := queries.GameUpdate(r.Context(), sqlc.GameUpdateParams{
@@ -281,6 +311,19 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 				redirectAlert(w, r, alertError)
 				return
 			}
+			spectators, err := queries.GameSpectators(r.Context(), gameID)
+			if err != nil {
+				log.Error("failed to get game spectators",
+					"error", err,
+					"game_id", gameID,
+				)
+				redirectAlert(w, r, alertError)
+				return
+			}
+			names := make(map[string]bool, len(players)+len(spectators))
+			for _, spectator := range spectators {
+				names[spectator.Name] = true
+			}
 			var initiativeMax int
 			for _, player := range players {
 				if player.Initiative.Int32 > int32(initiativeMax) {
@@ -290,19 +333,20 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 					)
 					initiativeMax = int(player.Initiative.Int32)
 				}
-				// enforce no duplicate player names
-				if player.Name == username {
-					log.Warn("player already exists in game",
-						"game_id", gameID,
-						"username", username,
-					)
-					trace.SpanFromContext(r.Context()).SetAttributes(attrAlert.String(alertNameTaken))
-					http.Redirect(w, r,
-						fmt.Sprintf("/%s/join?alert=%s", gameID, alertNameTaken),
-						http.StatusSeeOther,
-					)
-					return
-				}
+				names[player.Name] = true
+			}
+			// enforce no duplicate names, seated or watching
+			if names[username] {
+				log.Warn("player already exists in game",
+					"game_id", gameID,
+					"username", username,
+				)
+				trace.SpanFromContext(r.Context()).SetAttributes(attrAlert.String(alertNameTaken))
+				http.Redirect(w, r,
+					fmt.Sprintf("/%s/join?alert=%s", gameID, alertNameTaken),
+					http.StatusSeeOther,
+				)
+				return
 			}
 			id, err := queries.PlayerCreate(r.Context(), username)
 			if err != nil {
@@ -320,15 +364,18 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 			}
 			secretStr := hex.EncodeToString(secret)
 
-			// first join is host
+			// first join is host; a spectator gets no initiative at all
 			initiative := pgtype.Int4{Int32: 0, Valid: true}
-			if !firstJoin {
+			switch {
+			case spectate:
+				initiative = pgtype.Int4{}
+			case !firstJoin:
 				initiative = pgtype.Int4{Int32: int32(initiativeMax + 1), Valid: true}
 			}
 			err = queries.GamePlayerCreate(r.Context(), sqlc.GamePlayerCreateParams{
 				PlayerID:   id,
 				GameID:     gameID,
-				SessionKey: pgtype.Text{String: secretStr, Valid: true},
+				SessionKey: pgtype.Text{String: hashSessionKey(secretStr), Valid: true},
 				Initiative: initiative,
 			})
 			if err != nil {
@@ -342,20 +389,17 @@ func joinHandler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// give the player their session cookie
-			http.SetCookie(w, &http.Cookie{
-				Name:  "session",
-				Value: fmt.Sprintf("%v:%s", id, secretStr),
-				Path:  fmt.Sprintf("/%s", gameID),
-			})
+			http.SetCookie(w, sessionCookie(r, gameID, id, secretStr))
 			log.Info("player joined game",
 				"game_id", gameID,
 				"player_id", id,
 				"username", username,
+				"spectator", spectate,
 			)
 			// NOTE: it's necessary to invalidate the cache for this game
 			// to prevent a new joiner from being declined due to a stale cache
 			// that doesn't yet know about their join.
-			cache.Delete(gameID)
+			invalidate(r.Context(), gameID)
 			http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
 		default:
 			// every defined state is handled above; reaching here means an
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replayPlayer is a player as the table stood at some point in a game.
+type replayPlayer struct {
+	PlayerID int32
+	Name     string
+	Points   int32
+	Host     bool
+	Bot      bool
+	Left     bool // gone from the game by then
+	Cards    []replayCard
+}
+
+// replayCard is a card a player held, face up as it showed.
+type replayCard struct {
+	ID      int32
+	Type    string
+	Content string
+}
+
+// replayFrame is the table as it stood once the first Step of a game's
+// Steps events had happened.
+type replayFrame struct {
+	Step    int
+	Steps   int
+	Started bool // the wheel was dealt
+	Players []replayPlayer
+	Wheel   []wheelSlotView
+}
+
+// replayView is what the replay page shows: a frame, and the game log up to
+// it.
+type replayView struct {
+	Game  sqlc.GameStateRow
+	Frame replayFrame
+	Log   template.HTML
+}
+
+// replayTable is the table mid-replay: where each game card is, and each
+// player's points. A card held is in owner, a card on the wheel in slot, and
+// a card in neither is out of play (not yet dealt, or shredded).
+type replayTable struct {
+	owner   map[int32]int32 // game card id -> player holding it
+	slot    map[int32]int32 // game card id -> wheel slot it sits in
+	flipped map[int32]bool
+	points  map[int32]int32
+	host    int32 // 0 while unknown: before a vote, the log doesn't say
+	left    map[int32]bool
+	cloned  map[int32]int // card id -> clones of it made so far
+	started bool
+}
+
+// replayer rebuilds a game's table from its event log. The log records what
+// happened but not every detail of it, so the replayer also reads the cards
+// as they stand now: a card's first spin says which slot it was dealt to (the
+// rest are still where they were dealt), and a departed player's cards are
+// found wherever they next turn up.
+type replayer struct {
+	events []sqlc.ReplayEventsRow
+	cards  []sqlc.ReplayCardsRow
+	byID   map[int32]sqlc.ReplayCardsRow
+	dealt  map[int32]int32   // card id -> the game card dealt of it
+	clones map[int32][]int32 // card id -> its clones' game card ids, oldest first
+	dealAt map[int32]int32   // game card id -> the slot it was dealt to
+}
+
+func newReplayer(events []sqlc.ReplayEventsRow, cards []sqlc.ReplayCardsRow) *replayer {
+	rp := &replayer{
+		events: events,
+		cards:  cards,
+		byID:   make(map[int32]sqlc.ReplayCardsRow, len(cards)),
+		dealt:  make(map[int32]int32, len(cards)),
+		clones: make(map[int32][]int32),
+		dealAt: make(map[int32]int32, len(cards)),
+	}
+	for _, c := range cards {
+		rp.byID[c.ID] = c
+		if c.FromClone.Bool {
+			rp.clones[c.CardID] = append(rp.clones[c.CardID], c.ID)
+		} else {
+			rp.dealt[c.CardID] = c.ID
+		}
+	}
+	for _, e := range events {
+		if e.EventType != "spin" || !e.SpinCardID.Valid {
+			continue
+		}
+		gc, ok := rp.dealt[e.SpinCardID.Int32]
+		if _, seen := rp.dealAt[gc]; ok && !seen {
+			rp.dealAt[gc] = e.SpinSlot.Int32
+		}
+	}
+	for _, c := range cards {
+		if _, seen := rp.dealAt[c.ID]; !seen && !c.FromClone.Bool && c.Slot.Valid {
+			rp.dealAt[c.ID] = c.Slot.Int32
+		}
+	}
+	return rp
+}
+
+// frame replays the first step events onto s's players and wheel. s is the
+// game as it stands now, for its seats, starting points and host.
+func (rp *replayer) frame(s *state, step int) replayFrame {
+	step = min(max(step, 0), len(rp.events))
+	t := &replayTable{
+		owner:   make(map[int32]int32),
+		slot:    make(map[int32]int32),
+		flipped: make(map[int32]bool),
+		points:  make(map[int32]int32),
+		left:    make(map[int32]bool),
+		cloned:  make(map[int32]int),
+	}
+
+	// the seated, in seat order, then the departed as they left
+	var players []replayPlayer
+	seen := make(map[int32]bool)
+	for _, p := range s.Players {
+		players = append(players, replayPlayer{PlayerID: p.PlayerID, Name: p.Name, Bot: p.Bot})
+		seen[p.PlayerID] = true
+		if p.Initiative.Int32 == 0 {
+			t.host = p.PlayerID
+		}
+	}
+	for _, e := range rp.events {
+		id, name := e.ActorID, e.ActorName
+		if e.EventType == "kick" {
+			id, name = e.TargetID, e.TargetName
+		}
+		if (e.EventType == "leave" || e.EventType == "kick") && id.Valid && !seen[id.Int32] {
+			players = append(players, replayPlayer{PlayerID: id.Int32, Name: name.String})
+			seen[id.Int32] = true
+		}
+	}
+	for _, p := range players {
+		t.points[p.PlayerID] = s.Game.StartingPoints
+	}
+	// the host now was handed the seat by whoever held it before, back to
+	// the first vote the log can't see past
+	for i := len(rp.events) - 1; i >= 0; i-- {
+		if rp.events[i].EventType == "host" {
+			t.host = rp.events[i].ActorID.Int32
+		}
+	}
+
+	for i := range step {
+		rp.apply(t, i)
+	}
+
+	f := replayFrame{Step: step, Steps: len(rp.events), Started: t.started}
+	for _, p := range players {
+		p.Points = t.points[p.PlayerID]
+		p.Host = p.PlayerID == t.host
+		p.Left = t.left[p.PlayerID]
+		for _, c := range rp.cards {
+			if owner, ok := t.owner[c.ID]; !ok || owner != p.PlayerID {
+				continue
+			}
+			content := c.Front
+			if t.flipped[c.ID] && c.Back.String != "" {
+				content = c.Back.String
+			}
+			p.Cards = append(p.Cards, replayCard{ID: c.ID, Type: c.Type, Content: content})
+		}
+		f.Players = append(f.Players, p)
+	}
+	if t.started {
+		stacks := make(map[int32]int64, s.Game.WheelSlots)
+		for _, slot := range t.slot {
+			stacks[slot]++
+		}
+		for slot := int32(1); slot <= s.Game.WheelSlots; slot++ {
+			f.Wheel = append(f.Wheel, wheelSlotView{Slot: slot, StackSize: stacks[slot]})
+		}
+	}
+	return f
+}
+
+// apply plays event i onto t.
+func (rp *replayer) apply(t *replayTable, i int) {
+	// a drawn modifier or prompt is spent by whatever comes next: the
+	// modifier's own event, the prompt's ruling, or the turn moving on
+	for gc := range t.owner {
+		if rp.byID[gc].Type != "rule" {
+			delete(t.owner, gc)
+		}
+	}
+	e := rp.events[i]
+	gc := e.GameCardID.Int32
+	switch e.EventType {
+	case "start":
+		t.started = true
+		for gc, slot := range rp.dealAt {
+			t.slot[gc] = slot
+		}
+	case "spin":
+		if gc, ok := rp.dealt[e.SpinCardID.Int32]; ok && e.SpinCardID.Valid {
+			delete(t.slot, gc)
+			t.owner[gc] = e.ActorID.Int32
+			t.flipped[gc] = false
+		}
+	case "flip":
+		t.flipped[gc] = !t.flipped[gc]
+	case "shred":
+		delete(t.owner, gc)
+	case "clone":
+		cardID := rp.byID[gc].CardID
+		if n := t.cloned[cardID]; n < len(rp.clones[cardID]) {
+			clone := rp.clones[cardID][n]
+			t.cloned[cardID]++
+			t.owner[clone] = e.TargetID.Int32
+			t.flipped[clone] = t.flipped[gc]
+		}
+	case "transfer":
+		if _, held := t.owner[gc]; held {
+			t.owner[gc] = e.TargetID.Int32
+		}
+	case "points", "prompt":
+		if e.PointsPlayerID.Valid {
+			t.points[e.PointsPlayerID.Int32] += e.PointsDelta.Int32
+		}
+	case "undo":
+		// the undo's own detail rows say what it put back: the card
+		// returned to its sender or the clone shredded, and the points
+		switch e.UndoneType {
+		case "transfer":
+			if _, held := t.owner[gc]; held {
+				t.owner[gc] = e.TargetID.Int32
+			}
+		case "clone":
+			delete(t.owner, gc)
+		}
+		if e.PointsPlayerID.Valid {
+			t.points[e.PointsPlayerID.Int32] += e.PointsDelta.Int32
+		}
+	case "host":
+		t.host = e.TargetID.Int32
+	case "leave", "kick":
+		gone := e.ActorID.Int32
+		if e.EventType == "kick" {
+			gone = e.TargetID.Int32
+		}
+		t.left[gone] = true
+		for gc, owner := range t.owner {
+			if owner == gone {
+				rp.settle(t, gc, i)
+			}
+		}
+	}
+}
+
+// settle puts a departed player's card where it went when they left at
+// event i, which the log doesn't say: back on the wheel if it's spun again
+// or sits there now, to whoever next plays it or holds it now, or out of
+// play.
+func (rp *replayer) settle(t *replayTable, gc int32, i int) {
+	delete(t.owner, gc)
+	t.flipped[gc] = false
+	for _, e := range rp.events[i+1:] {
+		switch e.EventType {
+		case "spin":
+			if e.SpinCardID.Valid && rp.dealt[e.SpinCardID.Int32] == gc && !rp.byID[gc].FromClone.Bool {
+				t.slot[gc] = e.SpinSlot.Int32
+				return
+			}
+		case "flip", "shred", "clone", "transfer":
+			if e.GameCardID.Int32 == gc {
+				t.owner[gc] = e.ActorID.Int32
+				return
+			}
+		}
+	}
+	c := rp.byID[gc]
+	switch {
+	case c.Shredded.Bool:
+	case c.PlayerID.Valid:
+		t.owner[gc] = c.PlayerID.Int32
+	case c.Slot.Valid:
+		t.slot[gc] = c.Slot.Int32
+	}
+}
+
+// replayHandler handles the '/{game_id}/replay' endpoint: the table as it
+// stood after any event, with a scrubber to walk the game through. ?step=<n>
+// picks the frame after the first n events; none, or one past the end, is
+// the table as it stands. Anyone who may watch the game may replay it,
+// finished or not.
+func replayHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("game_id")
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(attrGameID.String(gameID))
+	log := log.With("handler", "replayHandler", "game_id", gameID)
+
+	if r.Method != http.MethodGet {
+		log.Debug("unsupported method", "method", r.Method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cookieID, cookieKey, err := cookie(r)
+	if err != nil {
+		if err != ErrCookieMissing && err != ErrCookieInvalid {
+			log.Error("unexpected error getting cookie", "error", err)
+			redirectAlert(w, r, alertError)
+			return
+		}
+		span.SetAttributes(
+			attrAlert.String(alertNoSession),
+			semconv.ErrorMessage(err.Error()),
+		)
+		http.Redirect(w, r, fmt.Sprintf("/%s/join", gameID), http.StatusSeeOther)
+		return
+	}
+	span.SetAttributes(attrPlayerID.String(cookieID))
+	log = log.With("cookie_id", cookieID)
+
+	s, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
+	if err != nil {
+		if err == ErrStateNoGame {
+			log.Warn("game not found")
+			redirectAlert(w, r, alertNotFound)
+			return
+		}
+		log.Error("unexpected error fetching game state", "error", err)
+		redirectAlert(w, r, alertError)
+		return
+	}
+	if !s.canWatch(cookieKey) {
+		log.Warn("prohibiting unauthorized replay")
+		span.SetAttributes(attrAlert.String(alertNotMember))
+		http.Redirect(w, r, fmt.Sprintf("/%s/join", gameID), http.StatusSeeOther)
+		return
+	}
+
+	events, err := queries.ReplayEvents(r.Context(), gameID)
+	if err != nil {
+		log.Error("list replay events", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	cards, err := queries.ReplayCards(r.Context(), gameID)
+	if err != nil {
+		log.Error("list replay cards", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	feed, err := queries.EventListSince(r.Context(), sqlc.EventListSinceParams{GameID: gameID})
+	if err != nil {
+		log.Error("list events", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	step := len(events)
+	if n, err := strconv.Atoi(r.URL.Query().Get("step")); err == nil {
+		step = n
+	}
+	v := replayView{
+		Game:  s.Game,
+		Frame: newReplayer(events, cards).frame(&s, step),
+	}
+
+	// the log up to the frame, worded as the game page words it
+	var feedHTML bytes.Buffer
+	feedPath := path.Join("static", "html", "tmpl.events.html")
+	if err := renderTemplate(r.Context(), &feedHTML, feedPath, feed[:min(v.Frame.Step, len(feed))]); err != nil {
+		log.Error("render events", "error", err, "template", feedPath)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	v.Log = template.HTML(feedHTML.String())
+
+	filepath := path.Join("static", "html", "tmpl.replay.html")
+	if err := renderPage(r.Context(), w, filepath, baseURL(r), true, v); err != nil {
+		log.Error("render template", "error", err, "template", filepath)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
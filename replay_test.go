@@ -0,0 +1,210 @@
+package main
+
+import (
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// replayTestGame is a short game: three players spin, one clones a rule to
+// another, who is penalized, then one leaves with their cards going back on
+// the wheel, the host hands over the seat, and a rule is shredded.
+func replayTestGame() (*state, []sqlc.ReplayEventsRow, []sqlc.ReplayCardsRow) {
+	s := &state{
+		Game: sqlc.GameStateRow{ID: "replay", StartingPoints: 20, WheelSlots: 3},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 11, Name: "Ann", Initiative: pgInt(0)},
+			{PlayerID: 10, Name: "Host", Initiative: pgInt(1)},
+			{PlayerID: 12, Name: "Bo", Initiative: pgInt(2), Bot: true},
+		},
+	}
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: true} }
+	spin := func(id, actor, slot, card int32) sqlc.ReplayEventsRow {
+		return sqlc.ReplayEventsRow{ID: id, EventType: "spin", ActorID: pgInt(actor), SpinSlot: pgInt(slot), SpinCardID: pgInt(card)}
+	}
+	events := []sqlc.ReplayEventsRow{
+		{ID: 1, EventType: "start"},
+		spin(2, 11, 1, 101),
+		spin(3, 12, 2, 102),
+		spin(4, 13, 3, 105),
+		spin(5, 11, 1, 103),
+		{ID: 6, EventType: "clone", ActorID: pgInt(11), TargetID: pgInt(12), GameCardID: pgInt(1)},
+		{ID: 7, EventType: "flip", ActorID: pgInt(12), GameCardID: pgInt(2)},
+		{ID: 8, EventType: "points", TargetID: pgInt(12), PointsPlayerID: pgInt(12), PointsDelta: pgInt(-3)},
+		{ID: 9, EventType: "leave", ActorID: pgInt(13), ActorName: text("Dee")},
+		{ID: 10, EventType: "prompt", TargetID: pgInt(11), PointsPlayerID: pgInt(11), PointsDelta: pgInt(2)},
+		{ID: 11, EventType: "host", ActorID: pgInt(10), TargetID: pgInt(11)},
+		{ID: 12, EventType: "shred", ActorID: pgInt(12), GameCardID: pgInt(2)},
+		{ID: 13, EventType: "end"},
+	}
+	cards := []sqlc.ReplayCardsRow{
+		{ID: 1, CardID: 101, PlayerID: pgInt(11), Type: "rule", Front: "talk like a pirate"},
+		{ID: 2, CardID: 102, PlayerID: pgInt(12), Shredded: pgtype.Bool{Bool: true, Valid: true}, Type: "rule", Front: "no names", Back: text("only names")},
+		{ID: 3, CardID: 103, PlayerID: pgInt(11), Shredded: pgtype.Bool{Bool: true, Valid: true}, Type: "modifier", Front: "clone"},
+		{ID: 4, CardID: 104, Slot: pgInt(2), Type: "rule", Front: "never spun"},
+		{ID: 5, CardID: 101, PlayerID: pgInt(12), FromClone: pgtype.Bool{Bool: true, Valid: true}, Type: "rule", Front: "talk like a pirate"},
+		{ID: 6, CardID: 105, Slot: pgInt(3), Type: "rule", Front: "left behind"},
+	}
+	return s, events, cards
+}
+
+func TestReplayFrame(t *testing.T) {
+	s, events, cards := replayTestGame()
+	rp := newReplayer(events, cards)
+
+	held := func(f replayFrame) map[string][]string {
+		m := make(map[string][]string)
+		for _, p := range f.Players {
+			for _, c := range p.Cards {
+				m[p.Name] = append(m[p.Name], c.Content)
+			}
+		}
+		return m
+	}
+	stacks := func(f replayFrame) []int64 {
+		var n []int64
+		for _, w := range f.Wheel {
+			n = append(n, w.StackSize)
+		}
+		return n
+	}
+	player := func(f replayFrame, name string) replayPlayer {
+		for _, p := range f.Players {
+			if p.Name == name {
+				return p
+			}
+		}
+		t.Fatalf("no player %s", name)
+		return replayPlayer{}
+	}
+
+	f := rp.frame(s, 0)
+	require.False(t, f.Started)
+	require.Empty(t, f.Wheel)
+	require.Equal(t, 13, f.Steps)
+	require.Equal(t, []string{"Ann", "Host", "Bo", "Dee"}, []string{f.Players[0].Name, f.Players[1].Name, f.Players[2].Name, f.Players[3].Name})
+	require.True(t, player(f, "Host").Host, "the host before the handoff")
+	require.True(t, player(f, "Bo").Bot)
+	require.Equal(t, int32(20), player(f, "Dee").Points)
+
+	f = rp.frame(s, 1)
+	require.True(t, f.Started)
+	require.Equal(t, []int64{2, 2, 1}, stacks(f), "each card dealt where it was first spun, or still sits")
+
+	f = rp.frame(s, 5)
+	require.Equal(t, []int64{0, 1, 0}, stacks(f))
+	require.Equal(t, []string{"talk like a pirate", "clone"}, held(f)["Ann"], "the modifier shows while it's pending")
+
+	f = rp.frame(s, 6)
+	require.Equal(t, []string{"talk like a pirate"}, held(f)["Ann"], "the modifier is spent")
+	require.Equal(t, []string{"no names", "talk like a pirate"}, held(f)["Bo"])
+
+	f = rp.frame(s, 8)
+	require.Equal(t, []string{"only names", "talk like a pirate"}, held(f)["Bo"])
+	require.Equal(t, int32(17), player(f, "Bo").Points)
+
+	f = rp.frame(s, 9)
+	require.True(t, player(f, "Dee").Left)
+	require.Empty(t, held(f)["Dee"])
+	require.Equal(t, []int64{0, 1, 1}, stacks(f), "a departed player's rule went back on the wheel")
+
+	f = rp.frame(s, 11)
+	require.Equal(t, int32(22), player(f, "Ann").Points)
+	require.True(t, player(f, "Ann").Host)
+	require.False(t, player(f, "Host").Host)
+
+	f = rp.frame(s, 99)
+	require.Equal(t, 13, f.Step, "past the end is the end")
+	require.Equal(t, []string{"talk like a pirate"}, held(f)["Bo"])
+	require.Equal(t, 0, rp.frame(s, -4).Step)
+}
+
+func TestReplaySettle(t *testing.T) {
+	s := &state{
+		Game:    sqlc.GameStateRow{StartingPoints: 20, WheelSlots: 2},
+		Players: []sqlc.GamePlayerPointsRow{{PlayerID: 10, Initiative: pgInt(0)}, {PlayerID: 11, Initiative: pgInt(1)}, {PlayerID: 12, Initiative: pgInt(2)}},
+	}
+	events := []sqlc.ReplayEventsRow{
+		{ID: 1, EventType: "start"},
+		{ID: 2, EventType: "spin", ActorID: pgInt(13), SpinSlot: pgInt(1), SpinCardID: pgInt(101)},
+		{ID: 3, EventType: "spin", ActorID: pgInt(13), SpinSlot: pgInt(2), SpinCardID: pgInt(102)},
+		{ID: 4, EventType: "spin", ActorID: pgInt(13), SpinSlot: pgInt(1), SpinCardID: pgInt(103)},
+		{ID: 5, EventType: "kick", ActorID: pgInt(10), TargetID: pgInt(13)},
+		// shared out: card 1 turns up with 11, who passes it on
+		{ID: 6, EventType: "transfer", ActorID: pgInt(11), TargetID: pgInt(12), GameCardID: pgInt(1)},
+		// back on the wheel: card 2 is spun again
+		{ID: 7, EventType: "spin", ActorID: pgInt(12), SpinSlot: pgInt(2), SpinCardID: pgInt(102)},
+	}
+	cards := []sqlc.ReplayCardsRow{
+		{ID: 1, CardID: 101, PlayerID: pgInt(12), Type: "rule", Front: "one"},
+		{ID: 2, CardID: 102, PlayerID: pgInt(12), Type: "rule", Front: "two"},
+		{ID: 3, CardID: 103, Shredded: pgtype.Bool{Bool: true, Valid: true}, PlayerID: pgInt(13), Type: "rule", Front: "three"},
+	}
+	rp := newReplayer(events, cards)
+
+	owners := func(step int) map[int32][]int32 {
+		m := make(map[int32][]int32)
+		for _, p := range rp.frame(s, step).Players {
+			for _, c := range p.Cards {
+				m[p.PlayerID] = append(m[p.PlayerID], c.ID)
+			}
+		}
+		return m
+	}
+	require.Equal(t, map[int32][]int32{13: {1, 2, 3}}, owners(4))
+	require.Equal(t, map[int32][]int32{11: {1}}, owners(5), "one shared, one back on the wheel, one shredded")
+	f := rp.frame(s, 5)
+	require.Equal(t, int64(1), f.Wheel[1].StackSize)
+	require.Equal(t, map[int32][]int32{12: {1, 2}}, owners(7))
+}
+
+func TestReplayUndo(t *testing.T) {
+	s := &state{
+		Game:    sqlc.GameStateRow{StartingPoints: 20, WheelSlots: 2},
+		Players: []sqlc.GamePlayerPointsRow{{PlayerID: 10, Initiative: pgInt(0)}, {PlayerID: 11, Initiative: pgInt(1)}, {PlayerID: 12, Initiative: pgInt(2)}},
+	}
+	events := []sqlc.ReplayEventsRow{
+		{ID: 1, EventType: "start"},
+		{ID: 2, EventType: "spin", ActorID: pgInt(11), SpinSlot: pgInt(1), SpinCardID: pgInt(101)},
+		{ID: 3, EventType: "spin", ActorID: pgInt(12), SpinSlot: pgInt(2), SpinCardID: pgInt(102)},
+		{ID: 4, EventType: "transfer", ActorID: pgInt(11), TargetID: pgInt(12), GameCardID: pgInt(1)},
+		{ID: 5, EventType: "undo", ActorID: pgInt(10), TargetID: pgInt(11), GameCardID: pgInt(1), UndoneType: "transfer"},
+		{ID: 6, EventType: "clone", ActorID: pgInt(12), TargetID: pgInt(11), GameCardID: pgInt(2)},
+		{ID: 7, EventType: "undo", ActorID: pgInt(10), TargetID: pgInt(11), GameCardID: pgInt(3), UndoneType: "clone"},
+		{ID: 8, EventType: "points", TargetID: pgInt(12), PointsPlayerID: pgInt(12), PointsDelta: pgInt(-4)},
+		{ID: 9, EventType: "decide", TargetID: pgInt(11)},
+		{ID: 10, EventType: "undo", ActorID: pgInt(10), TargetID: pgInt(12), PointsPlayerID: pgInt(12), PointsDelta: pgInt(4), UndoneType: "decide"},
+	}
+	cards := []sqlc.ReplayCardsRow{
+		{ID: 1, CardID: 101, PlayerID: pgInt(11), Type: "rule", Front: "one"},
+		{ID: 2, CardID: 102, PlayerID: pgInt(12), Type: "rule", Front: "two"},
+		{ID: 3, CardID: 102, PlayerID: pgInt(11), Shredded: pgtype.Bool{Bool: true, Valid: true}, FromClone: pgtype.Bool{Bool: true, Valid: true}, Type: "rule", Front: "two"},
+	}
+	rp := newReplayer(events, cards)
+
+	owners := func(step int) map[int32][]int32 {
+		m := make(map[int32][]int32)
+		for _, p := range rp.frame(s, step).Players {
+			for _, c := range p.Cards {
+				m[p.PlayerID] = append(m[p.PlayerID], c.ID)
+			}
+		}
+		return m
+	}
+	points := func(step int) int32 {
+		for _, p := range rp.frame(s, step).Players {
+			if p.PlayerID == 12 {
+				return p.Points
+			}
+		}
+		return 0
+	}
+	require.Equal(t, map[int32][]int32{12: {1, 2}}, owners(4))
+	require.Equal(t, map[int32][]int32{11: {1}, 12: {2}}, owners(5), "the card went back to its sender")
+	require.Equal(t, map[int32][]int32{11: {1, 3}, 12: {2}}, owners(6))
+	require.Equal(t, map[int32][]int32{11: {1}, 12: {2}}, owners(7), "the clone is gone")
+	require.Equal(t, int32(16), points(9))
+	require.Equal(t, int32(20), points(10), "the penalty came back")
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grackleclub/postgres"
+	"github.com/grackleclub/rulette/client"
 	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
@@ -83,10 +91,183 @@ func TestGame(t *testing.T) {
 	require.NotNil(t, queries)
 	t.Log("queries created")
 
+	var packs []cardPack
 	t.Run("run migration", func(t *testing.T) {
 		result, err := db.Conn.ExecContext(ctx, dbSchema)
 		require.NoError(t, err)
 		t.Log("schema up: ", result)
+		packs, err = embeddedCardPacks()
+		require.NoError(t, err)
+		require.NoError(t, syncCardPacks(ctx, packs))
+	})
+	t.Run("card packs sync", func(t *testing.T) {
+		live := func() int {
+			var n int
+			require.NoError(t, dbPool.QueryRow(ctx,
+				"SELECT COUNT(*) FROM cards WHERE generic IS TRUE AND retired IS FALSE",
+			).Scan(&n))
+			return n
+		}
+		card := func(pack, slug string) (id int, front string, retired bool) {
+			require.NoError(t, dbPool.QueryRow(ctx,
+				"SELECT id, front, retired FROM cards WHERE pack = $1 AND slug = $2",
+				pack, slug,
+			).Scan(&id, &front, &retired))
+			return id, front, retired
+		}
+		var total int
+		for _, p := range packs {
+			total += len(p.Cards)
+		}
+		require.Equal(t, total, live())
+
+		// syncing the same versions again changes nothing
+		require.NoError(t, syncCardPacks(ctx, packs))
+		require.Equal(t, total, live())
+
+		// copies packs, bumped to version, minus the cards and packs dropped
+		edit := func(version int32, drop ...string) []cardPack {
+			var out []cardPack
+			for _, p := range packs {
+				if slices.Contains(drop, p.Slug) {
+					continue
+				}
+				p.Version = version
+				p.Cards = slices.DeleteFunc(slices.Clone(p.Cards), func(c packCard) bool {
+					return slices.Contains(drop, p.Slug+"/"+c.Slug)
+				})
+				out = append(out, p)
+			}
+			return out
+		}
+
+		// a reworded card keeps its id; a dropped card is retired, not deleted
+		whisperID, whisper, _ := card("core", "whisper")
+		singingID, _, _ := card("core", "singing")
+		edited := edit(2, "core/singing")
+		for i, c := range edited[0].Cards {
+			if c.Slug == "whisper" {
+				edited[0].Cards[i].Front = "in a stage whisper"
+			}
+		}
+		require.NoError(t, syncCardPacks(ctx, edited))
+		id, front, retired := card("core", "whisper")
+		require.Equal(t, whisperID, id)
+		require.Equal(t, "in a stage whisper", front)
+		require.False(t, retired)
+		id, _, retired = card("core", "singing")
+		require.Equal(t, singingID, id)
+		require.True(t, retired)
+		require.Equal(t, total-1, live())
+
+		// an older file never rolls the database back
+		require.NoError(t, syncCardPacks(ctx, packs))
+		_, front, _ = card("core", "whisper")
+		require.Equal(t, "in a stage whisper", front)
+
+		// a pack that disappears is retired with its cards
+		require.NoError(t, syncCardPacks(ctx, edit(3, "impressions")))
+		var packRetired bool
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT retired FROM card_packs WHERE slug = 'impressions'",
+		).Scan(&packRetired))
+		require.True(t, packRetired)
+
+		// and everything comes back with the next release
+		require.NoError(t, syncCardPacks(ctx, edit(4)))
+		require.Equal(t, total, live())
+		_, front, _ = card("core", "whisper")
+		require.Equal(t, whisper, front)
+	})
+
+	t.Run("POST /library/import", func(t *testing.T) {
+		post := func(token, contentType, body string) *http.Response {
+			req := httptest.NewRequest(http.MethodPost, "/library/import", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			importHandler(w, req)
+			return w.Result()
+		}
+		body := "front,back\nin a hushed tone,in a booming voice\nin a whisper,a little too loudly\n"
+
+		require.Equal(t, http.StatusNotFound, post("", "text/csv", body).StatusCode,
+			"import is off without an admin token",
+		)
+		adminToken = "test-admin-token"
+		defer func() { adminToken = "" }()
+		require.Equal(t, http.StatusUnauthorized, post("wrong", "text/csv", body).StatusCode)
+		require.Equal(t, http.StatusBadRequest,
+			post(adminToken, "text/csv", "front,back\nno back,\n").StatusCode,
+		)
+
+		// "in a whisper" is already in a pack
+		res := post(adminToken, "text/csv", body)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var counts map[string]int
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&counts))
+		require.Equal(t, map[string]int{"queued": 1, "duplicates": 1}, counts)
+
+		res = post(adminToken, "text/markdown",
+			"### Front text\n\nin a hushed tone\n\n### Back text\n\nin a booming voice\n",
+		)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&counts))
+		require.Equal(t, map[string]int{"queued": 0, "duplicates": 1}, counts)
+
+		var pending bool
+		var source string
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT pending, source FROM cards WHERE front = 'in a hushed tone'",
+		).Scan(&pending, &source))
+		require.True(t, pending, "imported cards wait for moderation")
+		require.Equal(t, "upload:2", source)
+
+		// the queue lists, and rejects, what's waiting
+		_, _, err := importCards(ctx, []importCard{{Type: "rule", Front: "in rhyme", Back: "in prose", Source: "test"}})
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, moderateCommand(ctx, nil, &out))
+		require.Contains(t, out.String(), "in a hushed tone")
+		require.Contains(t, out.String(), "in rhyme")
+		var rhymeID, hushedID int32
+		require.NoError(t, dbPool.QueryRow(ctx, "SELECT id FROM cards WHERE front = 'in rhyme'").Scan(&rhymeID))
+		require.NoError(t, dbPool.QueryRow(ctx, "SELECT id FROM cards WHERE front = 'in a hushed tone'").Scan(&hushedID))
+		out.Reset()
+		require.NoError(t, moderateCommand(ctx, []string{"-reject", fmt.Sprint(rhymeID)}, &out))
+		require.Contains(t, out.String(), "rejected 1 of 1")
+		_, _, err = importCards(ctx, []importCard{{Type: "rule", Front: "in rhyme", Back: "in prose", Source: "test"}})
+		require.NoError(t, err)
+		var requeued bool
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM cards WHERE front = 'in rhyme' AND pending IS TRUE)",
+		).Scan(&requeued))
+		require.True(t, requeued, "a rejected card can be suggested again")
+
+		// approving prints a pack entry; the pack that adds it adopts the row
+		out.Reset()
+		require.NoError(t, moderateCommand(ctx, []string{"-approve", fmt.Sprint(hushedID)}, &out))
+		var entries []packCard
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+		require.Len(t, entries, 1)
+		require.Equal(t, "in-a-hushed-tone", entries[0].Slug)
+		adopted := slices.Clone(packs)
+		adopted[0].Version = 5
+		adopted[0].Cards = append(slices.Clone(packs[0].Cards), entries[0])
+		require.NoError(t, syncCardPacks(ctx, adopted))
+		var pack string
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT pending, pack FROM cards WHERE id = $1", hushedID,
+		).Scan(&pending, &pack))
+		require.False(t, pending, "an adopted card leaves the queue")
+		require.Equal(t, adopted[0].Slug, pack)
+
+		// leave the packs as the games below expect them
+		adopted[0].Version = 6
+		adopted[0].Cards = packs[0].Cards
+		require.NoError(t, syncCardPacks(ctx, adopted))
 	})
 
 	// welcome screen
@@ -120,6 +301,13 @@ func TestGame(t *testing.T) {
 		dataHandler(w, req)
 		require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
 	})
+	t.Run("GET /{game_id}/stream (no cookie)", func(t *testing.T) {
+		path := fmt.Sprintf("/%s/stream", gameID)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		streamHandler(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
 	// join game
 	t.Run("POST /{game_id}/join", func(t *testing.T) {
 		for i, user := range users {
@@ -254,9 +442,136 @@ func TestGame(t *testing.T) {
 		dataHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 	})
+	t.Run("cache invalidation from a peer instance", func(t *testing.T) {
+		listenCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go cacheListener(listenCtx, pool, &cache)
+
+		_, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		_, ok := cache.Load(gameID)
+		require.True(t, ok, "state should be cached after a lookup")
+
+		// the listener subscribes asynchronously, so keep broadcasting as
+		// another instance would until the eviction lands.
+		require.Eventually(t, func() bool {
+			require.NoError(t, queries.CacheInvalidate(ctx, "peer:"+gameID))
+			_, ok := cache.Load(gameID)
+			return !ok
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+	t.Run("shared game_cache tier", func(t *testing.T) {
+		invalidate(ctx, gameID)
+		_, err := queries.CacheGet(ctx, gameID)
+		require.ErrorIs(t, err, pgx.ErrNoRows, "invalidate should clear the shared entry")
+
+		fresh, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		_, err = queries.CacheGet(ctx, gameID)
+		require.NoError(t, err, "a database fetch should fill the shared tier")
+
+		// another instance has nothing locally, but reads the shared row
+		cache.Delete(gameID)
+		shared, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		require.Equal(t, fresh.Game.ID, shared.Game.ID)
+		require.True(t, fresh.Updated.Equal(shared.Updated),
+			"state should come from the shared tier, not a refetch")
+		require.Len(t, shared.Players, len(fresh.Players))
+
+		// a fetch that straddles an invalidation mustn't store what it read
+		version := sharedCacheVersion(ctx, gameID)
+		invalidate(ctx, gameID)
+		require.False(t, storeSharedCache(ctx, gameID, version, fresh),
+			"a store from before the invalidation should be refused")
+		_, err = queries.CacheGet(ctx, gameID)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+	// a saved deck, built through the library pages; the one-player game below
+	// plays it.
+	var deckID string
+	t.Run("saved decks", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/library/decks", decksHandler)
+		mux.HandleFunc("/library/decks/{deck_id}", deckHandler)
+		mux.HandleFunc("/library/decks/{deck_id}/cards", deckCardsHandler)
+		mux.HandleFunc("/library/decks/{deck_id}/cards/{card_id}", deckCardHandler)
+		serve := func(method, target string, values url.Values, c *http.Cookie) *http.Response {
+			req := httptest.NewRequest(method, target, strings.NewReader(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if c != nil {
+				req.AddCookie(c)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w.Result()
+		}
+
+		res := serve(http.MethodPost, "/library/decks", url.Values{"name": {"house rules"}}, nil)
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		deckPage := res.Header.Get("Location")
+		deckID = strings.TrimPrefix(deckPage, "/library/decks/")
+		var key *http.Cookie
+		for _, c := range res.Cookies() {
+			if c.Name == deckCookieName {
+				key = c
+			}
+		}
+		require.NotNil(t, key, "the creator should get the deck's edit key")
+		require.True(t, key.HttpOnly)
+		require.Equal(t, http.SameSiteLaxMode, key.SameSite)
+
+		cardsPath := deckPage + "/cards"
+		res = serve(http.MethodPost, cardsPath, url.Values{
+			"type": {"prompt"}, "front": {"sing the house anthem"},
+		}, nil)
+		require.Equal(t, http.StatusForbidden, res.StatusCode, "editing needs the key")
+		for _, values := range []url.Values{
+			{"type": {"rule"}, "front": {"in the house accent"}, "back": {"in no accent"}},
+			{"type": {"prompt"}, "front": {"sing the house anthem"}},
+			{"type": {"prompt"}, "front": {"name every housemate"}},
+		} {
+			res = serve(http.MethodPost, cardsPath, values, key)
+			require.Equal(t, http.StatusSeeOther, res.StatusCode)
+			require.Equal(t, deckPage, res.Header.Get("Location"))
+		}
+		res = serve(http.MethodPost, cardsPath, url.Values{"type": {"rule"}, "front": {"no back"}}, key)
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+		require.Contains(t, res.Header.Get("Location"), "alert="+alertCardInvalid)
+
+		id, err := strconv.Atoi(deckID)
+		require.NoError(t, err)
+		plain, err := queries.DeckAuthorized(ctx, sqlc.DeckAuthorizedParams{ID: int32(id), EditKey: key.Value})
+		require.NoError(t, err)
+		require.False(t, plain, "decks should store the edit key hashed")
+		cards, err := queries.DeckCards(ctx, int32(id))
+		require.NoError(t, err)
+		require.Len(t, cards, 3)
+		removePath := fmt.Sprintf("%s/%d", cardsPath, cards[2].ID)
+		res = serve(http.MethodDelete, removePath, nil, nil)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res = serve(http.MethodDelete, removePath, nil, key)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		req := httptest.NewRequest(http.MethodGet, deckPage, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.Contains(t, w.Body.String(), "in the house accent")
+		require.NotContains(t, w.Body.String(), "name every housemate")
+		require.NotContains(t, w.Body.String(), "ADD CARD", "no edit forms without the key")
+
+		req = httptest.NewRequest(http.MethodGet, "/library/decks", nil)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.Contains(t, w.Body.String(), "house rules")
+	})
 	// a game with a single non-host player can start, but only after the host
 	// confirms. this is self-contained: its own game and players, so the shared
 	// gameID and the package-level users slice are untouched.
+	var soloGameID string
+	var solo []testuser
 	t.Run("POST /{game_id}/action/start (one player confirm)", func(t *testing.T) {
 		// fresh game
 		req := httptest.NewRequest(http.MethodPost, "/create", nil)
@@ -265,10 +580,10 @@ func TestGame(t *testing.T) {
 		require.Equal(t, http.StatusSeeOther, w.Result().StatusCode)
 		parts := strings.Split(w.Result().Header.Get("Location"), "/")
 		require.Len(t, parts, 3)
-		soloGameID := parts[1]
+		soloGameID = parts[1]
 
 		// host (first joiner) plus a single non-host player
-		solo := []testuser{{username: "Anna"}, {username: "Oscar"}}
+		solo = []testuser{{username: "Anna"}, {username: "Oscar"}}
 		for i, user := range solo {
 			values := url.Values{}
 			values.Set("username", user.username)
@@ -296,6 +611,25 @@ func TestGame(t *testing.T) {
 		startPath := fmt.Sprintf("/%s/action/start", soloGameID)
 		statusPath := fmt.Sprintf("/%s/data/status", soloGameID)
 
+		// the host seeds the wheel with the saved deck
+		deckPath := fmt.Sprintf("/%s/action/deck", soloGameID)
+		pick := url.Values{"deck_id": {deckID}}
+		req = httptest.NewRequest(http.MethodPost, deckPath, strings.NewReader(pick.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(solo[1].cookie)
+		w = httptest.NewRecorder()
+		actionHandler(w, req)
+		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		req = httptest.NewRequest(http.MethodPost, deckPath, strings.NewReader(pick.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(host.cookie)
+		w = httptest.NewRecorder()
+		actionHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		gs, err := queries.GameState(ctx, soloGameID)
+		require.NoError(t, err)
+		require.Equal(t, "house rules", gs.DeckName.String)
+
 		// first attempt: the host is asked to confirm, the game does not start
 		req = httptest.NewRequest(http.MethodPost, startPath, nil)
 		req.AddCookie(host.cookie)
@@ -314,7 +648,7 @@ func TestGame(t *testing.T) {
 			"game should not start before the host confirms",
 		)
 
-		// confirmed attempt: the game starts and play begins
+		// confirmed attempt: the game moves on to the writing phase
 		req = httptest.NewRequest(http.MethodPost, startPath+"?confirm=1", nil)
 		req.AddCookie(host.cookie)
 		w = httptest.NewRecorder()
@@ -325,9 +659,324 @@ func TestGame(t *testing.T) {
 		w = httptest.NewRecorder()
 		dataHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.Contains(t, w.Body.String(), "writing",
+			"game should open card writing once the host confirms",
+		)
+
+		// only the host can force writing closed, which starts play even
+		// though nobody has written a card
+		closePath := fmt.Sprintf("/%s/action/close", soloGameID)
+		req = httptest.NewRequest(http.MethodPost, closePath, nil)
+		req.AddCookie(solo[1].cookie)
+		w = httptest.NewRecorder()
+		actionHandler(w, req)
+		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		req = httptest.NewRequest(http.MethodPost, closePath, nil)
+		req.AddCookie(host.cookie)
+		w = httptest.NewRecorder()
+		actionHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		req = httptest.NewRequest(http.MethodGet, statusPath, nil)
+		req.AddCookie(host.cookie)
+		w = httptest.NewRecorder()
+		dataHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 		require.Contains(t, w.Body.String(), "turn",
-			"game should start once the host confirms",
+			"game should start once the host closes writing",
+		)
+
+		// the wheel holds just the deck's cards, not the generic ones
+		var dealt, fromDeck int
+		require.NoError(t, dbPool.QueryRow(ctx,
+			`SELECT COUNT(*), COUNT(*) FILTER (WHERE card_id IN (
+				SELECT card_id FROM deck_cards WHERE deck_id = $2::int
+			)) FROM game_cards WHERE game_id = $1`,
+			soloGameID, deckID,
+		).Scan(&dealt, &fromDeck))
+		require.Equal(t, 2, dealt)
+		require.Equal(t, dealt, fromDeck)
+	})
+	// the turn clock moves a stalled turn on
+	t.Run("turn clock timeout", func(t *testing.T) {
+		events := func(eventType string) int {
+			var n int
+			require.NoError(t, dbPool.QueryRow(ctx,
+				"SELECT COUNT(*) FROM event_log WHERE game_id = $1 AND event_type = $2",
+				soloGameID, eventType,
+			).Scan(&n))
+			return n
+		}
+		stall := func() {
+			_, err := dbPool.Exec(ctx,
+				"UPDATE event_log SET ts = ts - INTERVAL '1 hour' WHERE game_id = $1",
+				soloGameID,
+			)
+			require.NoError(t, err)
+		}
+
+		s, err := fetchStateFromDB(ctx, soloGameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(stateTurn), s.Game.StateID)
+		require.Positive(t, secondsLeft(s.TurnDeadline))
+		require.LessOrEqual(t, secondsLeft(s.TurnDeadline), int(s.Game.InitiativeTimer))
+
+		// a clock that hasn't run out leaves the turn alone
+		turns := events("turn")
+		turnClockTick(ctx)
+		require.Equal(t, 0, events("timeout"))
+
+		stall()
+		turnClockTick(ctx)
+		require.Equal(t, 1, events("timeout"))
+		require.Equal(t, turns+1, events("turn"), "the timeout passes the turn on")
+		s, err = fetchStateFromDB(ctx, soloGameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(stateTurn), s.Game.StateID)
+		require.Positive(t, secondsLeft(s.TurnDeadline), "the next turn gets a fresh clock")
+
+		// a timed-out turn is only timed out once
+		require.NoError(t, timeoutTurn(ctx, soloGameID))
+		require.Equal(t, 1, events("timeout"))
+
+		// a timer of 0 turns the clock off
+		_, err = dbPool.Exec(ctx, "UPDATE games SET initiative_timer = 0 WHERE id = $1", soloGameID)
+		require.NoError(t, err)
+		stall()
+		turnClockTick(ctx)
+		require.Equal(t, 1, events("timeout"))
+		s, err = fetchStateFromDB(ctx, soloGameID)
+		require.NoError(t, err)
+		require.Equal(t, -1, secondsLeft(s.TurnDeadline))
+	})
+	// the host seat changes hands: handed over, then voted back once the new
+	// host goes quiet
+	t.Run("host handoff", func(t *testing.T) {
+		act := func(c *http.Cookie, action string, playerID int32) *http.Response {
+			values := url.Values{"player_id": {strconv.Itoa(int(playerID))}}
+			req := httptest.NewRequest(http.MethodPost,
+				fmt.Sprintf("/%s/action/%s", soloGameID, action),
+				strings.NewReader(values.Encode()),
+			)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			invalidate(ctx, soloGameID)
+			actionHandler(w, req)
+			return w.Result()
+		}
+		seats := func() map[string]int32 {
+			players, err := queries.GamePlayerPoints(ctx, soloGameID)
+			require.NoError(t, err)
+			m := make(map[string]int32, len(players))
+			for _, p := range players {
+				m[p.Name] = p.Initiative.Int32
+			}
+			return m
+		}
+		ids := make(map[string]int32)
+		players, err := queries.GamePlayerPoints(ctx, soloGameID)
+		require.NoError(t, err)
+		for _, p := range players {
+			ids[p.Name] = p.PlayerID
+		}
+		require.Equal(t, map[string]int32{"Anna": 0, "Oscar": 1}, seats())
+
+		require.Equal(t, http.StatusForbidden,
+			act(solo[1].cookie, "transfer-host", ids["Oscar"]).StatusCode,
+		)
+		require.Equal(t, http.StatusConflict,
+			act(solo[1].cookie, "vote-host", ids["Oscar"]).StatusCode,
+			"no vote while the host is here",
+		)
+		require.Equal(t, http.StatusOK,
+			act(solo[0].cookie, "transfer-host", ids["Oscar"]).StatusCode,
+		)
+		require.Equal(t, map[string]int32{"Anna": 1, "Oscar": 0}, seats(),
+			"the old host takes the new host's seat",
+		)
+
+		// the new host goes quiet past host_timeout
+		_, err = dbPool.Exec(ctx, `UPDATE game_players
+			SET last_seen = LOCALTIMESTAMP - (
+				SELECT host_timeout FROM games WHERE id = $1
+			) * INTERVAL '1 second' - INTERVAL '1 second'
+			WHERE game_id = $1 AND initiative = 0`,
+			soloGameID,
+		)
+		require.NoError(t, err)
+		lapsed, err := queries.GamesHostLapsed(ctx, 5)
+		require.NoError(t, err)
+		require.Contains(t, lapsed, soloGameID)
+		s, err := fetchStateFromDB(ctx, soloGameID)
+		require.NoError(t, err)
+		require.True(t, s.Game.HostAway)
+
+		// Anna is the only non-host player, so her vote is a majority
+		require.Equal(t, http.StatusOK,
+			act(solo[0].cookie, "vote-host", ids["Anna"]).StatusCode,
+		)
+		require.Equal(t, map[string]int32{"Anna": 0, "Oscar": 1}, seats())
+		var handoffs int
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT COUNT(*) FROM event_log WHERE game_id = $1 AND event_type = 'host'",
+			soloGameID,
+		).Scan(&handoffs))
+		require.Equal(t, 2, handoffs)
+	})
+	t.Run("leave and kick", func(t *testing.T) {
+		act := func(c *http.Cookie, action string, values url.Values) *http.Response {
+			req := httptest.NewRequest(http.MethodPost,
+				fmt.Sprintf("/%s/action/%s", soloGameID, action),
+				strings.NewReader(values.Encode()),
+			)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			invalidate(ctx, soloGameID)
+			actionHandler(w, req)
+			return w.Result()
+		}
+		ids := make(map[string]int32)
+		players, err := queries.GamePlayerPoints(ctx, soloGameID)
+		require.NoError(t, err)
+		for _, p := range players {
+			ids[p.Name] = p.PlayerID
+		}
+		// Oscar holds a card off the wheel
+		var cardID int32
+		require.NoError(t, dbPool.QueryRow(ctx, `UPDATE game_cards
+			SET player_id = $2, slot = NULL, stack = NULL
+			WHERE id = (
+				SELECT id FROM game_cards
+				WHERE game_id = $1 AND slot IS NOT NULL
+				LIMIT 1
+			)
+			RETURNING id`,
+			soloGameID, ids["Oscar"],
+		).Scan(&cardID))
+
+		resp := act(solo[0].cookie, "leave", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, resp.Header.Get("HX-Trigger"), "notice", "the host can't leave")
+		require.Equal(t, http.StatusForbidden,
+			act(solo[1].cookie, "kick", url.Values{
+				"player_id": {strconv.Itoa(int(ids["Anna"]))},
+			}).StatusCode,
+		)
+		require.Equal(t, http.StatusBadRequest,
+			act(solo[0].cookie, "kick", url.Values{
+				"player_id": {strconv.Itoa(int(ids["Oscar"]))},
+				"cards":     {"burn"},
+			}).StatusCode,
+		)
+		require.Equal(t, http.StatusOK,
+			act(solo[0].cookie, "kick", url.Values{
+				"player_id": {strconv.Itoa(int(ids["Oscar"]))},
+			}).StatusCode,
+		)
+
+		s, err := fetchStateFromDB(ctx, soloGameID)
+		require.NoError(t, err)
+		require.Len(t, s.Players, 1)
+		require.Equal(t, int32(stateOver), s.Game.StateID, "nobody left to take turns")
+		var onWheel bool
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT player_id IS NULL AND slot IS NOT NULL FROM game_cards WHERE id = $1",
+			cardID,
+		).Scan(&onWheel))
+		require.True(t, onWheel, "Oscar's card went back on the wheel")
+		var kicks int
+		require.NoError(t, dbPool.QueryRow(ctx, `SELECT COUNT(*) FROM event_log
+			WHERE game_id = $1 AND event_type = 'kick' AND actor_id = $2 AND target_id = $3`,
+			soloGameID, ids["Anna"], ids["Oscar"],
+		).Scan(&kicks))
+		require.Equal(t, 1, kicks)
+	})
+	t.Run("POST /{game_id}/action/packs", func(t *testing.T) {
+		path := fmt.Sprintf("/%s/action/packs", gameID)
+		pick := func(c *http.Cookie, slugs ...string) *http.Response {
+			values := url.Values{"pack": slugs}
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			actionHandler(w, req)
+			return w.Result()
+		}
+		enabled := func() []string {
+			rows, err := queries.GamePacks(ctx, gameID)
+			require.NoError(t, err)
+			var on []string
+			for _, p := range rows {
+				if p.Enabled {
+					on = append(on, p.Slug)
+				}
+			}
+			return on
+		}
+		require.Equal(t, []string{"core", "impressions"}, enabled())
+
+		require.Equal(t, http.StatusForbidden, pick(users[1].cookie, "core").StatusCode)
+		require.Equal(t, http.StatusBadRequest, pick(users[0].cookie, "nope").StatusCode)
+		res := pick(users[0].cookie)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Contains(t, res.Header.Get("HX-Trigger"), "notice",
+			"turning every pack off should be refused",
 		)
+		require.Equal(t, []string{"core", "impressions"}, enabled())
+
+		require.Equal(t, http.StatusOK, pick(users[0].cookie, "core").StatusCode)
+		require.Equal(t, []string{"core"}, enabled())
+		// back to both, so the rest of the game deals a full wheel
+		require.Equal(t, http.StatusOK, pick(users[0].cookie, "core", "impressions").StatusCode)
+		require.Equal(t, []string{"core", "impressions"}, enabled())
+	})
+	t.Run("POST /{game_id}/action/settings", func(t *testing.T) {
+		path := fmt.Sprintf("/%s/action/settings", gameID)
+		set := func(c *http.Cookie, values url.Values) *http.Response {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			invalidate(ctx, gameID)
+			actionHandler(w, req)
+			return w.Result()
+		}
+		require.Equal(t, http.StatusForbidden,
+			set(users[1].cookie, url.Values{"starting_points": {"99"}}).StatusCode,
+		)
+		for name, values := range map[string]url.Values{
+			"not a number":        {"wheel_slots": {"ten"}},
+			"out of bounds":       {"prompt_seconds": {"5"}},
+			"slots outnumber":     {"wheel_slots": {"20"}, "card_count": {"12"}},
+			"deck can't fill":     {"card_count": {"200"}},
+			"turn clock too fast": {"initiative_timer": {"3"}},
+		} {
+			res := set(users[0].cookie, values)
+			require.Equal(t, http.StatusOK, res.StatusCode, name)
+			require.Contains(t, res.Header.Get("HX-Trigger"), "notice", name)
+		}
+		game, err := queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(30), game.CardCount, "a refused change must not save")
+
+		res := set(users[0].cookie, url.Values{
+			"starting_points": {"25"},
+			"prompt_seconds":  {"45"},
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "refreshTable", res.Header.Get("HX-Trigger"))
+		game, err = queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(25), game.StartingPoints)
+		require.Equal(t, int32(45), game.PromptSeconds)
+		require.Equal(t, int32(30), game.CardCount, "fields left out keep their value")
+		// players who already joined take the new starting points
+		players, err := queries.GamePlayerPoints(ctx, gameID)
+		require.NoError(t, err)
+		for _, p := range players {
+			require.Equal(t, int32(25), p.Points.Int32, p.Name)
+		}
 	})
 	t.Run("POST /{game_id}/action/start", func(t *testing.T) {
 		path := fmt.Sprintf("/%s/action/start", gameID)
@@ -336,6 +985,80 @@ func TestGame(t *testing.T) {
 		w := httptest.NewRecorder()
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		// a settings change that loses the race to the start saves nothing
+		game, err := queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		n, err := queries.GameSettingsSet(ctx, sqlc.GameSettingsSetParams{
+			ID:                 gameID,
+			WheelSlots:         game.WheelSlots,
+			CardCount:          game.CardCount,
+			InitiativeTimer:    game.InitiativeTimer,
+			CardsPerPlayer:     game.CardsPerPlayer,
+			StartingPoints:     99,
+			PromptSeconds:      game.PromptSeconds,
+			RecommendedPlayers: game.RecommendedPlayers,
+			HostTimeout:        game.HostTimeout,
+		})
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+	// every player writes their cards; the last one starts the game
+	t.Run("POST /{game_id}/action/write", func(t *testing.T) {
+		path := fmt.Sprintf("/%s/action/write", gameID)
+		write := func(c *http.Cookie, values url.Values) int {
+			req := httptest.NewRequest(http.MethodPost, path,
+				strings.NewReader(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			invalidate(ctx, gameID)
+			actionHandler(w, req)
+			return w.Result().StatusCode
+		}
+		require.Equal(t, http.StatusBadRequest, write(users[0].cookie, url.Values{
+			"type": {"modifier"}, "front": {"flip everything"},
+		}), "players can only write rules and prompts")
+		require.Equal(t, http.StatusBadRequest, write(users[0].cookie, url.Values{
+			"type": {"rule"}, "front": {"in a whisper"},
+		}), "a rule needs a back")
+		require.Equal(t, http.StatusBadRequest, write(users[0].cookie, url.Values{
+			"type": {"prompt"}, "front": {"  "},
+		}), "a card needs a front")
+
+		gs, err := queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(stateWriting), gs.StateID)
+		for i, user := range users {
+			for n := 0; n < int(gs.CardsPerPlayer); n++ {
+				values := url.Values{
+					"type":  {"prompt"},
+					"front": {fmt.Sprintf("%s's prompt %d", user.username, n)},
+				}
+				if n%2 == 0 {
+					values = url.Values{
+						"type":  {"rule"},
+						"front": {fmt.Sprintf("like %s", user.username)},
+						"back":  {fmt.Sprintf("unlike %s", user.username)},
+					}
+				}
+				require.Equal(t, http.StatusOK, write(user.cookie, values),
+					"%s writing card %d", user.username, n)
+			}
+			if i == 0 {
+				require.Equal(t, http.StatusConflict, write(user.cookie, url.Values{
+					"type": {"prompt"}, "front": {"one too many"},
+				}), "no more cards than owed")
+			}
+		}
+
+		// written cards join the deck on the wheel, mixed with generic fill
+		wheel, err := queries.GameCardsWheelView(ctx, gameID)
+		require.NoError(t, err)
+		require.NotEmpty(t, wheel)
+		written, err := queries.GameCardsWritten(ctx, gameID)
+		require.NoError(t, err)
+		require.Len(t, written, len(users))
 	})
 	t.Run("GET /{game_id}/qr (not accepting invites)", func(t *testing.T) {
 		mux := http.NewServeMux()
@@ -343,7 +1066,7 @@ func TestGame(t *testing.T) {
 		path := fmt.Sprintf("/%s/qr", gameID)
 		req := httptest.NewRequest(http.MethodGet, path, nil)
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		mux.ServeHTTP(w, req)
 		require.Equal(t, http.StatusConflict, w.Result().StatusCode)
 	})
@@ -357,6 +1080,460 @@ func TestGame(t *testing.T) {
 		status := w.Body.String()
 		require.Contains(t, status, "turn")
 	})
+	t.Run("GET /{game_id}/data/state (no secrets)", func(t *testing.T) {
+		path := fmt.Sprintf("/%s/data/state", gameID)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(users[1].cookie)
+		w := httptest.NewRecorder()
+		dataHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		body := w.Body.String()
+		require.NotContains(t, body, "SessionKey")
+		require.NotContains(t, body, "TopCardType")
+		for _, u := range users {
+			parts := strings.Split(u.cookie.Value, ":")
+			require.Len(t, parts, 3)
+			require.NotContains(t, body, parts[1], "%s's session key", u.username)
+			require.NotContains(t, body, hashSessionKey(parts[1]), "%s's session key hash", u.username)
+		}
+		var v view
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v))
+		require.NotEqual(t, roleHost, v.Role)
+		require.Len(t, v.Players, len(users))
+	})
+	t.Run("legacy session upgrade", func(t *testing.T) {
+		// a session from before keys were hashed and cookies signed, as the
+		// migration leaves it: hashed in sql, and marked legacy
+		parts := strings.Split(users[3].cookie.Value, ":")
+		require.Len(t, parts, 3)
+		playerID, key := parts[0], "legacy-plaintext-key"
+		_, err := dbPool.Exec(ctx,
+			`UPDATE game_players
+			 SET session_key = encode(sha256(convert_to($3::text, 'UTF8')), 'hex'), session_key_legacy = TRUE
+			 WHERE game_id = $1 AND player_id = $2`,
+			gameID, playerID, key,
+		)
+		require.NoError(t, err)
+		invalidate(ctx, gameID)
+
+		get := func(value string) *http.Response {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/status", gameID), nil)
+			req.SetPathValue("game_id", gameID)
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: value})
+			w := httptest.NewRecorder()
+			sessionMW(http.HandlerFunc(dataHandler)).ServeHTTP(w, req)
+			return w.Result()
+		}
+		// the stored hash, sent as the key, is no session
+		require.Equal(t, http.StatusForbidden, get(playerID+":"+hashSessionKey(key)).StatusCode,
+			"a leaked hash can't be replayed as a legacy cookie",
+		)
+		resp := get(playerID + ":" + key)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var upgraded *http.Cookie
+		for _, c := range resp.Cookies() {
+			if c.Name == sessionCookieName {
+				upgraded = c
+			}
+		}
+		require.NotNil(t, upgraded, "a signed cookie replaces the legacy one")
+		require.True(t, upgraded.HttpOnly)
+
+		var stored string
+		var legacy bool
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT session_key, session_key_legacy FROM game_players WHERE game_id = $1 AND player_id = $2",
+			gameID, playerID,
+		).Scan(&stored, &legacy))
+		require.Equal(t, hashSessionKey(key), stored, "the migration hashes as the server does")
+		require.False(t, legacy)
+
+		require.Equal(t, http.StatusForbidden, get(playerID+":"+key).StatusCode,
+			"the unsigned cookie is spent once upgraded",
+		)
+		require.Equal(t, http.StatusOK, get(upgraded.Value).StatusCode)
+		users[3].cookie = upgraded
+	})
+	t.Run("spectator", func(t *testing.T) {
+		join := func(spectate bool) *http.Response {
+			values := url.Values{}
+			values.Set("username", "Watcher")
+			if spectate {
+				values.Set("spectate", "1")
+			}
+			u := &url.URL{Path: fmt.Sprintf("/%s/join", gameID), RawQuery: values.Encode()}
+			req := httptest.NewRequest(http.MethodPost, u.String(), nil)
+			w := httptest.NewRecorder()
+			joinHandler(w, req)
+			return w.Result()
+		}
+		resp := join(false)
+		require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		require.Equal(t, "/?alert=in-progress", resp.Header.Get("Location"),
+			"a started game takes no new players")
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/join", gameID), nil)
+		w := httptest.NewRecorder()
+		joinHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode, "a started game can still be watched")
+		require.Contains(t, w.Body.String(), "watch game")
+		require.NotContains(t, w.Body.String(), "JOIN GAME")
+
+		resp = join(true)
+		require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		require.Equal(t, fmt.Sprintf("/%s", gameID), resp.Header.Get("Location"))
+		var watcher *http.Cookie
+		for _, c := range resp.Cookies() {
+			if c.Name == sessionCookieName {
+				watcher = c
+			}
+		}
+		require.NotNil(t, watcher, "a spectator gets a session")
+
+		get := func(topic string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/%s", gameID, topic), nil)
+			req.AddCookie(watcher)
+			w := httptest.NewRecorder()
+			dataHandler(w, req)
+			return w
+		}
+		table := get("table")
+		require.Equal(t, http.StatusOK, table.Code)
+		require.NotContains(t, table.Body.String(), "hx-post", "spectators get no action buttons")
+		players := get("players")
+		require.Equal(t, http.StatusOK, players.Code)
+		require.Contains(t, players.Body.String(), "watching: Watcher")
+		require.NotContains(t, players.Body.String(), "hx-post")
+		require.Equal(t, http.StatusOK, get("events").Code)
+		require.Equal(t, http.StatusNoContent, get("accuse").Code)
+
+		for _, action := range []string{"accuse", "spin", "acknowledge", "leave"} {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/%s/action/%s", gameID, action), nil)
+			req.AddCookie(watcher)
+			w := httptest.NewRecorder()
+			actionHandler(w, req)
+			require.Equal(t, http.StatusForbidden, w.Code, "spectators can't %s", action)
+		}
+
+		seated, err := queries.GamePlayerPoints(ctx, gameID)
+		require.NoError(t, err)
+		require.Len(t, seated, len(users), "a spectator takes no seat")
+		state, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		require.Len(t, state.Spectators, 1)
+	})
+	t.Run("api v1", func(t *testing.T) {
+		mux := http.NewServeMux()
+		registerAPI(mux, func(h http.Handler) http.Handler { return h })
+		call := func(method, path string, c *http.Cookie, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, apiPrefix+path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w
+		}
+
+		w := call(http.MethodGet, "/games/"+gameID, users[1].cookie, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var game apiGame
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+		require.Equal(t, gameID, game.ID)
+		require.NotEqual(t, roleHost, game.Role)
+		require.NotNil(t, game.TurnPlayerID)
+		require.Contains(t, game.Settings, "wheel_slots")
+
+		w = call(http.MethodGet, "/games/"+gameID+"/players", users[1].cookie, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var players apiPlayers
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &players))
+		require.Len(t, players.Players, len(users))
+		require.True(t, players.Players[0].Host)
+		require.NotContains(t, w.Body.String(), "session")
+
+		for _, resource := range []string{"hands", "wheel", "infractions"} {
+			w = call(http.MethodGet, "/games/"+gameID+"/"+resource, users[1].cookie, "")
+			require.Equal(t, http.StatusOK, w.Code, resource)
+		}
+
+		w = call(http.MethodGet, "/games/"+gameID+"/events?limit=1", users[1].cookie, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var page apiEvents
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Events, 1)
+		require.True(t, page.More)
+		w = call(http.MethodGet, fmt.Sprintf("/games/%s/events?since=%d", gameID, page.NextSince), users[1].cookie, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var rest apiEvents
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rest))
+		require.NotEmpty(t, rest.Events)
+		require.Greater(t, rest.Events[0].ID, page.NextSince)
+
+		// a refused action answers with the same error body as any other
+		w = call(http.MethodPost, "/games/"+gameID+"/actions/advance", users[1].cookie, "{}")
+		require.Equal(t, http.StatusForbidden, w.Code)
+		var apiErr apiError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		require.Equal(t, "forbidden", apiErr.Error.Code)
+
+		w = call(http.MethodGet, "/games/zzzzzz", users[1].cookie, "")
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Contains(t, w.Body.String(), `"code":"not_found"`)
+	})
+	t.Run("bearer tokens", func(t *testing.T) {
+		mux := http.NewServeMux()
+		registerAPI(mux, tokenMW)
+		call := func(method, path string, c *http.Cookie, token, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, apiPrefix+path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if c != nil {
+				req.AddCookie(c)
+			}
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w
+		}
+		playerID, _, _ := strings.Cut(users[1].cookie.Value, ":")
+
+		// minted from the page's session, a token stands in for it
+		w := call(http.MethodPost, "/games/"+gameID+"/actions/token", users[1].cookie, "", "{}")
+		require.Equal(t, http.StatusOK, w.Code)
+		var result struct {
+			Signals struct {
+				APIToken mintedToken `json:"apiToken"`
+			} `json:"signals"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		token := result.Signals.APIToken.Token
+		require.True(t, strings.HasPrefix(token, tokenPrefix))
+
+		w = call(http.MethodGet, "/games/"+gameID, nil, token, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var game apiGame
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+		require.Equal(t, playerID, fmt.Sprint(game.CallerID))
+
+		// the page's data and action routes take it too, without a csrf token
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/state", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		tokenMW(http.HandlerFunc(dataHandler)).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/%s/action/advance", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec = httptest.NewRecorder()
+		csrfMW(tokenMW(http.HandlerFunc(actionHandler))).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code, "the token's player isn't host")
+		require.Contains(t, rec.Body.String(), "host")
+
+		// only the host may revoke another player's tokens
+		w = call(http.MethodPost, "/games/"+gameID+"/actions/revoke-tokens", users[2].cookie, "",
+			fmt.Sprintf(`{"player_id":%s}`, playerID))
+		require.Equal(t, http.StatusForbidden, w.Code)
+		w = call(http.MethodPost, "/games/"+gameID+"/actions/revoke-tokens", users[0].cookie, "",
+			fmt.Sprintf(`{"player_id":%s}`, playerID))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"tokensRevoked":1`)
+
+		w = call(http.MethodGet, "/games/"+gameID, nil, token, "")
+		require.Equal(t, http.StatusUnauthorized, w.Code, "a revoked token stands for no one")
+		require.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		w = call(http.MethodGet, "/games/"+gameID, nil, tokenPrefix+"made-up", "")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// joining by the api answers with a token
+		w = call(http.MethodPost, "/games/"+gameID+"/join", nil, "", `{"username":"Bot","spectate":true}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var joined apiJoined
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &joined))
+		require.True(t, joined.Spectator)
+		w = call(http.MethodGet, "/games/"+gameID, nil, joined.Token, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+		require.Equal(t, roleSpectator, game.Role)
+		require.Equal(t, joined.PlayerID, game.CallerID)
+
+		w = call(http.MethodPost, "/games/"+gameID+"/join", nil, "", `{"username":"Bot"}`)
+		require.Equal(t, http.StatusConflict, w.Code, "a started game takes no new players")
+		w = call(http.MethodPost, "/games/zzzzzz/join", nil, "", `{"username":"Bot"}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		// a spectator's tokens are theirs to revoke
+		w = call(http.MethodPost, "/games/"+gameID+"/actions/revoke-tokens", nil, joined.Token, "{}")
+		require.Equal(t, http.StatusOK, w.Code)
+		w = call(http.MethodGet, "/games/"+gameID, nil, joined.Token, "")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("client", func(t *testing.T) {
+		mux := http.NewServeMux()
+		registerAPI(mux, tokenMW)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		host := client.New(server.URL, nil)
+		id, err := host.Create(ctx)
+		require.NoError(t, err)
+		require.NoError(t, host.Join(ctx, id, "Host"))
+		players := make(map[int32]*client.Client)
+		for _, name := range []string{"Ada", "Bo"} {
+			c := client.New(server.URL, nil)
+			require.NoError(t, c.Join(ctx, id, name))
+			players[c.PlayerID()] = c
+		}
+		for _, c := range players {
+			require.ErrorIs(t, c.Start(ctx, true), client.ErrForbidden, "only the host starts")
+			break
+		}
+
+		none := int32(0)
+		require.NoError(t, host.Settings(ctx, client.Settings{CardsPerPlayer: &none}))
+		require.NoError(t, host.Start(ctx, true))
+		game, err := host.Game(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int32(client.StateTurn), game.StateID)
+		require.Equal(t, client.RoleHost, game.Role)
+		require.NotNil(t, game.TurnPlayerID)
+
+		turn := players[*game.TurnPlayerID]
+		require.NotNil(t, turn)
+		for pid, c := range players {
+			if pid != *game.TurnPlayerID {
+				_, err := c.Spin(ctx)
+				require.Error(t, err, "only the player whose turn it is spins")
+			}
+		}
+		spin, err := turn.Spin(ctx)
+		require.NoError(t, err)
+		require.True(t, spin.Rule != "" || spin.Prompt != "" || spin.Modifier, "%+v", spin)
+
+		events, err := turn.Events(ctx, 0, 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, events.Events)
+
+		require.NoError(t, host.End(ctx))
+		_, err = turn.Spin(ctx)
+		require.ErrorIs(t, err, client.ErrGone)
+	})
+	t.Run("bots", func(t *testing.T) {
+		mux := http.NewServeMux()
+		registerAPI(mux, tokenMW)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		host := client.New(server.URL, nil)
+		id, err := host.Create(ctx)
+		require.NoError(t, err)
+		require.NoError(t, host.Join(ctx, id, "Host"))
+		watcher := client.New(server.URL, nil)
+		require.NoError(t, watcher.Watch(ctx, id, "Bot 2"))
+		require.ErrorIs(t, watcher.AddBot(ctx), client.ErrForbidden)
+		require.NoError(t, host.AddBot(ctx))
+		require.NoError(t, host.AddBot(ctx))
+
+		seated, err := host.Players(ctx)
+		require.NoError(t, err)
+		require.Len(t, seated.Players, 3)
+		bots := make(map[string]int32)
+		for _, p := range seated.Players {
+			require.Equal(t, !p.Host, p.Bot, p.Name)
+			if p.Bot {
+				bots[p.Name] = p.ID
+			}
+		}
+		require.Contains(t, bots, "Bot 1")
+		require.Contains(t, bots, "Bot 3", "a spectator's name is taken")
+		require.ErrorIs(t, host.TransferHost(ctx, bots["Bot 1"]), client.ErrRefused)
+
+		none := int32(0)
+		require.NoError(t, host.Settings(ctx, client.Settings{CardsPerPlayer: &none}))
+		require.NoError(t, host.Start(ctx, true))
+
+		// the bots play; the host only rules on their prompts
+		spun := make(map[string]bool)
+		for i := 0; i < 60 && len(spun) < len(bots); i++ {
+			game, err := host.Game(ctx)
+			require.NoError(t, err)
+			switch game.StateID {
+			case client.StateTurn, client.StatePending:
+				botClockTick(ctx)
+			case client.StatePrompt:
+				require.NoError(t, host.Succeed(ctx))
+			default:
+				t.Fatalf("unexpected state %s", game.State)
+			}
+			events, err := host.Events(ctx, 0, 100)
+			require.NoError(t, err)
+			for _, e := range events.Events {
+				if e.Type == "spin" {
+					spun[e.Actor] = true
+				}
+			}
+		}
+		require.Len(t, spun, len(bots), "every bot took a turn")
+		require.NoError(t, host.End(ctx))
+	})
+	t.Run("simulate", func(t *testing.T) {
+		var gamesBefore, playersBefore int
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT (SELECT count(*) FROM games), (SELECT count(*) FROM players)",
+		).Scan(&gamesBefore, &playersBefore))
+		opts := simOptions{
+			Games:    3,
+			Seed:     7,
+			Seats:    3,
+			Policies: []string{"accuser", "bot"},
+			Host:     "coin",
+			MaxSpins: 60,
+		}
+		rep, err := simulate(ctx, opts)
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Games)
+		require.Equal(t, rep.Games, rep.Exhausted+rep.Capped, "every game ends on an empty slot or the cap")
+		require.Positive(t, rep.Spins.Min)
+		require.LessOrEqual(t, rep.Spins.Max, 60)
+		require.Len(t, rep.Seats, 3)
+		require.Equal(t, []string{"accuser", "bot", "accuser"}, []string{rep.Seats[0].Policy, rep.Seats[1].Policy, rep.Seats[2].Policy})
+		var wins float64
+		for _, s := range rep.Seats {
+			wins += s.Wins
+		}
+		require.InDelta(t, 3, wins, 1e-9, "each game has a win to share")
+		var spent int
+		for _, n := range rep.ModifiersSpent {
+			spent += n
+		}
+		require.LessOrEqual(t, spent, rep.ModifiersDrawn)
+
+		// the same seed plays the same games
+		again, err := simulate(ctx, opts)
+		require.NoError(t, err)
+		require.Equal(t, rep, again)
+
+		// the games were played in a schema since dropped, the real tables
+		// are untouched, and the pool and broadcasts are back
+		var schemas, gamesAfter, playersAfter int
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT count(*) FROM information_schema.schemata WHERE schema_name LIKE 'rulette_sim_%'",
+		).Scan(&schemas))
+		require.Zero(t, schemas)
+		require.NoError(t, dbPool.QueryRow(ctx,
+			"SELECT (SELECT count(*) FROM games), (SELECT count(*) FROM players)",
+		).Scan(&gamesAfter, &playersAfter))
+		require.Equal(t, gamesBefore, gamesAfter)
+		require.Equal(t, playersBefore, playersAfter)
+		require.True(t, cacheBroadcast)
+		_, err = queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+	})
 	// build initiative-ordered player list
 	players, err := queries.GamePlayerPoints(ctx, gameID)
 	require.NoError(t, err)
@@ -365,7 +1542,7 @@ func TestGame(t *testing.T) {
 		for _, u := range users {
 			if u.cookie != nil && u.cookie.Value != "" {
 				parts := strings.Split(u.cookie.Value, ":")
-				if len(parts) == 2 && parts[0] == fmt.Sprintf("%d", p.PlayerID) {
+				if len(parts) == 3 && parts[0] == fmt.Sprintf("%d", p.PlayerID) {
 					cookieByInitiative[p.Initiative.Int32] = u.cookie
 				}
 			}
@@ -394,7 +1571,7 @@ func TestGame(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, path, nil)
 			req.AddCookie(c)
 			w := httptest.NewRecorder()
-			cache.Delete(gameID)
+			invalidate(ctx, gameID)
 			actionHandler(w, req)
 
 			require.Equal(t, http.StatusOK, w.Result().StatusCode,
@@ -403,7 +1580,7 @@ func TestGame(t *testing.T) {
 
 			// a spent deck moves the game to the "ending" state (7) instead of
 			// ending outright; the host ends it explicitly.
-			cache.Delete(gameID)
+			invalidate(ctx, gameID)
 			gs, err := queries.GameState(ctx, gameID)
 			require.NoError(t, err)
 			if gs.StateID == stateEnding {
@@ -476,7 +1653,7 @@ func TestGame(t *testing.T) {
 					modReq := httptest.NewRequest(http.MethodPost, actionPath, nil)
 					modReq.AddCookie(c)
 					modW := httptest.NewRecorder()
-					cache.Delete(gameID)
+					invalidate(ctx, gameID)
 					actionHandler(modW, modReq)
 					require.Equal(t, http.StatusOK, modW.Result().StatusCode,
 						"spin %d: %s action failed", i, effect,
@@ -525,7 +1702,7 @@ func TestGame(t *testing.T) {
 					fmt.Sprintf("/%s/action/fail", gameID), nil)
 				failReq.AddCookie(cookieByInitiative[0]) // host
 				failW := httptest.NewRecorder()
-				cache.Delete(gameID)
+				invalidate(ctx, gameID)
 				actionHandler(failW, failReq)
 				require.Equal(t, http.StatusTooEarly, failW.Result().StatusCode,
 					"spin %d: failing a fresh prompt should be too early", i)
@@ -536,12 +1713,12 @@ func TestGame(t *testing.T) {
 					fmt.Sprintf("/%s/action/succeed", gameID), nil)
 				doneReq.AddCookie(cookieByInitiative[0]) // host
 				doneW := httptest.NewRecorder()
-				cache.Delete(gameID)
+				invalidate(ctx, gameID)
 				actionHandler(doneW, doneReq)
 				require.Equal(t, http.StatusOK, doneW.Result().StatusCode,
 					"spin %d prompt succeed failed", i)
 
-				cache.Delete(gameID)
+				invalidate(ctx, gameID)
 				pts, err = queries.GamePlayerPoints(ctx, gameID)
 				require.NoError(t, err)
 				var after int32
@@ -569,7 +1746,7 @@ func TestGame(t *testing.T) {
 					fmt.Sprintf("/%s/action/acknowledge", gameID), nil)
 				ackReq.AddCookie(c)
 				ackW := httptest.NewRecorder()
-				cache.Delete(gameID)
+				invalidate(ctx, gameID)
 				actionHandler(ackW, ackReq)
 				require.Equal(t, http.StatusOK, ackW.Result().StatusCode,
 					"spin %d acknowledge failed", i)
@@ -585,11 +1762,11 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateOver), gs.StateID, "expected game over")
@@ -604,18 +1781,18 @@ func TestGame(t *testing.T) {
 		},
 	})
 	require.NoError(t, err)
-	cache.Delete(gameID)
+	invalidate(ctx, gameID)
 
 	t.Run("POST /{game_id}/action/continue (host continues)", func(t *testing.T) {
 		path := fmt.Sprintf("/%s/action/continue", gameID)
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateTurn), gs.StateID, "expected game back to turn")
@@ -647,14 +1824,14 @@ func TestGame(t *testing.T) {
 		 SELECT $1, $2, 1, c.id FROM cards c WHERE c.type = 'rule' LIMIT 1`,
 		gameID, advancePlayerID)
 	require.NoError(t, err)
-	cache.Delete(gameID)
+	invalidate(ctx, gameID)
 
 	t.Run("POST /{game_id}/action/advance (non-host rejected)", func(t *testing.T) {
 		path := fmt.Sprintf("/%s/action/advance", gameID)
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[1]) // not the host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
 	})
@@ -668,16 +1845,24 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gsAfter, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateTurn), gsAfter.StateID)
 		require.NotEqual(t, initBefore, gsAfter.InitiativeCurrent.Int32,
 			"initiative should advance after host advance")
+
+		// the same turn ended again, from state read before, advances nothing
+		advanced, err := advanceTurnFrom(ctx, log, queries, gameID, initBefore)
+		require.NoError(t, err)
+		require.False(t, advanced)
+		gsAgain, err := queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, gsAfter.InitiativeCurrent.Int32, gsAgain.InitiativeCurrent.Int32)
 	})
 
 	t.Run("POST /{game_id}/action/advance (no pending ack rejected)", func(t *testing.T) {
@@ -685,7 +1870,7 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusConflict, w.Result().StatusCode)
 	})
@@ -699,7 +1884,7 @@ func TestGame(t *testing.T) {
 		},
 	})
 	require.NoError(t, err)
-	cache.Delete(gameID)
+	invalidate(ctx, gameID)
 
 	// deterministically give a non-host player a rule card to accuse on.
 	// relying on the random spin loop to deal one is flaky: a run where no
@@ -717,7 +1902,7 @@ func TestGame(t *testing.T) {
 		 SELECT $1, c.id, $2, NULL FROM cards c WHERE c.type = 'rule' LIMIT 1`,
 		gameID, ruleHolderID)
 	require.NoError(t, err)
-	cache.Delete(gameID)
+	invalidate(ctx, gameID)
 
 	// find a rule card held by a non-host player to accuse on
 	allCards, err := queries.GameCardsPlayerView(ctx, gameID)
@@ -761,12 +1946,12 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(accuserCookie)
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
 		// verify game entered challenge state
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateChallenge), gs.StateID, "expected challenge state")
@@ -780,7 +1965,7 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(accuserCookie) // not the host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
 	})
@@ -794,12 +1979,12 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
 		// verify game returned to turn state
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateTurn), gs.StateID, "expected turn state")
@@ -826,7 +2011,7 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(accuserCookie)
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 	})
@@ -839,12 +2024,12 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
 		// verify game returned to turn state
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateTurn), gs.StateID)
@@ -909,7 +2094,7 @@ func TestGame(t *testing.T) {
 		StateID:           statePending,
 		InitiativeCurrent: pgtype.Int4{Int32: 1, Valid: true},
 	}))
-	cache.Delete(gameID)
+	invalidate(ctx, gameID)
 
 	t.Run("POST /{game_id}/action/transfer (423 during challenge)", func(t *testing.T) {
 		// a non-host accuses the turn player, interrupting the pending modifier
@@ -920,10 +2105,10 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, accusePath, nil)
 		req.AddCookie(modAccuserCookie)
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(stateChallenge), gs.StateID, "accuse should enter challenge")
@@ -936,13 +2121,13 @@ func TestGame(t *testing.T) {
 		req = httptest.NewRequest(http.MethodPost, xferPath, nil)
 		req.AddCookie(turnCookie)
 		w = httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusLocked, w.Result().StatusCode,
 			"transfer during challenge should defer with 423")
 
 		// card stays with the turn player
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		cards, err := queries.GameCardsPlayerView(ctx, gameID)
 		require.NoError(t, err)
 		for _, c := range cards {
@@ -965,12 +2150,12 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, decidePath, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode)
 
 		// the turn player still holds the modifier, so pending is restored
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		gs, err := queries.GameState(ctx, gameID)
 		require.NoError(t, err)
 		require.Equal(t, int32(statePending), gs.StateID,
@@ -984,13 +2169,13 @@ func TestGame(t *testing.T) {
 		req = httptest.NewRequest(http.MethodPost, xferPath, nil)
 		req.AddCookie(turnCookie)
 		w = httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusOK, w.Result().StatusCode,
 			"retried transfer should succeed once pending is restored")
 
 		// rule card moved to target; modifier card shredded (gone from the view)
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
 		cards, err := queries.GameCardsPlayerView(ctx, gameID)
 		require.NoError(t, err)
 		var moved, modifierGone bool
@@ -1009,6 +2194,94 @@ func TestGame(t *testing.T) {
 		require.True(t, modifierGone, "used modifier card should be shredded")
 	})
 
+	t.Run("POST /{game_id}/action/undo", func(t *testing.T) {
+		undo := func(c *http.Cookie) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/%s/action/undo", gameID), nil)
+			req.AddCookie(c)
+			w := httptest.NewRecorder()
+			invalidate(ctx, gameID)
+			actionHandler(w, req)
+			return w
+		}
+		decide := func(verdict string, amount int32) {
+			var infID int32
+			require.NoError(t, dbPool.QueryRow(ctx,
+				`SELECT id FROM infractions WHERE game_id = $1 AND active = true`,
+				gameID).Scan(&infID))
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf(
+				"/%s/action/decide?infraction_id=%d&verdict=%s&amount=%d",
+				gameID, infID, verdict, amount), nil)
+			req.AddCookie(cookieByInitiative[0]) // host
+			w := httptest.NewRecorder()
+			invalidate(ctx, gameID)
+			actionHandler(w, req)
+			require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		}
+		points := func() int32 {
+			players, err := queries.GamePlayerPoints(ctx, gameID)
+			require.NoError(t, err)
+			for _, p := range players {
+				if p.PlayerID == turnPlayerID {
+					return p.Points.Int32
+				}
+			}
+			t.Fatal("turn player left")
+			return 0
+		}
+		undone := func() string {
+			var undoneType string
+			require.NoError(t, dbPool.QueryRow(ctx,
+				`SELECT undone.event_type FROM event_log e
+				 JOIN event_log undone ON undone.id = e.undo_of
+				 WHERE e.game_id = $1 AND e.event_type = 'undo'
+				 ORDER BY e.id DESC LIMIT 1`, gameID).Scan(&undoneType))
+			return undoneType
+		}
+
+		require.Equal(t, http.StatusForbidden, undo(modAccuserCookie).Code, "only the host undoes")
+
+		// the transfer goes first: the card is back with the turn player
+		require.Equal(t, http.StatusOK, undo(cookieByInitiative[0]).Code)
+		require.Equal(t, "transfer", undone())
+		cards, err := queries.GameCardsPlayerView(ctx, gameID)
+		require.NoError(t, err)
+		for _, c := range cards {
+			if c.ID == ruleGCID {
+				require.Equal(t, turnPlayerID, c.PlayerID.Int32, "the card went back")
+			}
+		}
+		// a move from someone no longer holding the card changes nothing
+		moved, err := queries.GameCardMove(ctx, sqlc.GameCardMoveParams{
+			ID:       ruleGCID,
+			GameID:   gameID,
+			PlayerID: pgInt(turnPlayerID),
+			Holder:   pgInt(-1),
+		})
+		require.NoError(t, err)
+		require.Zero(t, moved)
+
+		// then the verdict before it, reopening the challenge
+		require.Equal(t, http.StatusOK, undo(cookieByInitiative[0]).Code)
+		require.Equal(t, "decide", undone())
+		gs, err := queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(stateChallenge), gs.StateID, "the challenge is open again")
+
+		// decided wrongly, then taken back: the points come back
+		before := points()
+		decide("affirm", 3)
+		require.Equal(t, before-3, points())
+		require.Equal(t, http.StatusOK, undo(cookieByInitiative[0]).Code)
+		require.Equal(t, before, points(), "the penalty is refunded")
+
+		// and decided rightly
+		decide("absolve", 0)
+		invalidate(ctx, gameID)
+		gs, err = queries.GameState(ctx, gameID)
+		require.NoError(t, err)
+		require.NotEqual(t, int32(stateChallenge), gs.StateID)
+	})
+
 	t.Run("POST /{game_id}/action/end", func(t *testing.T) {
 		// ensure game is in a playable state first
 		err := queries.GameUpdate(ctx, sqlc.GameUpdateParams{
@@ -1023,8 +2296,206 @@ func TestGame(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, path, nil)
 		req.AddCookie(cookieByInitiative[0]) // host
 		w := httptest.NewRecorder()
-		cache.Delete(gameID)
+		invalidate(ctx, gameID)
+		actionHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.Equal(t, "refreshTable", w.Result().Header.Get("HX-Trigger"))
+
+		// a game already over says so
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+		req.AddCookie(cookieByInitiative[0])
+		w = httptest.NewRecorder()
+		invalidate(ctx, gameID)
 		actionHandler(w, req)
 		require.Equal(t, http.StatusGone, w.Result().StatusCode)
 	})
+	t.Run("GET /{game_id}/replay", func(t *testing.T) {
+		invalidate(ctx, gameID)
+		s, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		events, err := queries.ReplayEvents(ctx, gameID)
+		require.NoError(t, err)
+		cards, err := queries.ReplayCards(ctx, gameID)
+		require.NoError(t, err)
+
+		// replayed to the end, the table is the table as it stands
+		final := newReplayer(events, cards).frame(&s, len(events))
+		require.True(t, final.Started)
+		held, err := queries.GameCardsPlayerView(ctx, gameID)
+		require.NoError(t, err)
+		for _, p := range s.Players {
+			var replayed, want []int32
+			for _, fp := range final.Players {
+				if fp.PlayerID != p.PlayerID {
+					continue
+				}
+				require.Equal(t, p.Points.Int32, fp.Points, p.Name)
+				require.Equal(t, p.Initiative.Int32 == 0, fp.Host, p.Name)
+				for _, c := range fp.Cards {
+					replayed = append(replayed, c.ID)
+				}
+			}
+			for _, c := range held {
+				if c.PlayerID.Int32 == p.PlayerID && c.Type == "rule" {
+					want = append(want, c.ID)
+				}
+			}
+			require.ElementsMatch(t, want, replayed, p.Name)
+		}
+		require.Empty(t, newReplayer(events, cards).frame(&s, 0).Wheel)
+
+		for _, step := range []string{"", "?step=0", "?step=3", "?step=-1", "?step=x"} {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/replay%s", gameID, step), nil)
+			req.SetPathValue("game_id", gameID)
+			req.AddCookie(cookieByInitiative[1])
+			w := httptest.NewRecorder()
+			replayHandler(w, req)
+			require.Equal(t, http.StatusOK, w.Code, step)
+			require.Contains(t, w.Body.String(), "replay-frame", step)
+		}
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/replay", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		w := httptest.NewRecorder()
+		replayHandler(w, req)
+		require.Equal(t, http.StatusSeeOther, w.Code, "no session, no replay")
+	})
+	t.Run("GET /{game_id}/export", func(t *testing.T) {
+		invalidate(ctx, gameID)
+		s, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		export := func(format string, c *http.Cookie) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/export?format=%s", gameID, format), nil)
+			req.SetPathValue("game_id", gameID)
+			if c != nil {
+				req.AddCookie(c)
+			}
+			w := httptest.NewRecorder()
+			exportHandler(w, req)
+			return w
+		}
+
+		w := export("json", cookieByInitiative[1])
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.Contains(t, w.Header().Get("Content-Disposition"), "rulette-"+gameID+".json")
+		var got transcript
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		require.Len(t, got.Players, len(s.Players))
+		for _, p := range got.Players {
+			for _, sp := range s.Players {
+				if sp.Name == p.Name {
+					require.Equal(t, sp.Points.Int32, p.Points, p.Name)
+				}
+			}
+		}
+		// every player's points are their start plus their ledger
+		net := make(map[string]int32)
+		for _, pc := range got.Points {
+			net[pc.Player] += pc.Delta
+		}
+		for _, p := range got.Players {
+			require.Equal(t, got.StartingPoints+net[p.Name], p.Points, p.Name)
+		}
+		events, err := queries.EventListSince(ctx, sqlc.EventListSinceParams{GameID: gameID})
+		require.NoError(t, err)
+		require.Len(t, got.Events, len(events))
+		require.Equal(t, "game started", got.Events[0].Text)
+		require.Equal(t, "game over", got.Events[len(got.Events)-1].Text)
+
+		w = export("md", cookieByInitiative[1])
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "# rulette game "+gameID)
+		w = export("csv", cookieByInitiative[1])
+		require.Equal(t, http.StatusOK, w.Code)
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, csvHeader, rows[0])
+
+		require.Equal(t, http.StatusBadRequest, export("pdf", cookieByInitiative[1]).Code)
+		require.Equal(t, http.StatusUnauthorized, export("json", nil).Code, "no session, no export")
+	})
+	t.Run("GET /api/v1/games/{game_id}/summary", func(t *testing.T) {
+		invalidate(ctx, gameID)
+		s, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/games/%s/summary", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.AddCookie(cookieByInitiative[1])
+		w := httptest.NewRecorder()
+		apiSummaryHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var sum gameSummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sum))
+		require.True(t, sum.Final)
+
+		// the tallies agree with the tables they're counted from
+		require.Equal(t, len(s.Infractions), sum.Accusations)
+		var affirmed int
+		for _, inf := range s.Infractions {
+			if !inf.Active.Bool && inf.Affirmed.Bool {
+				affirmed++
+			}
+		}
+		require.Equal(t, affirmed, sum.Affirmed)
+		var spins int
+		for _, p := range sum.Players {
+			spins += p.Spins
+			for _, sp := range s.Players {
+				if sp.PlayerID == p.PlayerID {
+					require.True(t, p.Seated, p.Name)
+					require.Equal(t, sp.Points.Int32, p.Points, p.Name)
+				}
+			}
+		}
+		require.Equal(t, sum.Spins, spins)
+		for _, a := range sum.Awards {
+			require.NotEmpty(t, a.Players, a.Key)
+			require.Positive(t, a.Value, a.Key)
+		}
+
+		// and the game-over screen shows them
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/players", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.SetPathValue("topic", "players")
+		req.AddCookie(cookieByInitiative[1])
+		w = httptest.NewRecorder()
+		dataHandler(w, req)
+		require.Contains(t, w.Body.String(), "game-over-totals")
+		for _, a := range sum.Awards {
+			require.Contains(t, w.Body.String(), a.Title)
+		}
+	})
+	t.Run("points chart", func(t *testing.T) {
+		invalidate(ctx, gameID)
+		s, err := stateFromCacheOrDB(ctx, &cache, gameID)
+		require.NoError(t, err)
+		v := s.viewFor("")
+		chart, err := pointsChartFor(ctx, v)
+		require.NoError(t, err)
+		require.Positive(t, chart.Steps, "points changed over the game")
+		// each line ends at the player's balance
+		for _, line := range chart.Lines {
+			for _, p := range s.Players {
+				if p.Name == line.Name {
+					require.Equal(t, p.Points.Int32, line.Final, p.Name)
+				}
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/players", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.SetPathValue("topic", "players")
+		req.AddCookie(cookieByInitiative[1])
+		w := httptest.NewRecorder()
+		dataHandler(w, req)
+		require.Contains(t, w.Body.String(), `class="points-chart"`, "the game-over screen draws it")
+
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/data/points", gameID), nil)
+		req.SetPathValue("game_id", gameID)
+		req.SetPathValue("topic", "points")
+		req.AddCookie(cookieByInitiative[1])
+		w = httptest.NewRecorder()
+		dataHandler(w, req)
+		require.Equal(t, 286, w.Code, "the points fragment stops polling once the game is over")
+	})
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// sessionSecret signs session cookies. The server sets it from
+// RULETTE_SESSION_SECRET, and won't start without it; the random default is
+// for the commands and tests, whose cookies never outlive the process.
+var sessionSecret = newSessionSecret()
+
+func newSessionSecret() []byte {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("generate session secret: %v", err))
+	}
+	return b
+}
+
+// hashSessionKey returns the form game_players stores a session key in, so a
+// leaked table holds no usable sessions.
+func hashSessionKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// sameSession reports whether stored, a game_players session key, is
+// keyHash, in constant time.
+func sameSession(stored pgtype.Text, keyHash string) bool {
+	return stored.Valid &&
+		subtle.ConstantTimeCompare([]byte(stored.String), []byte(keyHash)) == 1
+}
+
+// signSession returns the signature a session cookie carries for its
+// {player_id}:{session_key} payload; csrf tokens are signed the same way.
+func signSession(payload string) string {
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionCookie returns the signed session cookie for playerID in gameID,
+// kept from scripts (HttpOnly), plain http when r came over https (Secure)
+// and cross-site posts (SameSite).
+//
+// Cookie format is: {player_id}:{session_key}:{signature}
+func sessionCookie(r *http.Request, gameID string, playerID int32, key string) *http.Cookie {
+	payload := fmt.Sprintf("%d:%s", playerID, key)
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    payload + ":" + signSession(payload),
+		Path:     fmt.Sprintf("/%s", gameID),
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// sessionMW upgrades a session from before keys were stored hashed and
+// cookies signed: an unsigned {player_id}:{session_key} cookie whose key
+// hashes to a legacy row's (the migration hashed those once) spends that
+// row's legacy mark, and the request carries on with, and the response sets,
+// a signed cookie. The stored hash itself, sent as a key, hashes to nothing
+// stored. Any other cookie passes through for the handler to judge.
+func sessionMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(sessionCookieName)
+		if err != nil || strings.Count(c.Value, ":") != 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, key, _ := strings.Cut(c.Value, ":")
+		playerID, err := strconv.ParseInt(id, 10, 32)
+		if err != nil || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gameID := r.PathValue("game_id")
+		n, err := queries.GamePlayerSessionUpgrade(r.Context(), sqlc.GamePlayerSessionUpgradeParams{
+			GameID:     gameID,
+			PlayerID:   int32(playerID),
+			SessionKey: pgtype.Text{String: hashSessionKey(key), Valid: true},
+		})
+		if err != nil {
+			log.Error("upgrade session", "error", err, "game_id", gameID)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if n == 1 {
+			upgraded := sessionCookie(r, gameID, int32(playerID), key)
+			http.SetCookie(w, upgraded)
+			cookies := r.Cookies()
+			r.Header.Del("Cookie")
+			for _, c := range cookies {
+				if c.Name == sessionCookieName {
+					c = upgraded
+				}
+				r.AddCookie(c)
+			}
+			log.Info("session upgraded", "game_id", gameID, "player_id", playerID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
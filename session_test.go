@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionCookie(t *testing.T) {
+	initLogger(nil)
+	c := sessionCookie(httptest.NewRequest(http.MethodGet, "https://example.com/abc123/join", nil), "abc123", 7, "s3cret")
+	require.True(t, c.HttpOnly)
+	require.True(t, c.Secure)
+	dev := sessionCookie(httptest.NewRequest(http.MethodGet, "http://localhost:7777/abc123/join", nil), "abc123", 7, "s3cret")
+	require.False(t, dev.Secure, "a plain http dev server must get its cookie back")
+	require.Equal(t, http.SameSiteLaxMode, c.SameSite)
+	require.Equal(t, "/abc123", c.Path)
+
+	read := func(value string) (string, string, error) {
+		r := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: value})
+		return cookie(r)
+	}
+	id, keyHash, err := read(c.Value)
+	require.NoError(t, err)
+	require.Equal(t, "7", id)
+	require.Equal(t, hashSessionKey("s3cret"), keyHash)
+	require.NotContains(t, keyHash, "s3cret")
+
+	forged := strings.Replace(c.Value, "7:", "8:", 1)
+	tests := map[string]string{
+		"unsigned":       "7:s3cret",
+		"forged player":  forged,
+		"bad signature":  "7:s3cret:AAAA",
+		"extra segments": c.Value + ":x",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := read(value)
+			require.ErrorIs(t, err, ErrCookieInvalid)
+		})
+	}
+}
+
+func TestSameSession(t *testing.T) {
+	stored := pgtype.Text{String: hashSessionKey("s3cret"), Valid: true}
+	require.True(t, sameSession(stored, hashSessionKey("s3cret")))
+	require.False(t, sameSession(stored, hashSessionKey("other")))
+	require.False(t, sameSession(stored, "s3cret"), "the plaintext key isn't its hash")
+	require.False(t, sameSession(pgtype.Text{}, ""), "no key matches nothing")
+}
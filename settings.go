@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+)
+
+// minTurnSeconds is the shortest turn timer, short of turning it off (0).
+const minTurnSeconds = 10
+
+// gameSetting is one host setting: its form field and label, its inclusive
+// bounds, and where it lives in the saved settings.
+type gameSetting struct {
+	Field string
+	Label string
+	Min   int32
+	Max   int32
+	value func(*sqlc.GameSettingsSetParams) *int32
+}
+
+// gameSettings are the settings a host may change before the start, in the
+// order the settings form lists them.
+var gameSettings = []gameSetting{
+	{"wheel_slots", "wheel slots", 2, 20,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.WheelSlots }},
+	{"card_count", "deck size", 2, 200,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.CardCount }},
+	{"cards_per_player", "cards per player", 0, 10,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.CardsPerPlayer }},
+	{"starting_points", "starting points", 1, 100,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.StartingPoints }},
+	{"initiative_timer", "turn seconds", 0, 600,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.InitiativeTimer }},
+	{"prompt_seconds", "prompt seconds", 10, 300,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.PromptSeconds }},
+	{"recommended_players", "recommended players", minimumPlayers, 12,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.RecommendedPlayers }},
+	{"host_timeout", "host away seconds", 30, 3600,
+		func(p *sqlc.GameSettingsSetParams) *int32 { return &p.HostTimeout }},
+}
+
+// settingView is a setting and its current value, for the settings form.
+type settingView struct {
+	gameSetting
+	Value int32
+}
+
+// settingViews lists the game's settings for the lobby.
+func settingViews(game sqlc.GameStateRow) []settingView {
+	p := settingsParams(game)
+	views := make([]settingView, 0, len(gameSettings))
+	for _, setting := range gameSettings {
+		views = append(views, settingView{setting, *setting.value(&p)})
+	}
+	return views
+}
+
+// settingsParams returns the game's current settings, ready to save.
+func settingsParams(game sqlc.GameStateRow) sqlc.GameSettingsSetParams {
+	return sqlc.GameSettingsSetParams{
+		ID:                 game.ID,
+		WheelSlots:         game.WheelSlots,
+		CardCount:          game.CardCount,
+		InitiativeTimer:    game.InitiativeTimer,
+		CardsPerPlayer:     game.CardsPerPlayer,
+		StartingPoints:     game.StartingPoints,
+		PromptSeconds:      game.PromptSeconds,
+		RecommendedPlayers: game.RecommendedPlayers,
+		HostTimeout:        game.HostTimeout,
+	}
+}
+
+// settingsFromForm reads the settings form over the game's current settings,
+// so a field left out keeps its value. The error is safe to show the host.
+func settingsFromForm(r *http.Request, game sqlc.GameStateRow) (sqlc.GameSettingsSetParams, error) {
+	p := settingsParams(game)
+	for _, s := range gameSettings {
+		v := strings.TrimSpace(r.FormValue(s.Field))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return p, fmt.Errorf("Set %s to a whole number.", s.Label)
+		}
+		*s.value(&p) = int32(n)
+	}
+	return p, validateSettings(p)
+}
+
+// validateSettings checks settings against their bounds and each other: every
+// wheel slot needs a card, and a turn clock must leave time to take a turn.
+// The error is safe to show the host.
+func validateSettings(p sqlc.GameSettingsSetParams) error {
+	for _, s := range gameSettings {
+		if n := *s.value(&p); n < s.Min || n > s.Max {
+			return fmt.Errorf("Set %s from %d to %d.", s.Label, s.Min, s.Max)
+		}
+	}
+	if p.CardCount < p.WheelSlots {
+		return fmt.Errorf("Set deck size to at least %d, a card for every wheel slot.", p.WheelSlots)
+	}
+	if p.InitiativeTimer != 0 && p.InitiativeTimer < minTurnSeconds {
+		return fmt.Errorf("Set turn seconds to 0 (off) or at least %d.", minTurnSeconds)
+	}
+	return nil
+}
+
+// deckCapacity returns how many cards the game's deck can deal: the chosen
+// deck's cards, or its enabled packs' cards when it has no deck (or the deck
+// has since been emptied), as GameCardsInitDeck falls back. Decks and packs
+// are only populated before the start.
+func (s *state) deckCapacity() int64 {
+	if s.Game.DeckID.Valid {
+		for _, deck := range s.Decks {
+			if deck.ID == s.Game.DeckID.Int32 && deck.CardCount > 0 {
+				return deck.CardCount
+			}
+		}
+	}
+	var total int64
+	for _, pack := range s.Packs {
+		if pack.Enabled {
+			total += pack.CardCount
+		}
+	}
+	return total
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsFromForm(t *testing.T) {
+	game := sqlc.GameStateRow{
+		ID:                 "abc123",
+		WheelSlots:         10,
+		CardCount:          30,
+		InitiativeTimer:    30,
+		CardsPerPlayer:     2,
+		StartingPoints:     20,
+		PromptSeconds:      60,
+		RecommendedPlayers: 2,
+		HostTimeout:        120,
+	}
+	form := func(values url.Values) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	p, err := settingsFromForm(form(url.Values{
+		"wheel_slots":      {"8"},
+		"initiative_timer": {"0"},
+		"starting_points":  {" 15 "},
+	}), game)
+	require.NoError(t, err)
+	require.Equal(t, sqlc.GameSettingsSetParams{
+		ID:                 "abc123",
+		WheelSlots:         8,
+		CardCount:          30,
+		InitiativeTimer:    0,
+		CardsPerPlayer:     2,
+		StartingPoints:     15,
+		PromptSeconds:      60,
+		RecommendedPlayers: 2,
+		HostTimeout:        120,
+	}, p)
+
+	tests := map[string]url.Values{
+		"not a number":          {"card_count": {"lots"}},
+		"fraction":              {"starting_points": {"2.5"}},
+		"below bounds":          {"prompt_seconds": {"0"}},
+		"above bounds":          {"cards_per_player": {"50"}},
+		"no recommended player": {"recommended_players": {"0"}},
+		"empty wheel slots":     {"wheel_slots": {"12"}, "card_count": {"10"}},
+		"turn clock too fast":   {"initiative_timer": {"5"}},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := settingsFromForm(form(values), game)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDeckCapacity(t *testing.T) {
+	s := state{
+		Decks: []sqlc.DecksRow{{ID: 1, Name: "house", CardCount: 12}},
+		Packs: []sqlc.GamePacksRow{
+			{Slug: "core", Enabled: true, CardCount: 24},
+			{Slug: "impressions", Enabled: false, CardCount: 6},
+		},
+	}
+	require.Equal(t, int64(24), s.deckCapacity(), "enabled packs without a deck")
+	s.Game.DeckID = pgInt(1)
+	require.Equal(t, int64(12), s.deckCapacity(), "the chosen deck")
+	s.Game.DeckID = pgInt(2)
+	require.Equal(t, int64(24), s.deckCapacity(), "an emptied deck falls back to packs")
+}
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grackleclub/rulette/client"
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// simBaseURL is the address simulated players use; it's never dialed, since
+// handlerTransport serves the API in process.
+const simBaseURL = "http://simulate"
+
+// simStepsMax bounds the moves in one simulated game, past any -max-spins,
+// so a policy that stalls the game fails the run instead of hanging it.
+const simStepsMax = 20000
+
+// simOptions configure a simulation run.
+type simOptions struct {
+	Games    int
+	Seed     uint64
+	Seats    int      // players seated besides the host
+	Policies []string // each seat's player policy, cycled over the seats
+	Host     string   // the host policy
+	// Settings are the host's settings; cards_per_player is always zero, as
+	// no one writes cards in a simulation.
+	Settings client.Settings
+	Packs    []string
+	PlayOn   bool // continue past an empty slot while the wheel holds cards
+	MaxSpins int  // the host ends a game after this many spins
+	Keep     bool // keep the schema the games were played in
+}
+
+// simDist summarises a distribution of whole numbers.
+type simDist struct {
+	Min  int     `json:"min"`
+	P10  int     `json:"p10"`
+	P50  int     `json:"p50"`
+	P90  int     `json:"p90"`
+	Max  int     `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// distribution summarises values, by nearest rank.
+func distribution(values []int) simDist {
+	if len(values) == 0 {
+		return simDist{}
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	at := func(q float64) int {
+		return sorted[int(math.Round(q*float64(len(sorted)-1)))]
+	}
+	var sum int
+	for _, v := range sorted {
+		sum += v
+	}
+	return simDist{
+		Min:  sorted[0],
+		P10:  at(.1),
+		P50:  at(.5),
+		P90:  at(.9),
+		Max:  sorted[len(sorted)-1],
+		Mean: float64(sum) / float64(len(sorted)),
+	}
+}
+
+// simSeatReport is how one seat fared across the run.
+type simSeatReport struct {
+	Seat   int     `json:"seat"`
+	Policy string  `json:"policy"`
+	Points simDist `json:"points"`
+	Wins   float64 `json:"wins"` // a tie shares the win
+}
+
+// simReport is what a simulation run found.
+type simReport struct {
+	Games int    `json:"games"`
+	Seed  uint64 `json:"seed"`
+	Host  string `json:"host"`
+	// Spins is each game's length.
+	Spins simDist `json:"spins"`
+	// FinalPoints are every player's points at the end of their game.
+	FinalPoints   simDist `json:"final_points"`
+	WinningPoints simDist `json:"winning_points"`
+	// Margin is each winner's lead over the runner-up.
+	Margin simDist `json:"margin"`
+	// CardsLeft is how many cards each game left on the wheel.
+	CardsLeft simDist `json:"cards_left"`
+	// Exhausted games had a spin find an empty slot; Capped ones were ended
+	// at -max-spins. Continued counts the times the host played on.
+	Exhausted      int             `json:"exhausted"`
+	Capped         int             `json:"capped"`
+	Continued      int             `json:"continued"`
+	ModifiersDrawn int             `json:"modifiers_drawn"`
+	ModifiersSpent map[string]int  `json:"modifiers_spent"`
+	PromptsPassed  int             `json:"prompts_passed"`
+	PromptsFailed  int             `json:"prompts_failed"`
+	Accusations    int             `json:"accusations"`
+	Affirmed       int             `json:"affirmed"`
+	Seats          []simSeatReport `json:"seats"`
+	// Schema holds the games, when the run kept them.
+	Schema string `json:"schema,omitempty"`
+}
+
+// simResult is one game's outcome.
+type simResult struct {
+	spins, continued, modifiersDrawn int
+	promptsPassed, promptsFailed     int
+	accusations, affirmed            int
+	spent                            map[string]int
+	exhausted, capped                bool
+	cardsLeft                        int
+	points                           []int // by seat, the first seat first
+}
+
+// add folds one game's result into the report; raw and seatPoints gather
+// the values the distributions are drawn from.
+func (rep *simReport) add(res simResult, raw map[string][]int, seatPoints [][]int) {
+	raw["spins"] = append(raw["spins"], res.spins)
+	raw["cards_left"] = append(raw["cards_left"], res.cardsLeft)
+	if res.exhausted {
+		rep.Exhausted++
+	}
+	if res.capped {
+		rep.Capped++
+	}
+	rep.Continued += res.continued
+	rep.ModifiersDrawn += res.modifiersDrawn
+	for effect, n := range res.spent {
+		rep.ModifiersSpent[effect] += n
+	}
+	rep.PromptsPassed += res.promptsPassed
+	rep.PromptsFailed += res.promptsFailed
+	rep.Accusations += res.accusations
+	rep.Affirmed += res.affirmed
+
+	best, runnerUp := math.MinInt, math.MinInt
+	for i, p := range res.points {
+		raw["final_points"] = append(raw["final_points"], p)
+		seatPoints[i] = append(seatPoints[i], p)
+		switch {
+		case p > best:
+			best, runnerUp = p, best
+		case p > runnerUp:
+			runnerUp = p
+		}
+	}
+	if len(res.points) == 0 {
+		return
+	}
+	raw["winning_points"] = append(raw["winning_points"], best)
+	if runnerUp != math.MinInt {
+		raw["margin"] = append(raw["margin"], best-runnerUp)
+	}
+	var winners int
+	for _, p := range res.points {
+		if p == best {
+			winners++
+		}
+	}
+	for i, p := range res.points {
+		if p == best {
+			rep.Seats[i].Wins += 1 / float64(winners)
+		}
+	}
+}
+
+// handlerTransport serves a client's requests with handler, in process.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	inner := req.Clone(req.Context())
+	if inner.Body == nil {
+		inner.Body = http.NoBody
+	}
+	rec := &apiRecorder{header: make(http.Header), status: http.StatusOK}
+	t.handler.ServeHTTP(rec, inner)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.status, http.StatusText(rec.status)),
+		StatusCode:    rec.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.header,
+		Body:          io.NopCloser(&rec.body),
+		ContentLength: int64(rec.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// simulate plays opts.Games games through the API, as their host and
+// players, and reports on them. It plays in a schema of its own, migrated
+// afresh and dropped afterwards unless opts.Keep, so a seed replays the same
+// games whatever the database holds, and nothing it does reaches other
+// instances. It runs on a pool of its own with a single connection, so the
+// database's random() follows the seed game after game, and on a prompt clock
+// where every prompt's time is up, so the host can rule one failed at once;
+// all of it is put back when it returns.
+func simulate(ctx context.Context, opts simOptions) (simReport, error) {
+	host, ok := simHosts[opts.Host]
+	if !ok {
+		return simReport{}, fmt.Errorf("unknown host policy %q (have %s)", opts.Host, policyNames(simHosts))
+	}
+	if len(opts.Policies) == 0 {
+		return simReport{}, errors.New("no player policies")
+	}
+	players := make([]simPlayer, opts.Seats)
+	for i := range players {
+		name := opts.Policies[i%len(opts.Policies)]
+		if players[i], ok = simPlayers[name]; !ok {
+			return simReport{}, fmt.Errorf("unknown player policy %q (have %s)", name, policyNames(simPlayers))
+		}
+	}
+
+	schema := "rulette_sim_" + newInstanceID()
+	quoted := pgx.Identifier{schema}.Sanitize()
+	if _, err := dbPool.Exec(ctx, "CREATE SCHEMA "+quoted); err != nil {
+		return simReport{}, fmt.Errorf("create simulation schema: %w", err)
+	}
+	if !opts.Keep {
+		defer func(pool *pgxpool.Pool) {
+			if _, err := pool.Exec(ctx, "DROP SCHEMA "+quoted+" CASCADE"); err != nil {
+				log.Error("drop simulation schema", "error", err, "schema", schema)
+			}
+		}(dbPool)
+	}
+	cfg := dbPool.Config()
+	cfg.MaxConns, cfg.MinConns = 1, 0
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return simReport{}, fmt.Errorf("open simulation pool: %w", err)
+	}
+	defer pool.Close()
+	prevPool, prevQueries, prevElapsed, prevShared, prevBroadcast := dbPool, queries, promptElapsed, sharedCacheAge, cacheBroadcast
+	defer func() {
+		dbPool, queries, promptElapsed, sharedCacheAge, cacheBroadcast = prevPool, prevQueries, prevElapsed, prevShared, prevBroadcast
+	}()
+	dbPool, queries, sharedCacheAge, cacheBroadcast = pool, sqlc.New(pool), 0, false
+	promptElapsed = func(context.Context, string) (int32, error) { return math.MaxInt32, nil }
+	if err := migrateDatabase(ctx); err != nil {
+		return simReport{}, fmt.Errorf("simulation schema: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	registerAPI(mux, tokenMW)
+	hc := &http.Client{Transport: handlerTransport{mux}}
+
+	rep := simReport{
+		Games:          opts.Games,
+		Seed:           opts.Seed,
+		Host:           opts.Host,
+		ModifiersSpent: map[string]int{},
+		Seats:          make([]simSeatReport, opts.Seats),
+	}
+	if opts.Keep {
+		rep.Schema = schema
+	}
+	for i := range rep.Seats {
+		rep.Seats[i] = simSeatReport{Seat: i + 1, Policy: opts.Policies[i%len(opts.Policies)]}
+	}
+	raw := make(map[string][]int)
+	seatPoints := make([][]int, opts.Seats)
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	for n := range opts.Games {
+		res, err := simulateGame(ctx, hc, opts, rng, host, players)
+		if err != nil {
+			return rep, fmt.Errorf("game %d: %w", n+1, err)
+		}
+		rep.add(res, raw, seatPoints)
+	}
+	rep.Spins = distribution(raw["spins"])
+	rep.FinalPoints = distribution(raw["final_points"])
+	rep.WinningPoints = distribution(raw["winning_points"])
+	rep.Margin = distribution(raw["margin"])
+	rep.CardsLeft = distribution(raw["cards_left"])
+	for i := range rep.Seats {
+		rep.Seats[i].Points = distribution(seatPoints[i])
+	}
+	return rep, nil
+}
+
+// simSeated is a simulated player at the table.
+type simSeated struct {
+	id     int32
+	client *client.Client
+	policy simPlayer
+}
+
+// simulateGame plays one game to its end: the host sets it up and starts
+// it, then each move goes to whoever the game waits on.
+func simulateGame(ctx context.Context, hc *http.Client, opts simOptions, rng *rand.Rand, hostPolicy simHost, policies []simPlayer) (simResult, error) {
+	res := simResult{spent: map[string]int{}}
+	if err := queries.RandomSeed(ctx, rng.Float64()*2-1); err != nil {
+		return res, fmt.Errorf("seed database: %w", err)
+	}
+	host := client.New(simBaseURL, hc)
+	gameID, err := host.Create(ctx)
+	if err != nil {
+		return res, fmt.Errorf("create: %w", err)
+	}
+	seats := make([]*simSeated, len(policies))
+	if !opts.Keep {
+		defer func() {
+			if err := queries.GameDelete(ctx, gameID); err != nil {
+				log.Error("delete simulated game", "error", err, "game_id", gameID)
+			}
+			ids := []int32{host.PlayerID()}
+			for _, seat := range seats {
+				if seat != nil {
+					ids = append(ids, seat.id)
+				}
+			}
+			for _, id := range ids {
+				if id == 0 {
+					continue
+				}
+				if err := queries.PlayerDelete(ctx, id); err != nil {
+					log.Error("delete simulated player", "error", err, "player_id", id)
+				}
+			}
+		}()
+	}
+	if err := host.Join(ctx, gameID, "Host"); err != nil {
+		return res, fmt.Errorf("host join: %w", err)
+	}
+	bySeat := make(map[int32]*simSeated, len(policies))
+	for i, policy := range policies {
+		c := client.New(simBaseURL, hc)
+		if err := c.Join(ctx, gameID, fmt.Sprintf("P%d", i+1)); err != nil {
+			return res, fmt.Errorf("seat %d join: %w", i+1, err)
+		}
+		seats[i] = &simSeated{id: c.PlayerID(), client: c, policy: policy}
+		bySeat[c.PlayerID()] = seats[i]
+	}
+	if len(opts.Packs) > 0 {
+		if err := host.Packs(ctx, opts.Packs...); err != nil {
+			return res, fmt.Errorf("packs: %w", err)
+		}
+	}
+	settings := opts.Settings
+	none := int32(0)
+	settings.CardsPerPlayer = &none
+	if err := host.Settings(ctx, settings); err != nil {
+		return res, fmt.Errorf("settings: %w", err)
+	}
+	if err := host.Start(ctx, true); err != nil {
+		return res, fmt.Errorf("start: %w", err)
+	}
+
+	accusedAt := -1 // the spin count when players last had their chance to accuse
+	for step := 0; ; step++ {
+		if step == simStepsMax {
+			return res, fmt.Errorf("game %s still going after %d moves", gameID, step)
+		}
+		t, err := readSimTable(ctx, host, rng)
+		if err != nil {
+			return res, err
+		}
+		var turn *simSeated
+		if t.game.TurnPlayerID != nil {
+			turn = bySeat[*t.game.TurnPlayerID]
+		}
+		switch t.game.StateID {
+		case client.StateOver:
+			return finishSimGame(ctx, host, t, res)
+		case client.StateEnding:
+			res.exhausted = true
+			wheel, err := host.Wheel(ctx)
+			if err != nil {
+				return res, fmt.Errorf("read wheel: %w", err)
+			}
+			if opts.PlayOn && wheel.Cards > 0 {
+				res.continued++
+				err = host.Continue(ctx)
+			} else {
+				err = host.EndGame(ctx)
+			}
+			if err != nil {
+				return res, fmt.Errorf("end or continue: %w", err)
+			}
+		case client.StateTurn:
+			if turn == nil {
+				return res, fmt.Errorf("game %s: no one's turn", gameID)
+			}
+			if t.game.AwaitingAck {
+				if err := turn.client.Acknowledge(ctx); err != nil {
+					return res, fmt.Errorf("acknowledge: %w", err)
+				}
+				continue
+			}
+			if accusedAt != res.spins {
+				accusedAt = res.spins
+				accused, err := simAccusations(ctx, t, seats, turn)
+				if err != nil {
+					return res, err
+				}
+				if accused {
+					res.accusations++
+					continue
+				}
+			}
+			if res.spins >= opts.MaxSpins {
+				res.capped = true
+				if err := host.End(ctx); err != nil {
+					return res, fmt.Errorf("end: %w", err)
+				}
+				continue
+			}
+			if _, err := turn.client.Spin(ctx); err != nil {
+				return res, fmt.Errorf("spin: %w", err)
+			}
+			res.spins++
+		case client.StatePending:
+			if turn == nil {
+				return res, fmt.Errorf("game %s: no one's turn", gameID)
+			}
+			effect := t.pendingEffect(turn.id)
+			cardID, targetID := turn.policy.modifier(t, turn.id, effect)
+			switch effect {
+			case modFlip:
+				err = turn.client.Flip(ctx, cardID)
+			case modShred:
+				err = turn.client.Shred(ctx, cardID)
+			case modClone:
+				err = turn.client.Clone(ctx, cardID, targetID)
+			case modTransfer:
+				err = turn.client.Transfer(ctx, cardID, targetID)
+			default:
+				err = fmt.Errorf("no modifier in hand")
+			}
+			if err != nil {
+				return res, fmt.Errorf("%s: %w", effect, err)
+			}
+			res.spent[effect]++
+		case client.StatePrompt:
+			if turn == nil {
+				return res, fmt.Errorf("game %s: no one's turn", gameID)
+			}
+			if hostPolicy.prompt(t, turn.id) {
+				res.promptsPassed++
+				err = host.Succeed(ctx)
+			} else {
+				res.promptsFailed++
+				err = host.Fail(ctx)
+			}
+			if err != nil {
+				return res, fmt.Errorf("rule on prompt: %w", err)
+			}
+		case client.StateChallenge:
+			infractions, err := host.Infractions(ctx)
+			if err != nil {
+				return res, fmt.Errorf("read infractions: %w", err)
+			}
+			i := slices.IndexFunc(infractions, func(inf client.Infraction) bool { return inf.Active })
+			if i < 0 {
+				return res, fmt.Errorf("game %s: a challenge with no open accusation", gameID)
+			}
+			affirm, amount := hostPolicy.decide(t, infractions[i])
+			if affirm {
+				res.affirmed++
+			}
+			if err := host.Decide(ctx, infractions[i].ID, affirm, amount); err != nil {
+				return res, fmt.Errorf("decide: %w", err)
+			}
+		default:
+			return res, fmt.Errorf("game %s: unexpected state %s", gameID, t.game.State)
+		}
+	}
+}
+
+// simAccusations gives each player but the one whose turn it is, in seat
+// order, the chance to accuse someone; the first to take it does. It
+// reports whether anyone did.
+func simAccusations(ctx context.Context, t *simTable, seats []*simSeated, turn *simSeated) (bool, error) {
+	for _, s := range seats {
+		if s == turn {
+			continue
+		}
+		defendantID, cardID, ok := s.policy.accuse(t, s.id)
+		if !ok {
+			continue
+		}
+		if _, err := s.client.Accuse(ctx, defendantID, cardID); err != nil {
+			return false, fmt.Errorf("accuse: %w", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// finishSimGame reads the ended game's final points, the cards it left on
+// the wheel, and the modifiers drawn from its log.
+func finishSimGame(ctx context.Context, host *client.Client, t *simTable, res simResult) (simResult, error) {
+	for _, p := range t.players {
+		if !p.Host {
+			res.points = append(res.points, int(p.Points))
+		}
+	}
+	wheel, err := host.Wheel(ctx)
+	if err != nil {
+		return res, fmt.Errorf("read wheel: %w", err)
+	}
+	res.cardsLeft = int(wheel.Cards)
+	var since int32
+	for {
+		page, err := host.Events(ctx, since, 0)
+		if err != nil {
+			return res, fmt.Errorf("read events: %w", err)
+		}
+		for _, e := range page.Events {
+			if e.Type == "spin" && e.CardType == "modifier" {
+				res.modifiersDrawn++
+			}
+		}
+		if !page.More {
+			return res, nil
+		}
+		since = page.NextSince
+	}
+}
+
+// policyNames lists a policy table's names, for flag help and errors.
+func policyNames[P any](policies map[string]P) string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// write prints the report as a table.
+func (rep simReport) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%d games, seed %d, host %s\n\n", rep.Games, rep.Seed, rep.Host)
+	fmt.Fprintln(tw, "\tmin\tp10\tp50\tp90\tmax\tmean\t")
+	row := func(name string, d simDist) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f\t\n", name, d.Min, d.P10, d.P50, d.P90, d.Max, d.Mean)
+	}
+	row("spins", rep.Spins)
+	row("final points", rep.FinalPoints)
+	row("winning points", rep.WinningPoints)
+	row("winning margin", rep.Margin)
+	row("cards left", rep.CardsLeft)
+	for _, seat := range rep.Seats {
+		row(fmt.Sprintf("seat %d (%s)", seat.Seat, seat.Policy), seat.Points)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	games := float64(max(rep.Games, 1))
+	fmt.Fprintf(w, "\ndeck exhausted in %d games (%.0f%%), capped %d, played on %d times\n",
+		rep.Exhausted, 100*float64(rep.Exhausted)/games, rep.Capped, rep.Continued)
+	var spent []string
+	var spentTotal int
+	for _, effect := range []string{modFlip, modShred, modClone, modTransfer} {
+		spent = append(spent, fmt.Sprintf("%s %d", effect, rep.ModifiersSpent[effect]))
+		spentTotal += rep.ModifiersSpent[effect]
+	}
+	fmt.Fprintf(w, "modifiers: %d drawn, %d spent (%s), %d with no rule to spend on\n",
+		rep.ModifiersDrawn, spentTotal, strings.Join(spent, ", "), rep.ModifiersDrawn-spentTotal)
+	fmt.Fprintf(w, "prompts: %d passed, %d failed\n", rep.PromptsPassed, rep.PromptsFailed)
+	fmt.Fprintf(w, "accusations: %d, %d affirmed\n", rep.Accusations, rep.Affirmed)
+	var wins []string
+	for _, seat := range rep.Seats {
+		wins = append(wins, fmt.Sprintf("seat %d %.0f%%", seat.Seat, 100*seat.Wins/games))
+	}
+	if _, err := fmt.Fprintf(w, "wins: %s\n", strings.Join(wins, ", ")); err != nil {
+		return err
+	}
+	if rep.Schema != "" {
+		if _, err := fmt.Fprintf(w, "games kept in schema %s\n", rep.Schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulateCommand runs `rulette simulate [flags]`, playing whole games with
+// scripted players and host against the game's own actions, then reporting
+// on their length, points, modifiers and deck. The seed drives the scripted
+// choices and the database's random(), and the games are played in a fresh
+// schema, so a seed replays the same games. connect opens the database, once
+// the flags are parsed; it is left unmigrated.
+func simulateCommand(ctx context.Context, args []string, stdout io.Writer, connect func(context.Context) (func(), error)) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	games := fs.Int("games", 100, "games to play")
+	seed := fs.Uint64("seed", 1, "random seed for the policies and the database")
+	seats := fs.Int("players", 4, "players seated besides the host")
+	policies := fs.String("policy", "random", "player policies, comma separated, cycled over the seats: "+policyNames(simPlayers))
+	hostPolicy := fs.String("host", "coin", "host policy: "+policyNames(simHosts))
+	points := fs.Int("points", 0, "starting points (default: the game's)")
+	cards := fs.Int("cards", 0, "cards dealt to the wheel (default: the game's)")
+	slots := fs.Int("slots", 0, "wheel slots (default: the game's)")
+	packs := fs.String("packs", "", "card packs to deal from, comma separated (default: the game's)")
+	playOn := fs.Bool("play-on", false, "play on past an empty slot while the wheel holds cards")
+	maxSpins := fs.Int("max-spins", 500, "end a game after this many spins")
+	keep := fs.Bool("keep", false, "keep the schema the games were played in")
+	asJSON := fs.Bool("json", false, "report as JSON")
+	verbose := fs.Bool("v", false, "log every action")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *games < 1 || *seats < 1 {
+		return errors.New("usage: rulette simulate [-games n] [-seed n] [-players n] [-policy p,...] [-host h] [flags]")
+	}
+
+	opts := simOptions{
+		Games:    *games,
+		Seed:     *seed,
+		Seats:    *seats,
+		Policies: strings.Split(*policies, ","),
+		Host:     *hostPolicy,
+		PlayOn:   *playOn,
+		MaxSpins: *maxSpins,
+		Keep:     *keep,
+	}
+	if *packs != "" {
+		opts.Packs = strings.Split(*packs, ",")
+	}
+	setting := func(v int) *int32 {
+		n := int32(v)
+		return &n
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "points":
+			opts.Settings.StartingPoints = setting(*points)
+		case "cards":
+			opts.Settings.CardCount = setting(*cards)
+		case "slots":
+			opts.Settings.WheelSlots = setting(*slots)
+		}
+	})
+	if !*verbose {
+		prev := log
+		defer func() { log = prev }()
+		log = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	}
+
+	closeDB, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	rep, err := simulate(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+	return rep.write(stdout)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/grackleclub/rulette/client"
+)
+
+// simTable is the game as a simulated player or host sees it when they
+// choose a move, and the run's random source to choose with.
+type simTable struct {
+	rng     *rand.Rand
+	game    client.Game
+	players []client.Player // in seat order, the host first
+	hands   map[int32][]client.Card
+}
+
+// readSimTable reads the game as its host sees it.
+func readSimTable(ctx context.Context, host *client.Client, rng *rand.Rand) (*simTable, error) {
+	t := &simTable{rng: rng, hands: make(map[int32][]client.Card)}
+	var err error
+	if t.game, err = host.Game(ctx); err != nil {
+		return nil, fmt.Errorf("read game: %w", err)
+	}
+	players, err := host.Players(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read players: %w", err)
+	}
+	t.players = players.Players
+	hands, err := host.Hands(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read hands: %w", err)
+	}
+	for _, h := range hands {
+		t.hands[h.PlayerID] = h.Cards
+	}
+	return t, nil
+}
+
+// rules returns the rules playerID holds.
+func (t *simTable) rules(playerID int32) []client.Card {
+	var rules []client.Card
+	for _, c := range t.hands[playerID] {
+		if c.Type == "rule" {
+			rules = append(rules, c)
+		}
+	}
+	return rules
+}
+
+// opponents returns the players other than playerID who take turns.
+func (t *simTable) opponents(playerID int32) []client.Player {
+	var others []client.Player
+	for _, p := range t.players {
+		if !p.Host && p.ID != playerID {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+// pendingEffect returns the effect of the modifier playerID drew and owes a
+// target: the newest in their hand.
+func (t *simTable) pendingEffect(playerID int32) string {
+	var newest client.Card
+	for _, c := range t.hands[playerID] {
+		if c.Type == "modifier" && c.ID > newest.ID {
+			newest = c
+		}
+	}
+	return newest.ModifierEffect
+}
+
+// simPlayer is how a simulated player plays.
+type simPlayer interface {
+	// modifier picks a rule of me's own to spend a pending modifier on and,
+	// for clone or transfer, who gets it. me holds a rule, or the game would
+	// have spent the modifier itself.
+	modifier(t *simTable, me int32, effect string) (cardID, targetID int32)
+	// accuse picks an accusation to make as a turn begins, if any: a rule
+	// its defendant holds.
+	accuse(t *simTable, me int32) (defendantID, cardID int32, ok bool)
+}
+
+// simHost is how a simulated host rules.
+type simHost interface {
+	// prompt rules on spinnerID's prompt: true passes it.
+	prompt(t *simTable, spinnerID int32) bool
+	// decide rules on an accusation: affirmed, and for how many points.
+	decide(t *simTable, inf client.Infraction) (affirm bool, amount int32)
+}
+
+// simPlayers are the player policies, by the name -policy takes.
+var simPlayers = map[string]simPlayer{
+	"bot":     simBotPlayer{},
+	"random":  simRandomPlayer{},
+	"accuser": simRandomPlayer{accuseRate: .25},
+}
+
+// simHosts are the host policies, by the name -host takes.
+var simHosts = map[string]simHost{
+	"coin":    simCoinHost{},
+	"lenient": simLenientHost{},
+	"strict":  simStrictHost{},
+}
+
+// simBotPlayer plays as the server's bots do: the first rule it holds, to
+// the leader on points, and no accusations.
+type simBotPlayer struct{}
+
+func (simBotPlayer) modifier(t *simTable, me int32, effect string) (int32, int32) {
+	var cardID int32
+	if rules := t.rules(me); len(rules) > 0 {
+		cardID = rules[0].ID
+	}
+	target := me
+	var best int32
+	for _, p := range t.opponents(me) {
+		if target == me || p.Points > best {
+			target, best = p.ID, p.Points
+		}
+	}
+	return cardID, target
+}
+
+func (simBotPlayer) accuse(*simTable, int32) (int32, int32, bool) { return 0, 0, false }
+
+// simRandomPlayer picks its rules and targets at random, and accuses a
+// random opponent of a random rule they hold at accuseRate of its chances.
+type simRandomPlayer struct {
+	accuseRate float64
+}
+
+func (simRandomPlayer) modifier(t *simTable, me int32, effect string) (int32, int32) {
+	var cardID int32
+	if rules := t.rules(me); len(rules) > 0 {
+		cardID = rules[t.rng.IntN(len(rules))].ID
+	}
+	target := me
+	if others := t.opponents(me); len(others) > 0 {
+		target = others[t.rng.IntN(len(others))].ID
+	}
+	return cardID, target
+}
+
+func (p simRandomPlayer) accuse(t *simTable, me int32) (int32, int32, bool) {
+	if p.accuseRate == 0 || t.rng.Float64() >= p.accuseRate {
+		return 0, 0, false
+	}
+	var suspects []client.Player
+	for _, o := range t.opponents(me) {
+		if len(t.rules(o.ID)) > 0 {
+			suspects = append(suspects, o)
+		}
+	}
+	if len(suspects) == 0 {
+		return 0, 0, false
+	}
+	defendant := suspects[t.rng.IntN(len(suspects))].ID
+	rules := t.rules(defendant)
+	return defendant, rules[t.rng.IntN(len(rules))].ID, true
+}
+
+// simCoinHost passes half the prompts and affirms half the accusations, for
+// 1 to 3 points.
+type simCoinHost struct{}
+
+func (simCoinHost) prompt(t *simTable, _ int32) bool { return t.rng.IntN(2) == 0 }
+
+func (simCoinHost) decide(t *simTable, _ client.Infraction) (bool, int32) {
+	if t.rng.IntN(2) == 0 {
+		return false, 0
+	}
+	return true, 1 + t.rng.Int32N(3)
+}
+
+// simLenientHost passes every prompt and absolves every accusation.
+type simLenientHost struct{}
+
+func (simLenientHost) prompt(*simTable, int32) bool                      { return true }
+func (simLenientHost) decide(*simTable, client.Infraction) (bool, int32) { return false, 0 }
+
+// simStrictHost fails every prompt and affirms every accusation, for 2
+// points.
+type simStrictHost struct{}
+
+func (simStrictHost) prompt(*simTable, int32) bool                      { return false }
+func (simStrictHost) decide(*simTable, client.Infraction) (bool, int32) { return true, 2 }
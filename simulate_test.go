@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"testing"
+
+	"github.com/grackleclub/rulette/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistribution(t *testing.T) {
+	require.Equal(t, simDist{}, distribution(nil))
+	require.Equal(t, simDist{Min: 7, P10: 7, P50: 7, P90: 7, Max: 7, Mean: 7}, distribution([]int{7}))
+	d := distribution([]int{10, 1, 9, 2, 8, 3, 7, 4, 6, 5, 0})
+	require.Equal(t, simDist{Min: 0, P10: 1, P50: 5, P90: 9, Max: 10, Mean: 5}, d)
+}
+
+func TestSimReportAdd(t *testing.T) {
+	rep := simReport{ModifiersSpent: map[string]int{}, Seats: make([]simSeatReport, 3)}
+	raw := make(map[string][]int)
+	seatPoints := make([][]int, 3)
+	rep.add(simResult{
+		spins: 12, exhausted: true, cardsLeft: 4, modifiersDrawn: 2,
+		spent:  map[string]int{modFlip: 1},
+		points: []int{20, 25, 18},
+	}, raw, seatPoints)
+	rep.add(simResult{
+		spins: 30, capped: true, accusations: 2, affirmed: 1,
+		spent:  map[string]int{modFlip: 1, modClone: 1},
+		points: []int{22, 19, 22},
+	}, raw, seatPoints)
+
+	require.Equal(t, []int{12, 30}, raw["spins"])
+	require.Equal(t, []int{25, 22}, raw["winning_points"])
+	require.Equal(t, []int{5, 0}, raw["margin"], "a tie wins by nothing")
+	require.Equal(t, []int{20, 22}, seatPoints[0])
+	require.Equal(t, 1, rep.Exhausted)
+	require.Equal(t, 1, rep.Capped)
+	require.Equal(t, map[string]int{modFlip: 2, modClone: 1}, rep.ModifiersSpent)
+	require.Equal(t, []float64{.5, 1, .5}, []float64{rep.Seats[0].Wins, rep.Seats[1].Wins, rep.Seats[2].Wins})
+}
+
+func simTestTable() *simTable {
+	return &simTable{
+		rng: rand.New(rand.NewPCG(1, 1)),
+		players: []client.Player{
+			{ID: 10, Host: true, Points: 99},
+			{ID: 11, Seat: 1, Points: 20},
+			{ID: 12, Seat: 2, Points: 24},
+			{ID: 13, Seat: 3, Points: 24},
+		},
+		hands: map[int32][]client.Card{
+			11: {{ID: 1, Type: "rule"}, {ID: 5, Type: "modifier", ModifierEffect: modClone}, {ID: 2, Type: "rule"}},
+			12: {{ID: 3, Type: "rule"}},
+		},
+	}
+}
+
+func TestSimPolicies(t *testing.T) {
+	table := simTestTable()
+	require.Equal(t, modClone, table.pendingEffect(11))
+	require.Empty(t, table.pendingEffect(12))
+
+	cardID, targetID := simBotPlayer{}.modifier(table, 11, modClone)
+	require.Equal(t, int32(1), cardID, "the first rule")
+	require.Equal(t, int32(12), targetID, "the leader, seat order breaking the tie; never the host")
+	_, _, ok := simBotPlayer{}.accuse(table, 11)
+	require.False(t, ok)
+
+	random := simRandomPlayer{}
+	for range 20 {
+		cardID, targetID := random.modifier(table, 11, modTransfer)
+		require.Contains(t, []int32{1, 2}, cardID)
+		require.Contains(t, []int32{12, 13}, targetID)
+	}
+	_, _, ok = random.accuse(table, 11)
+	require.False(t, ok, "no accusations at a zero rate")
+
+	accuser := simRandomPlayer{accuseRate: 1}
+	defendantID, cardID, ok := accuser.accuse(table, 11)
+	require.True(t, ok)
+	require.Equal(t, int32(12), defendantID, "the only opponent holding a rule")
+	require.Equal(t, int32(3), cardID)
+	defendantID, _, ok = accuser.accuse(table, 12)
+	require.True(t, ok)
+	require.Equal(t, int32(11), defendantID)
+
+	for range 20 {
+		affirm, amount := simCoinHost{}.decide(table, client.Infraction{})
+		if affirm {
+			require.True(t, amount >= 1 && amount <= 3, "amount %d", amount)
+		} else {
+			require.Zero(t, amount)
+		}
+	}
+}
+
+func TestSimulateOptions(t *testing.T) {
+	_, err := simulate(t.Context(), simOptions{Games: 1, Seats: 2, Policies: []string{"random"}, Host: "nope"})
+	require.ErrorContains(t, err, `unknown host policy "nope"`)
+	_, err = simulate(t.Context(), simOptions{Games: 1, Seats: 2, Policies: []string{"bot", "nope"}, Host: "coin"})
+	require.ErrorContains(t, err, `unknown player policy "nope"`)
+
+	var out bytes.Buffer
+	require.Error(t, simulateCommand(t.Context(), []string{"-games", "0"}, &out, nil))
+	require.Error(t, simulateCommand(t.Context(), []string{"extra"}, &out, nil))
+}
+
+func TestHandlerTransport(t *testing.T) {
+	hc := &http.Client{Transport: handlerTransport{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotNil(t, r.Body)
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	})}}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, simBaseURL+"/api/v1/games/abc123", nil)
+	require.NoError(t, err)
+	resp, err := hc.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+	require.Equal(t, "/api/v1/games/abc123", resp.Header.Get("X-Path"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "short and stout", string(body))
+}
+
+func TestSimReportWrite(t *testing.T) {
+	rep := simReport{
+		Games: 2, Seed: 7, Host: "coin",
+		Spins:          simDist{Min: 10, P10: 10, P50: 12, P90: 14, Max: 14, Mean: 12},
+		Exhausted:      2,
+		ModifiersDrawn: 3,
+		ModifiersSpent: map[string]int{modFlip: 1, modTransfer: 1},
+		Seats:          []simSeatReport{{Seat: 1, Policy: "bot", Wins: 1.5}, {Seat: 2, Policy: "random", Wins: .5}},
+	}
+	var out bytes.Buffer
+	require.NoError(t, rep.write(&out))
+	require.Contains(t, out.String(), "2 games, seed 7, host coin")
+	require.Contains(t, out.String(), "deck exhausted in 2 games (100%)")
+	require.Contains(t, out.String(), "modifiers: 3 drawn, 2 spent (flip 1, shred 0, clone 0, transfer 1), 1 with no rule to spend on")
+	require.Contains(t, out.String(), "wins: seat 1 75%, seat 2 25%")
+	require.Contains(t, out.String(), "seat 2 (random)")
+}
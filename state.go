@@ -1,9 +1,10 @@
 package main
 
 import (
+	"crypto/hmac"
 	"fmt"
 	"net/http"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +15,8 @@ import (
 type state struct {
 	Updated      time.Time
 	Game         sqlc.GameStateRow
-	Players      []sqlc.GamePlayerPointsRow
+	Players      []sqlc.GamePlayerPointsRow    // seated: the host and those taking turns
+	Spectators   []sqlc.GameSpectatorsRow      // watching, with no seat
 	CardsWheel   []sqlc.GameCardsWheelViewRow  // hidden cards on the wheel
 	CardsPlayers []sqlc.GameCardsPlayerViewRow // revealed cards held by players
 	Config       map[string]string             // generic baggage (e.g. frontend refresh rate)
@@ -24,24 +26,55 @@ type state struct {
 	// AwaitingAck is true when the current-turn player has spun a rule card
 	// they must acknowledge before the turn advances.
 	AwaitingAck bool
+	// TurnDeadline is when the current turn times out; zero when no clock
+	// is running (between turns, paused, or the game's timer is off).
+	TurnDeadline time.Time
+	// CardsWritten counts each player's written cards during the writing phase.
+	CardsWritten []sqlc.GameCardsWrittenRow
+	// Decks lists the saved decks the host can pick from before the start.
+	Decks []sqlc.DecksRow
+	// Packs lists the card packs, and whether this game plays each, before the
+	// start.
+	Packs []sqlc.GamePacksRow
+	// HostVotes tallies the players' picks for a new host while the host is
+	// away (Game.HostAway).
+	HostVotes []sqlc.HostVotesRow
 }
 
-// isPlayerInGame returns true when cookieKey exists in game_players.
+// isPlayerInGame returns true when cookieKey belongs to a seated player;
+// spectators have no seat.
 func (s *state) isPlayerInGame(cookieKey string) bool {
 	for _, player := range s.Players {
-		if player.SessionKey.String == cookieKey {
+		if sameSession(player.SessionKey, cookieKey) {
 			return true
 		}
 	}
 	return false
 }
 
+// isSpectator returns true when cookieKey belongs to someone watching the
+// game without a seat.
+func (s *state) isSpectator(cookieKey string) bool {
+	for _, spectator := range s.Spectators {
+		if sameSession(spectator.SessionKey, cookieKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// canWatch returns true when cookieKey may see the game: a seated player or
+// a spectator.
+func (s *state) canWatch(cookieKey string) bool {
+	return s.isPlayerInGame(cookieKey) || s.isSpectator(cookieKey)
+}
+
 // isHost verifies that the player is host
 // by checking that they are initiative 0 for the game.
 func (s *state) isHost(cookieKey string) bool {
 	var inGame bool
 	for _, player := range s.Players {
-		if player.SessionKey.String == cookieKey {
+		if sameSession(player.SessionKey, cookieKey) {
 			inGame = true
 			if player.Initiative.Int32 == int32(0) {
 				return true
@@ -52,6 +85,16 @@ func (s *state) isHost(cookieKey string) bool {
 	return false
 }
 
+// isBot returns true when playerID is a seated bot.
+func (s *state) isBot(playerID int32) bool {
+	for _, player := range s.Players {
+		if player.PlayerID == playerID {
+			return player.Bot
+		}
+	}
+	return false
+}
+
 // nonHostPlayers returns the count of players who can take turns,
 // i.e. everyone except the host (initiative 0).
 func (s *state) nonHostPlayers() int {
@@ -67,7 +110,7 @@ func (s *state) nonHostPlayers() int {
 func (s *state) isPlayerTurn(cookieKey string) bool {
 	var inGame bool
 	for _, player := range s.Players {
-		if player.SessionKey.String == cookieKey {
+		if sameSession(player.SessionKey, cookieKey) {
 			inGame = true
 			if player.Initiative.Int32 == s.Game.InitiativeCurrent.Int32 {
 				return true
@@ -80,7 +123,7 @@ func (s *state) isPlayerTurn(cookieKey string) bool {
 
 func (s *state) callerID(cookieKey string) (int, error) {
 	for _, player := range s.Players {
-		if player.SessionKey.String == cookieKey {
+		if sameSession(player.SessionKey, cookieKey) {
 			return int(player.PlayerID), nil
 		}
 	}
@@ -89,7 +132,7 @@ func (s *state) callerID(cookieKey string) (int, error) {
 
 func (s *state) callerName(cookieKey string) (string, error) {
 	for _, player := range s.Players {
-		if player.SessionKey.String == cookieKey {
+		if sameSession(player.SessionKey, cookieKey) {
 			return player.Name, nil
 		}
 	}
@@ -111,27 +154,33 @@ func (s *state) callerInfo(cookieKey string) error {
 	return nil
 }
 
-// cookie inspects the request for cookie and returns
-// the player ID and session key, or any error.
+// cookie inspects the request for a signed session cookie and returns the
+// player ID and the hash of the session key (the form game_players stores),
+// or any error. A bearer token tokenMW resolved stands in for the cookie.
 //
-// Cookie format is: {player_id}:{session_key}
+// Cookie format is: {player_id}:{session_key}:{signature}
 func cookie(r *http.Request) (string, string, error) {
-	var cookieID string
-	var cookieKey string
-	cookie, err := r.Cookie("session")
+	if b, ok := r.Context().Value(bearerKey{}).(bearer); ok {
+		if b.err != nil {
+			return "", "", b.err
+		}
+		return strconv.Itoa(int(b.playerID)), b.keyHash, nil
+	}
+	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil {
 		return "", "", ErrCookieMissing
 	}
 	parts := strings.Split(cookie.Value, ":")
-	if len(parts) != 2 {
-		log.Warn("invalid session cookie format",
-			"cookie_value", cookie.Value,
-		)
+	if len(parts) != 3 {
+		log.Warn("invalid session cookie format", "parts", len(parts))
 		return "", "", ErrCookieInvalid
 	}
-	cookieID = parts[0]
-	cookieKey = parts[1]
-	return cookieID, cookieKey, nil
+	payload := parts[0] + ":" + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signSession(payload))) {
+		log.Warn("invalid session cookie signature", "cookie_id", parts[0])
+		return "", "", ErrCookieInvalid
+	}
+	return parts[0], hashSessionKey(parts[1]), nil
 }
 
 // isGameActive returns true when when game is active
@@ -165,34 +214,32 @@ func (s *state) hasPendingModifier() bool {
 	return false
 }
 
-// Standings returns the non-host players ranked by points, highest first.
-// Value receiver so templates can call it on the by-value state data.
-func (s state) Standings() []sqlc.GamePlayerPointsRow {
-	ranked := make([]sqlc.GamePlayerPointsRow, 0, len(s.Players))
-	for _, p := range s.Players {
-		if p.Initiative.Int32 == 0 {
-			continue // skip the host
+// cardsOwed returns how many more cards playerID must write, by the game's
+// cards per player, before the writing phase closes on its own.
+func cardsOwed(game sqlc.GameStateRow, written []sqlc.GameCardsWrittenRow, playerID int32) int {
+	var n int64
+	for _, row := range written {
+		if row.Creator.Int32 == playerID {
+			n = row.Written
+			break
 		}
-		ranked = append(ranked, p)
 	}
-	sort.SliceStable(ranked, func(i, j int) bool {
-		return ranked[i].Points.Int32 > ranked[j].Points.Int32
-	})
-	return ranked
+	owed := int64(game.CardsPerPlayer) - n
+	if owed < 0 {
+		return 0
+	}
+	return int(owed)
 }
 
-// Winners returns the player(s) with the most points, including ties.
-func (s state) Winners() []sqlc.GamePlayerPointsRow {
-	ranked := s.Standings()
-	if len(ranked) == 0 {
-		return nil
-	}
-	most := ranked[0].Points.Int32
-	var winners []sqlc.GamePlayerPointsRow
-	for _, p := range ranked {
-		if p.Points.Int32 == most {
-			winners = append(winners, p)
+// cardsOutstanding returns the cards still owed across every player; bots
+// write none.
+func (s *state) cardsOutstanding() int {
+	var total int
+	for _, player := range s.Players {
+		if player.Bot {
+			continue
 		}
+		total += cardsOwed(s.Game, s.CardsWritten, player.PlayerID)
 	}
-	return winners
+	return total
 }
@@ -13,10 +13,12 @@ import (
 )
 
 // sharedPartials are parsed into every template so their {{define}}
-// blocks are callable from any page or fragment: currently just the
-// footer.
+// blocks are callable from any page or fragment: the footer, the wording
+// of a game event, and the points chart.
 var sharedPartials = []string{
 	"static/html/tmpl.footer.html",
+	"static/html/tmpl.event_text.html",
+	"static/html/tmpl.points_chart.html",
 }
 
 // pagePartials hold {{define}} blocks only full pages need: the link-preview
@@ -79,7 +81,7 @@ func readParse(fs embed.FS, path, base string, fullPage bool) (*template.Templat
 			return n
 		},
 		"inGame": func(data any) bool {
-			_, ok := data.(state)
+			_, ok := data.(view)
 			return ok
 		},
 	}
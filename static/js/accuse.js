@@ -79,7 +79,11 @@
       var formData = new FormData();
       formData.append("verdict", "absolve");
       formData.append("infraction_id", input.value);
-      fetch("/" + gameId + "/action/decide", { method: "POST", body: formData }).then(function(res) {
+      fetch("/" + gameId + "/action/decide", {
+        method: "POST",
+        headers: { "X-CSRF-Token": document.querySelector('meta[name="csrf-token"]').content },
+        body: formData
+      }).then(function(res) {
         if (res.ok) {
           lastDecidedId = input.value;
           dialog.close();
@@ -0,0 +1,23 @@
+(function () {
+  // the turn clock: the status fragment renders the seconds left when it was
+  // drawn (data-remaining), and this counts each .turn-clock down locally
+  // between redraws. counting from the local receipt time, rather than a
+  // server timestamp, keeps client clock skew out of it. the server times the
+  // turn out itself; reaching 0 here only stops the count.
+  function tick() {
+    var clocks = document.querySelectorAll(".turn-clock");
+    for (var i = 0; i < clocks.length; i++) {
+      var el = clocks[i];
+      // a redraw (or a morph that changed data-remaining) restarts the count
+      if (el._ruletteFrom !== el.dataset.remaining) {
+        el._ruletteFrom = el.dataset.remaining;
+        el._ruletteDeadline = Date.now() + Number(el.dataset.remaining) * 1000;
+      }
+      var left = Math.max(0, Math.ceil((el._ruletteDeadline - Date.now()) / 1000));
+      el.textContent = left + "s";
+      el.classList.toggle("turn-clock-low", left <= 5);
+    }
+  }
+  tick();
+  setInterval(tick, 250);
+})();
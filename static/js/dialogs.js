@@ -71,6 +71,22 @@
   }
   document.body.addEventListener("notice", showNotice);
 
+  // a new api token: HX-Trigger {"apiToken":{"id":1,"token":"rlt_..."}}. shown
+  // in the help dialog, once; the server keeps only its hash.
+  document.body.addEventListener("apiToken", function (e) {
+    var output = document.getElementById("api-token");
+    if (!output || !e.detail || !e.detail.token) return;
+    output.textContent = e.detail.token;
+  });
+
+  // HX-Trigger {"tokensRevoked":n}: say how many went
+  document.body.addEventListener("tokensRevoked", function (e) {
+    var n = e.detail && typeof e.detail.value === "number" ? e.detail.value : 0;
+    document.body.dispatchEvent(new CustomEvent("notice", {
+      detail: { value: "Revoked " + n + (n === 1 ? " token." : " tokens.") },
+    }));
+  });
+
   // server-rendered notice shown on page load: <dialog data-autoshow>. used by
   // the home and join pages after a redirect carrying ?alert=<code>.
   var autoshow = document.querySelector("dialog[data-autoshow]");
@@ -39,7 +39,10 @@
   function attempt(url, effect) {
     if (inFlight) return;
     inFlight = true;
-    fetch(url, { method: "POST" }).then(function (res) {
+    fetch(url, {
+      method: "POST",
+      headers: { "X-CSRF-Token": document.querySelector('meta[name="csrf-token"]').content }
+    }).then(function (res) {
       inFlight = false;
       if (res.ok) {
         pending = null;
@@ -126,7 +129,10 @@
     btn.className = "button-teal";
     btn.textContent = "spin again";
     btn.addEventListener("click", function () {
-      htmx.ajax("POST", "/" + gameId + "/action/spin", { swap: "none" });
+      htmx.ajax("POST", "/" + gameId + "/action/spin", {
+        swap: "none",
+        headers: { "X-CSRF-Token": document.querySelector('meta[name="csrf-token"]').content }
+      });
       dialog.close();
     });
     wrapper.appendChild(btn);
@@ -151,7 +151,10 @@
     if (!dialog) return;
     ruling = true;
     var gameId = dialog.dataset.gameId;
-    fetch("/" + gameId + "/action/" + action, { method: "POST" }).then(
+    fetch("/" + gameId + "/action/" + action, {
+      method: "POST",
+      headers: { "X-CSRF-Token": document.querySelector('meta[name="csrf-token"]').content }
+    }).then(
       function (res) {
         ruling = false;
         if (res.ok) {
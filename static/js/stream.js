@@ -0,0 +1,32 @@
+(function () {
+  // server-pushed updates: /{game_id}/stream emits "update" whenever the game
+  // changes, and every fragment refetches on the gameUpdate body event. while
+  // the stream is open, window.ruletteStreaming pauses the fallback polls;
+  // when it drops (or EventSource is unavailable) the polls take over again
+  // until EventSource reconnects on its own.
+  window.ruletteStreaming = false;
+  var src = document.body.dataset.stream;
+  if (!src || !window.EventSource) return;
+
+  function refresh() {
+    document.body.dispatchEvent(new Event("gameUpdate"));
+  }
+
+  var source = new EventSource(src);
+  source.addEventListener("open", function () {
+    window.ruletteStreaming = true;
+    // catch up on anything that changed while the stream was down
+    refresh();
+  });
+  source.addEventListener("error", function () {
+    window.ruletteStreaming = false;
+  });
+  source.addEventListener("update", refresh);
+  // the game ended: refresh once more for the final screen and stop; the
+  // fragments answer 286 from here on, which settles any remaining polls.
+  source.addEventListener("over", function () {
+    window.ruletteStreaming = false;
+    source.close();
+    refresh();
+  });
+})();
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// streamHeartbeat is how often an idle event stream writes a comment, so
+	// proxies and load balancers don't close it for inactivity.
+	streamHeartbeat = 15 * time.Second
+	// streamRetry is the reconnect delay (ms) the browser's EventSource waits
+	// after the stream drops. fallback polling covers the gap.
+	streamRetry = 3000
+)
+
+// streamBroker fans game change notifications out to every open event
+// stream for that game. A notification carries no payload: it only tells the
+// client something changed, and the client refetches whatever it shows.
+type streamBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func newStreamBroker() *streamBroker {
+	return &streamBroker{subs: make(map[string]map[chan struct{}]struct{})}
+}
+
+// subscribe registers a listener for gameID. The returned channel receives a
+// value whenever the game changes; bursts coalesce into a single pending
+// notification, so a slow client never blocks publish. Call the returned func
+// to unsubscribe.
+func (b *streamBroker) subscribe(gameID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	if b.subs[gameID] == nil {
+		b.subs[gameID] = make(map[chan struct{}]struct{})
+	}
+	b.subs[gameID][ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[gameID], ch)
+		if len(b.subs[gameID]) == 0 {
+			delete(b.subs, gameID)
+		}
+	}
+}
+
+// publish notifies every listener for gameID without blocking.
+func (b *streamBroker) publish(gameID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[gameID] {
+		select {
+		case ch <- struct{}{}:
+		default: // a notification is already pending
+		}
+	}
+}
+
+// listeners returns how many streams are open for gameID.
+func (b *streamBroker) listeners(gameID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[gameID])
+}
+
+// streamHandler handles the '/{game_id}/stream' endpoint: a server-sent event
+// stream that emits "update" whenever the game changes, and "over" (then
+// closes) once the game has ended. Clients refetch their fragments on each
+// update; polling remains the fallback for clients without a stream.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := strings.Trim(strings.TrimSuffix(r.URL.Path, "/stream"), "/")
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(attrGameID.String(gameID))
+	log := log.With("handler", "streamHandler", "game_id", gameID)
+
+	if r.Method != http.MethodGet {
+		log.Debug("unsupported method", "method", r.Method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cookieID, cookieKey, err := cookie(r)
+	if err != nil {
+		setCookieErr(w, err)
+		return
+	}
+	span.SetAttributes(attrPlayerID.String(cookieID))
+	log = log.With("cookie_id", cookieID)
+	state, err := stateFromCacheOrDB(r.Context(), &cache, gameID)
+	if err != nil {
+		if err == ErrStateNoGame {
+			log.Warn("game not found", "game_id", gameID)
+			http.Error(w, "game not found", http.StatusNotFound)
+			return
+		}
+		log.Error("unexpected error getting state", "error", err, "game_id", gameID)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !state.canWatch(cookieKey) {
+		log.Warn("prohibiting unauthorized player access")
+		http.Error(w, "player not in game", http.StatusForbidden)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: don't buffer the stream
+	w.WriteHeader(http.StatusOK)
+	if state.Game.StateID == stateOver {
+		fmt.Fprint(w, "event: over\ndata: \n\n")
+		rc.Flush()
+		return
+	}
+	updates, unsubscribe := streams.subscribe(gameID)
+	defer unsubscribe()
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if err := rc.Flush(); err != nil {
+		log.Error("stream flush unsupported", "error", err)
+		return
+	}
+	log.Debug("stream opened", "listeners", streams.listeners(gameID))
+	// a streaming client doesn't poll, so the heartbeat marks it seen
+	markSeen(r.Context(), log, gameID, cookieKey)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Debug("stream closed by client")
+			return
+		case <-heartbeat.C:
+			markSeen(r.Context(), log, gameID, cookieKey)
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-updates:
+			// a state change may have ended the game; tell the client so it
+			// can close the stream instead of reconnecting forever.
+			event := "update"
+			if s, err := stateFromCacheOrDB(r.Context(), &cache, gameID); err == nil &&
+				s.Game.StateID == stateOver {
+				event = "over"
+			}
+			fmt.Fprintf(w, "event: %s\ndata: \n\n", event)
+			if event == "over" {
+				rc.Flush()
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			log.Debug("stream write failed", "error", err)
+			return
+		}
+	}
+}
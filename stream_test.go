@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamBroker(t *testing.T) {
+	b := newStreamBroker()
+	a, unsubA := b.subscribe("aaaaaa")
+	other, unsubOther := b.subscribe("bbbbbb")
+	defer unsubOther()
+	require.Equal(t, 1, b.listeners("aaaaaa"))
+
+	// a burst of changes coalesces into one pending notification
+	b.publish("aaaaaa")
+	b.publish("aaaaaa")
+	require.Len(t, a, 1)
+	<-a
+	require.Len(t, a, 0)
+
+	// other games are not notified
+	require.Len(t, other, 0)
+
+	// publishing to a game with no listeners is a no-op
+	b.publish("cccccc")
+
+	unsubA()
+	require.Equal(t, 0, b.listeners("aaaaaa"))
+	b.publish("aaaaaa")
+	require.Len(t, a, 0, "unsubscribed listener should not be notified")
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+)
+
+// gameSummary is a game in numbers: each player's tallies, the awards they
+// earned, and totals for the table. It's built from the event log, so the
+// departed are counted for what they did before leaving.
+type gameSummary struct {
+	Final       bool            `json:"final"` // the game is over; until then it's the game so far
+	Players     []summaryPlayer `json:"players"`
+	Awards      []award         `json:"awards"`
+	Turns       int             `json:"turns"`
+	Spins       int             `json:"spins"`
+	Accusations int             `json:"accusations"`
+	Affirmed    int             `json:"affirmed"`
+	PromptsWon  int             `json:"prompts_won"`
+}
+
+// summaryPlayer is one non-host player's tallies. Points and RulesHeld are
+// as the game stands, so zero for those who left.
+type summaryPlayer struct {
+	PlayerID     int32  `json:"player_id"`
+	Name         string `json:"name"`
+	Seated       bool   `json:"seated"`
+	Points       int32  `json:"points"`
+	RulesHeld    int    `json:"rules_held"`
+	Spins        int    `json:"spins"`
+	Accusations  int    `json:"accusations"` // made
+	Affirmed     int    `json:"affirmed"`    // infractions affirmed against them
+	PromptsWon   int    `json:"prompts_won"`
+	PromptPoints int32  `json:"prompt_points"`
+	BiggestSwing int32  `json:"biggest_swing"` // their largest single points change, signed
+	LongestTurn  int    `json:"longest_turn_seconds"`
+}
+
+// award goes to whoever leads one tally; ties share it.
+type award struct {
+	Key         string   `json:"key"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	PlayerIDs   []int32  `json:"player_ids"`
+	Players     []string `json:"players"`
+	Value       int64    `json:"value"`
+	Detail      string   `json:"detail"` // the value, worded
+}
+
+// awardKinds are the awards handed out, in the order they're shown. Each
+// scores a player; the highest positive score wins it.
+var awardKinds = []struct {
+	key, title, description string
+	score                   func(summaryPlayer) int64
+	detail                  func(int64) string
+}{
+	{"most_accusations", "sharpest eye", "most accusations made",
+		func(p summaryPlayer) int64 { return int64(p.Accusations) },
+		func(n int64) string { return countOf(n, "accusation") }},
+	{"most_affirmed", "rule breaker", "most infractions affirmed against them",
+		func(p summaryPlayer) int64 { return int64(p.Affirmed) },
+		func(n int64) string { return countOf(n, "infraction") }},
+	{"most_rules", "rule hoarder", "most rules held at the end",
+		func(p summaryPlayer) int64 { return int64(p.RulesHeld) },
+		func(n int64) string { return countOf(n, "rule") }},
+	{"biggest_swing", "big swing", "biggest single points change",
+		func(p summaryPlayer) int64 { return int64(abs32(p.BiggestSwing)) },
+		nil}, // worded with its sign, by swingDetail
+	{"longest_turn", "slowpoke", "longest turn",
+		func(p summaryPlayer) int64 { return int64(p.LongestTurn) },
+		func(n int64) string { return (time.Duration(n) * time.Second).String() }},
+	{"prompt_champion", "prompt champion", "most points won at prompts",
+		func(p summaryPlayer) int64 { return int64(p.PromptPoints) },
+		func(n int64) string { return countOf(n, "point") }},
+}
+
+// countOf words n of a thing, e.g. "1 rule" or "3 rules".
+func countOf(n int64, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// Totals words the table's totals, for the game-over screen.
+func (s gameSummary) Totals() string {
+	return fmt.Sprintf("%s · %s · %s, %d upheld · %s won",
+		countOf(int64(s.Turns), "turn"), countOf(int64(s.Spins), "spin"),
+		countOf(int64(s.Accusations), "accusation"), s.Affirmed,
+		countOf(int64(s.PromptsWon), "prompt"))
+}
+
+// summarizeGame reads the game's event log and sums it up.
+func summarizeGame(ctx context.Context, v view) (gameSummary, error) {
+	events, err := queries.SummaryEvents(ctx, v.Game.ID)
+	if err != nil {
+		return gameSummary{}, fmt.Errorf("list summary events: %w", err)
+	}
+	return summarize(v, events), nil
+}
+
+// summarize tallies events for each player who took part, seated players in
+// seat order and then the departed as they turn up, and hands out the awards.
+// A turn runs from its turn event to the next, or to the game's end, less any
+// time paused. What the host undid isn't counted.
+func summarize(v view, events []sqlc.SummaryEventsRow) gameSummary {
+	sum := gameSummary{Final: v.Game.StateID == stateOver}
+	index := make(map[int32]int)
+	var host int32
+	for _, p := range v.Players {
+		if p.Initiative.Int32 == 0 {
+			host = p.PlayerID
+			continue
+		}
+		index[p.PlayerID] = len(sum.Players)
+		sum.Players = append(sum.Players, summaryPlayer{
+			PlayerID: p.PlayerID,
+			Name:     p.Name,
+			Seated:   true,
+			Points:   p.Points.Int32,
+		})
+		for _, c := range v.CardsPlayers {
+			if c.PlayerID.Int32 == p.PlayerID && c.Type == "rule" {
+				sum.Players[len(sum.Players)-1].RulesHeld++
+			}
+		}
+	}
+	// seat finds where id's tallies are, starting them for a departed
+	// player; -1 for the host, or no one
+	seat := func(id int32, name string) int {
+		if id == 0 || id == host {
+			return -1
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(sum.Players)
+			index[id] = i
+			sum.Players = append(sum.Players, summaryPlayer{PlayerID: id, Name: name})
+		}
+		return i
+	}
+	// player is seat's tallies, to count on at once: appending a departed
+	// player may move the rest
+	player := func(id int32, name string) *summaryPlayer {
+		if i := seat(id, name); i >= 0 {
+			return &sum.Players[i]
+		}
+		return nil
+	}
+
+	// an undo takes back the event it names; undoing a verdict takes back
+	// every verdict on that infraction so far, and their points
+	undone := make(map[int32]bool)
+	reopened := make(map[int32]int32) // infraction id -> its latest undo
+	for _, e := range events {
+		if e.EventType != "undo" {
+			continue
+		}
+		if e.UndoOf.Valid {
+			undone[e.UndoOf.Int32] = true
+		}
+		if e.InfractionID.Valid {
+			reopened[e.InfractionID.Int32] = e.ID
+		}
+	}
+	counts := func(e sqlc.SummaryEventsRow) bool {
+		switch e.EventType {
+		case "undo":
+			return false
+		case "decide", "points":
+			if e.InfractionID.Valid && e.ID < reopened[e.InfractionID.Int32] {
+				return false
+			}
+		}
+		return !undone[e.ID]
+	}
+
+	var (
+		turn     = -1
+		turnFrom time.Time
+		paused   time.Duration
+		pausedAt time.Time
+	)
+	// a game can end while paused, so a pause not yet resumed counts too
+	endTurn := func(at time.Time) {
+		if !pausedAt.IsZero() {
+			paused += at.Sub(pausedAt)
+		}
+		if turn >= 0 {
+			p := &sum.Players[turn]
+			if secs := int((at.Sub(turnFrom) - paused) / time.Second); secs > p.LongestTurn {
+				p.LongestTurn = secs
+			}
+		}
+		turn, paused, pausedAt = -1, 0, time.Time{}
+	}
+	for _, e := range events {
+		if !counts(e) {
+			continue
+		}
+		at := e.Ts.Time
+		if e.PointsPlayerID.Valid {
+			if p := player(e.PointsPlayerID.Int32, e.TargetName.String); p != nil && abs32(e.PointsDelta.Int32) > abs32(p.BiggestSwing) {
+				p.BiggestSwing = e.PointsDelta.Int32
+			}
+		}
+		switch e.EventType {
+		case "turn":
+			endTurn(at)
+			sum.Turns++
+			turn, turnFrom = seat(e.TargetID.Int32, e.TargetName.String), at
+		case "end", "rolled-end":
+			endTurn(at)
+		case "pause":
+			pausedAt = at
+		case "resume":
+			if !pausedAt.IsZero() {
+				paused += at.Sub(pausedAt)
+				pausedAt = time.Time{}
+			}
+		case "spin":
+			sum.Spins++
+			spinner, name := e.SpinPlayerID, e.ActorName
+			if !spinner.Valid {
+				spinner = e.ActorID
+			}
+			if p := player(spinner.Int32, name.String); p != nil {
+				p.Spins++
+			}
+		case "accuse":
+			sum.Accusations++
+			if p := player(e.ActorID.Int32, e.ActorName.String); p != nil {
+				p.Accusations++
+			}
+		case "decide":
+			if !e.InfractionAffirmed.Bool {
+				continue
+			}
+			sum.Affirmed++
+			if p := player(e.InfractionAccused.Int32, e.AccusedName.String); p != nil {
+				p.Affirmed++
+			}
+		case "prompt":
+			if !e.PointsDelta.Valid {
+				continue
+			}
+			sum.PromptsWon++
+			if p := player(e.TargetID.Int32, e.TargetName.String); p != nil {
+				p.PromptsWon++
+				p.PromptPoints += e.PointsDelta.Int32
+			}
+		}
+	}
+
+	for _, kind := range awardKinds {
+		a := award{Key: kind.key, Title: kind.title, Description: kind.description}
+		for _, p := range sum.Players {
+			score := kind.score(p)
+			switch {
+			case score <= 0 || score < a.Value:
+				continue
+			case score > a.Value:
+				a.Value, a.PlayerIDs, a.Players = score, nil, nil
+			}
+			a.PlayerIDs = append(a.PlayerIDs, p.PlayerID)
+			a.Players = append(a.Players, p.Name)
+		}
+		if a.Value == 0 {
+			continue // nobody earned it
+		}
+		if kind.detail != nil {
+			a.Detail = kind.detail(a.Value)
+		} else {
+			a.Detail = swingDetail(sum.Players, a)
+		}
+		sum.Awards = append(sum.Awards, a)
+	}
+	if sum.Players == nil {
+		sum.Players = []summaryPlayer{}
+	}
+	if sum.Awards == nil {
+		sum.Awards = []award{}
+	}
+	return sum
+}
+
+// swingDetail words the biggest swing with its sign, when every holder's
+// went the same way.
+func swingDetail(players []summaryPlayer, a award) string {
+	var up, down bool
+	for _, p := range players {
+		for _, id := range a.PlayerIDs {
+			if p.PlayerID == id {
+				up, down = up || p.BiggestSwing > 0, down || p.BiggestSwing < 0
+			}
+		}
+	}
+	switch {
+	case up && !down:
+		return "+" + countOf(a.Value, "point")
+	case down && !up:
+		return "-" + countOf(a.Value, "point")
+	}
+	return "±" + countOf(a.Value, "point")
+}
+
+func abs32(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	v := view{
+		Game: sqlc.GameStateRow{ID: "sum", StateID: stateOver},
+		Players: []playerView{
+			{PlayerID: 10, Name: "Host", Initiative: pgInt(0)},
+			{PlayerID: 11, Name: "Ann", Initiative: pgInt(1), Points: pgInt(22)},
+			{PlayerID: 12, Name: "Bo", Initiative: pgInt(2), Points: pgInt(15)},
+		},
+		CardsPlayers: []sqlc.GameCardsPlayerViewRow{
+			{PlayerID: pgInt(12), Type: "rule"},
+			{PlayerID: pgInt(12), Type: "rule"},
+			{PlayerID: pgInt(11), Type: "rule"},
+			{PlayerID: pgInt(11), Type: "modifier"},
+		},
+	}
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	at := func(sec int) pgtype.Timestamp {
+		return pgtype.Timestamp{Time: start.Add(time.Duration(sec) * time.Second), Valid: true}
+	}
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: true} }
+	turn := func(id, sec int, name string) sqlc.SummaryEventsRow {
+		return sqlc.SummaryEventsRow{EventType: "turn", Ts: at(sec), TargetID: pgInt(int32(id)), TargetName: text(name)}
+	}
+	spin := func(id, sec int, name string) sqlc.SummaryEventsRow {
+		return sqlc.SummaryEventsRow{EventType: "spin", Ts: at(sec), ActorID: pgInt(int32(id)), ActorName: text(name), SpinPlayerID: pgInt(int32(id))}
+	}
+	events := []sqlc.SummaryEventsRow{
+		{EventType: "start", Ts: at(0)},
+		turn(11, 0, "Ann"),
+		spin(11, 5, "Ann"),
+		{EventType: "prompt", Ts: at(20), TargetID: pgInt(11), TargetName: text("Ann"), PointsPlayerID: pgInt(11), PointsDelta: pgInt(3)},
+		turn(12, 30, "Bo"),
+		spin(12, 31, "Bo"),
+		// a pause doesn't count against the turn
+		{EventType: "pause", Ts: at(40)},
+		{EventType: "resume", Ts: at(400)},
+		{EventType: "accuse", Ts: at(410), ActorID: pgInt(13), ActorName: text("Dee"), TargetID: pgInt(12), TargetName: text("Bo"), InfractionAccused: pgInt(12), AccusedName: text("Bo")},
+		{EventType: "decide", Ts: at(415), TargetID: pgInt(13), InfractionAccused: pgInt(12), AccusedName: text("Bo"), InfractionAffirmed: pgtype.Bool{Bool: true, Valid: true}},
+		{EventType: "points", Ts: at(415), TargetID: pgInt(12), TargetName: text("Bo"), PointsPlayerID: pgInt(12), PointsDelta: pgInt(-5)},
+		{EventType: "accuse", Ts: at(420), ActorID: pgInt(11), ActorName: text("Ann"), TargetID: pgInt(12), TargetName: text("Bo"), InfractionAccused: pgInt(12), AccusedName: text("Bo")},
+		{EventType: "decide", Ts: at(425), TargetID: pgInt(11), InfractionAccused: pgInt(12), AccusedName: text("Bo"), InfractionAffirmed: pgtype.Bool{Valid: true}},
+		turn(13, 430, "Dee"),
+		spin(13, 431, "Dee"),
+		{EventType: "leave", Ts: at(500), ActorID: pgInt(13), ActorName: text("Dee")},
+		turn(11, 500, "Ann"),
+		{EventType: "prompt", Ts: at(510), TargetID: pgInt(11), TargetName: text("Ann")},
+		{EventType: "end", Ts: at(520)},
+	}
+	sum := summarize(v, events)
+
+	require.True(t, sum.Final)
+	require.Equal(t, 4, sum.Turns)
+	require.Equal(t, 3, sum.Spins)
+	require.Equal(t, 2, sum.Accusations)
+	require.Equal(t, 1, sum.Affirmed)
+	require.Equal(t, 1, sum.PromptsWon, "a failed prompt wins nothing")
+	require.Equal(t, "4 turns · 3 spins · 2 accusations, 1 upheld · 1 prompt won", sum.Totals())
+	require.Equal(t, []summaryPlayer{
+		{PlayerID: 11, Name: "Ann", Seated: true, Points: 22, RulesHeld: 1, Spins: 1, Accusations: 1, PromptsWon: 1, PromptPoints: 3, BiggestSwing: 3, LongestTurn: 30},
+		{PlayerID: 12, Name: "Bo", Seated: true, Points: 15, RulesHeld: 2, Spins: 1, Affirmed: 1, BiggestSwing: -5, LongestTurn: 40},
+		{PlayerID: 13, Name: "Dee", Spins: 1, Accusations: 1, LongestTurn: 70},
+	}, sum.Players, "the host is left out, and the departed come last")
+
+	awards := make(map[string]award)
+	for _, a := range sum.Awards {
+		awards[a.Key] = a
+	}
+	require.Equal(t, []string{"Ann", "Dee"}, awards["most_accusations"].Players, "ties share an award")
+	require.Equal(t, "1 accusation", awards["most_accusations"].Detail)
+	require.Equal(t, []int32{12}, awards["most_affirmed"].PlayerIDs)
+	require.Equal(t, []string{"Bo"}, awards["most_rules"].Players)
+	require.Equal(t, "2 rules", awards["most_rules"].Detail)
+	require.Equal(t, []string{"Bo"}, awards["biggest_swing"].Players)
+	require.Equal(t, "-5 points", awards["biggest_swing"].Detail)
+	require.Equal(t, []string{"Dee"}, awards["longest_turn"].Players)
+	require.Equal(t, "1m10s", awards["longest_turn"].Detail)
+	require.Equal(t, []string{"Ann"}, awards["prompt_champion"].Players)
+	require.Equal(t, "sharpest eye", sum.Awards[0].Title, "awards keep their order")
+}
+
+func TestSummarizeNothing(t *testing.T) {
+	v := view{Players: []playerView{{PlayerID: 10, Name: "Host", Initiative: pgInt(0)}}}
+	sum := summarize(v, []sqlc.SummaryEventsRow{{EventType: "start"}})
+	require.False(t, sum.Final)
+	require.NotNil(t, sum.Players)
+	require.Empty(t, sum.Players)
+	require.NotNil(t, sum.Awards, "no awards is an empty list")
+	require.Empty(t, sum.Awards)
+}
+
+func TestSummarizeUndo(t *testing.T) {
+	v := view{
+		Game: sqlc.GameStateRow{ID: "undo", StateID: stateOver},
+		Players: []playerView{
+			{PlayerID: 10, Name: "Host", Initiative: pgInt(0)},
+			{PlayerID: 11, Name: "Ann", Initiative: pgInt(1)},
+			{PlayerID: 12, Name: "Bo", Initiative: pgInt(2)},
+		},
+	}
+	inf := pgInt(7)
+	affirmed := pgtype.Bool{Bool: true, Valid: true}
+	events := []sqlc.SummaryEventsRow{
+		{ID: 1, EventType: "accuse", ActorID: pgInt(11), TargetID: pgInt(12), InfractionID: inf},
+		// guilty for 9, taken back, then guilty for 2
+		{ID: 2, EventType: "points", TargetID: pgInt(12), InfractionID: inf, PointsPlayerID: pgInt(12), PointsDelta: pgInt(-9)},
+		{ID: 3, EventType: "decide", TargetID: pgInt(11), InfractionID: inf, InfractionAccused: pgInt(12), InfractionAffirmed: affirmed},
+		{ID: 4, EventType: "undo", ActorID: pgInt(10), TargetID: pgInt(12), InfractionID: inf, UndoOf: pgInt(3), PointsPlayerID: pgInt(12), PointsDelta: pgInt(9)},
+		{ID: 5, EventType: "points", TargetID: pgInt(12), InfractionID: inf, PointsPlayerID: pgInt(12), PointsDelta: pgInt(-2)},
+		{ID: 6, EventType: "decide", TargetID: pgInt(11), InfractionID: inf, InfractionAccused: pgInt(12), InfractionAffirmed: affirmed},
+		// prompt points taken back
+		{ID: 7, EventType: "prompt", TargetID: pgInt(11), PointsPlayerID: pgInt(11), PointsDelta: pgInt(4)},
+		{ID: 8, EventType: "undo", ActorID: pgInt(10), TargetID: pgInt(11), UndoOf: pgInt(7), PointsPlayerID: pgInt(11), PointsDelta: pgInt(-4)},
+	}
+	sum := summarize(v, events)
+
+	require.Equal(t, 1, sum.Accusations)
+	require.Equal(t, 1, sum.Affirmed, "the verdict counts once")
+	require.Equal(t, 0, sum.PromptsWon)
+	require.Equal(t, []summaryPlayer{
+		{PlayerID: 11, Name: "Ann", Seated: true, Accusations: 1},
+		{PlayerID: 12, Name: "Bo", Seated: true, Affirmed: 1, BiggestSwing: -2},
+	}, sum.Players, "nothing undone counts, nor the undos")
+}
+
+func TestSummarizeEndedPaused(t *testing.T) {
+	v := view{
+		Game: sqlc.GameStateRow{ID: "paused", StateID: stateOver},
+		Players: []playerView{
+			{PlayerID: 10, Name: "Host", Initiative: pgInt(0)},
+			{PlayerID: 11, Name: "Ann", Initiative: pgInt(1)},
+		},
+	}
+	start := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	at := func(sec int) pgtype.Timestamp {
+		return pgtype.Timestamp{Time: start.Add(time.Duration(sec) * time.Second), Valid: true}
+	}
+	events := []sqlc.SummaryEventsRow{
+		{EventType: "turn", Ts: at(0), TargetID: pgInt(11), TargetName: pgtype.Text{String: "Ann", Valid: true}},
+		// paused for the night, and ended without resuming
+		{EventType: "pause", Ts: at(25)},
+		{EventType: "end", Ts: at(3600)},
+	}
+	sum := summarize(v, events)
+	require.Len(t, sum.Players, 1)
+	require.Equal(t, 25, sum.Players[0].LongestTurn, "the open pause doesn't count against the turn")
+}
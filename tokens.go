@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// tokenPrefix marks a rulette bearer token, so one pasted somewhere it
+// shouldn't be is easy to spot.
+const tokenPrefix = "rlt_"
+
+// bearerKey carries a request's bearer identity in its context.
+type bearerKey struct{}
+
+// bearer is who a bearer token stands for: a game player and the session key
+// hash they'd otherwise present in a cookie. err is set instead when the
+// token stands for no one.
+type bearer struct {
+	playerID int32
+	keyHash  string
+	err      error
+}
+
+// bearerToken returns the token an Authorization: Bearer header carries.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenMW resolves an Authorization: Bearer token to the game player it
+// stands for, for cookie to hand the handler in place of a session cookie.
+// A token that's unknown, revoked, or for another game leaves the request
+// carrying ErrTokenInvalid, which the handler answers.
+func tokenMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gameID := r.PathValue("game_id")
+		id := bearer{err: ErrTokenInvalid}
+		row, err := queries.TokenIdentity(r.Context(), hashSessionKey(token))
+		switch {
+		case err == nil && row.GameID == gameID && row.SessionKey.Valid:
+			id = bearer{playerID: row.PlayerID, keyHash: row.SessionKey.String}
+		case err == nil:
+			log.Warn("bearer token for another game", "game_id", gameID, "token_game_id", row.GameID)
+		case errors.Is(err, pgx.ErrNoRows):
+			log.Debug("unknown bearer token", "game_id", gameID)
+		default:
+			log.Error("look up bearer token", "error", err, "game_id", gameID)
+			id.err = err
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bearerKey{}, id)))
+	})
+}
+
+// newToken returns a fresh bearer token.
+func newToken() (string, error) {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+// mintToken makes playerID a bearer token for gameID, returning its id and
+// the token itself, which is kept nowhere but the answer.
+func mintToken(ctx context.Context, gameID string, playerID int32) (int32, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return 0, "", fmt.Errorf("make token: %w", err)
+	}
+	id, err := queries.TokenCreate(ctx, sqlc.TokenCreateParams{
+		GameID:    gameID,
+		PlayerID:  playerID,
+		TokenHash: hashSessionKey(token),
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("store token: %w", err)
+	}
+	return id, token, nil
+}
+
+// mintedToken is the apiToken signal a new token rides out on.
+type mintedToken struct {
+	ID    int32  `json:"id"`
+	Token string `json:"token"`
+}
+
+// tokenAction handles the caller's bearer tokens, seated or watching, in any
+// state. "token": mint one, sent back once in the apiToken signal.
+// "revoke-tokens": revoke the caller's tokens, or only token_id; the host
+// may name another player's with player_id.
+func tokenAction(w http.ResponseWriter, r *http.Request, log *slog.Logger, s *state, action, cookieKey string) {
+	gameID := s.Game.ID
+	callerID := int32(s.viewFor(cookieKey).CallerID)
+	switch action {
+	case "token":
+		id, token, err := mintToken(r.Context(), gameID, callerID)
+		if err != nil {
+			log.Error("mint token", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		signal, _ := json.Marshal(map[string]mintedToken{"apiToken": {ID: id, Token: token}})
+		log.Info("token minted", "player_id", callerID, "token_id", id)
+		w.Header().Set("HX-Trigger", string(signal))
+		w.WriteHeader(http.StatusOK)
+	case "revoke-tokens":
+		playerID := callerID
+		if v := r.FormValue("player_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				http.Error(w, "invalid player id", http.StatusBadRequest)
+				return
+			}
+			playerID = int32(id)
+		}
+		if playerID != callerID && !s.isHost(cookieKey) {
+			log.Warn("non-host attempted to revoke another player's tokens", "player_id", playerID)
+			http.Error(w, "only the host can revoke another player's tokens", http.StatusForbidden)
+			return
+		}
+		var tokenID pgtype.Int4
+		if v := r.FormValue("token_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				http.Error(w, "invalid token id", http.StatusBadRequest)
+				return
+			}
+			tokenID = pgInt(int32(id))
+		}
+		n, err := queries.TokenRevoke(r.Context(), sqlc.TokenRevokeParams{
+			GameID:   gameID,
+			PlayerID: playerID,
+			TokenID:  tokenID,
+		})
+		if err != nil {
+			log.Error("revoke tokens", "error", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("tokens revoked", "player_id", playerID, "token_id", tokenID.Int32, "count", n)
+		w.Header().Set("HX-Trigger", fmt.Sprintf(`{"tokensRevoked":%d}`, n))
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, ErrActionInvalid.Error(), http.StatusBadRequest)
+	}
+}
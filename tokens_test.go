@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer rlt_abc", "rlt_abc", true},
+		{"bearer  rlt_abc ", "rlt_abc", true},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer", "", false},
+		{"Bearer ", "", false},
+		{"", "", false},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/abc123/data/state", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		got, ok := bearerToken(r)
+		require.Equal(t, tc.ok, ok, "%q", tc.header)
+		require.Equal(t, tc.want, got, "%q", tc.header)
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	a, err := newToken()
+	require.NoError(t, err)
+	b, err := newToken()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(a, tokenPrefix))
+	require.Len(t, a, len(tokenPrefix)+2*secretLength)
+	require.NotEqual(t, a, b)
+}
+
+func TestCookieBearer(t *testing.T) {
+	initLogger(nil)
+	r := httptest.NewRequest(http.MethodGet, "/abc123/data/state", nil)
+	// a signed cookie the bearer identity takes precedence over
+	r.AddCookie(sessionCookie(r, "abc123", 9, "cookie-key"))
+
+	resolved := r.WithContext(context.WithValue(r.Context(), bearerKey{}, bearer{playerID: 4, keyHash: "hash"}))
+	id, key, err := cookie(resolved)
+	require.NoError(t, err)
+	require.Equal(t, "4", id)
+	require.Equal(t, "hash", key)
+
+	refused := r.WithContext(context.WithValue(r.Context(), bearerKey{}, bearer{err: ErrTokenInvalid}))
+	_, _, err = cookie(refused)
+	require.ErrorIs(t, err, ErrTokenInvalid)
+
+	w := httptest.NewRecorder()
+	setCookieErr(w, err)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	id, key, err = cookie(r)
+	require.NoError(t, err)
+	require.Equal(t, "9", id)
+	require.Equal(t, hashSessionKey("cookie-key"), key)
+}
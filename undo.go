@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// infraction returns the game's infraction id, and false if there's none.
+func (s *state) infraction(id int32) (sqlc.Infractions, bool) {
+	for _, inf := range s.Infractions {
+		if inf.ID == id {
+			return inf, true
+		}
+	}
+	return sqlc.Infractions{}, false
+}
+
+// undoable reports why the host can't undo last right now, or nil if they
+// can. An undone verdict reopens the challenge, so it needs a game players
+// can accuse in and an accused still seated, neither of them now the host.
+// Prompt points go back only from a player still seated. A transferred card
+// goes back only while the recipient still holds it, out of any open
+// challenge, to a sender still seated. The error is safe to show.
+func (s *state) undoable(last sqlc.EventLastUndoableRow) error {
+	switch last.EventType {
+	case "decide":
+		if s.Game.StateID == statePrompt {
+			return errors.New("Wait for the prompt to finish.")
+		}
+		if !s.isGameActive() {
+			return errors.New("A verdict can't be reopened now.")
+		}
+		inf, ok := s.infraction(last.InfractionID.Int32)
+		if !ok {
+			return errors.New("That accusation is gone.")
+		}
+		if seat, ok := s.playerSeat(inf.Accused); !ok || seat == 0 {
+			return errors.New("The accused isn't at the table to be judged again.")
+		}
+		if seat, ok := s.playerSeat(inf.Accuser); ok && seat == 0 {
+			return errors.New("The accuser is the host now, and can't judge their own accusation.")
+		}
+	case "prompt":
+		if _, ok := s.playerSeat(last.PointsPlayerID.Int32); !ok {
+			return errors.New("They've left the game; their points stand.")
+		}
+	case "transfer":
+		if _, ok := s.playerSeat(last.ActorID.Int32); !ok {
+			return errors.New("Whoever gave the card has left the game.")
+		}
+		var held bool
+		for _, c := range s.CardsPlayers {
+			if c.ID == last.GameCardID.Int32 && c.PlayerID.Int32 == last.TargetID.Int32 {
+				held = true
+				break
+			}
+		}
+		if !held {
+			return errors.New("That card has moved on since; it can't go back.")
+		}
+		for _, inf := range s.Infractions {
+			if inf.Active.Bool && inf.GameCardID == last.GameCardID.Int32 {
+				return errors.New("That card is part of the open challenge; decide it first.")
+			}
+		}
+	}
+	return nil
+}
+
+// undo reverses last from the detail rows its event references and returns
+// the undo event recording it, for the caller to add; last itself stays in
+// the log. A verdict's points come back and its challenge reopens, prompt
+// points are taken back, a transferred card returns to its sender and a
+// cloned card is shredded, unless it's part of the open challenge, which is
+// ErrCloneChallenged. The modifier spent on a transfer or clone stays spent.
+// pgx.ErrNoRows means what last changed has since moved on.
+func undo(ctx context.Context, q *sqlc.Queries, s *state, last sqlc.EventLastUndoableRow) (sqlc.EventCreateParams, error) {
+	gameID := s.Game.ID
+	event := sqlc.EventCreateParams{
+		GameID:    gameID,
+		EventType: "undo",
+		ActorID:   pgInt(int32(s.CallerID)),
+		UndoOf:    pgInt(last.ID),
+	}
+	var err error
+	switch last.EventType {
+	case "decide":
+		inf, _ := s.infraction(last.InfractionID.Int32)
+		reopened, err := q.InfractionUndecide(ctx, sqlc.InfractionUndecideParams{
+			ID:     inf.ID,
+			GameID: gameID,
+		})
+		if err != nil {
+			return event, fmt.Errorf("reopen infraction: %w", err)
+		}
+		if reopened == 0 {
+			return event, pgx.ErrNoRows
+		}
+		// every verdict on it so far, less those undone already
+		net, err := q.PointChangesInfractionNet(ctx, sqlc.PointChangesInfractionNetParams{
+			GameID:       gameID,
+			InfractionID: last.InfractionID,
+		})
+		if err != nil {
+			return event, fmt.Errorf("sum infraction points: %w", err)
+		}
+		event.PointChangeID, err = adjustPoints(ctx, q, gameID, inf.Accused, -net, last.InfractionID)
+		if err != nil {
+			return event, err
+		}
+		// the challenge interrupts whatever's underway, as an accusation
+		// does; deciding it again resumes it
+		if err := q.GameUpdate(ctx, sqlc.GameUpdateParams{
+			ID:                gameID,
+			StateID:           stateChallenge,
+			InitiativeCurrent: pgInt(s.Game.InitiativeCurrent.Int32),
+		}); err != nil {
+			return event, fmt.Errorf("reopen challenge: %w", err)
+		}
+		event.TargetID = pgInt(inf.Accused)
+		event.InfractionID = last.InfractionID
+	case "prompt":
+		event.PointChangeID, err = adjustPoints(ctx, q, gameID, last.PointsPlayerID.Int32, -last.PointsDelta.Int32, pgtype.Int4{})
+		if err != nil {
+			return event, err
+		}
+		event.TargetID = last.PointsPlayerID
+	case "transfer":
+		moved, err := q.GameCardMove(ctx, sqlc.GameCardMoveParams{
+			ID:       last.GameCardID.Int32,
+			GameID:   gameID,
+			PlayerID: last.ActorID,
+			Holder:   last.TargetID,
+		})
+		if err != nil {
+			return event, fmt.Errorf("move card back: %w", err)
+		}
+		if moved == 0 {
+			return event, pgx.ErrNoRows
+		}
+		event.TargetID = last.ActorID
+		event.GameCardID = last.GameCardID
+	case "clone":
+		clone, err := q.GameCardCloneHeld(ctx, sqlc.GameCardCloneHeldParams{
+			ID:       last.GameCardID.Int32,
+			GameID:   gameID,
+			PlayerID: last.TargetID,
+		})
+		if err != nil {
+			return event, fmt.Errorf("find clone: %w", err)
+		}
+		for _, inf := range s.Infractions {
+			if inf.Active.Bool && inf.GameCardID == clone {
+				return event, ErrCloneChallenged
+			}
+		}
+		if err := q.GameCardShred(ctx, sqlc.GameCardShredParams{
+			ID:     clone,
+			GameID: gameID,
+		}); err != nil {
+			return event, fmt.Errorf("shred clone: %w", err)
+		}
+		event.TargetID = last.TargetID
+		event.GameCardID = pgInt(clone)
+	}
+	return event, nil
+}
+
+// adjustPoints changes playerID's points by delta and records the change in
+// the ledger, returning its row to reference from an event. No change needs
+// no row.
+func adjustPoints(ctx context.Context, q *sqlc.Queries, gameID string, playerID, delta int32, infractionID pgtype.Int4) (pgtype.Int4, error) {
+	if delta == 0 {
+		return pgtype.Int4{}, nil
+	}
+	if err := q.GamePointsAdjust(ctx, sqlc.GamePointsAdjustParams{
+		Points:   pgInt(delta),
+		GameID:   gameID,
+		PlayerID: playerID,
+	}); err != nil {
+		return pgtype.Int4{}, fmt.Errorf("adjust points: %w", err)
+	}
+	id, err := q.PointChangeCreate(ctx, sqlc.PointChangeCreateParams{
+		GameID:       gameID,
+		PlayerID:     pgInt(playerID),
+		Delta:        delta,
+		InfractionID: infractionID,
+	})
+	if err != nil {
+		return pgtype.Int4{}, fmt.Errorf("record point change: %w", err)
+	}
+	return pgInt(id), nil
+}
+
+// undoAction handles the host's undo, in any state short of game over: the
+// newest verdict, prompt points, transfer or clone not yet undone is reversed,
+// and an undo event records the reversal beside it. Undoing again walks
+// further back.
+func undoAction(w http.ResponseWriter, r *http.Request, log *slog.Logger, s *state, cookieKey string) {
+	gameID := s.Game.ID
+	if !s.isHost(cookieKey) {
+		log.Warn("non-host attempted to undo", "game_id", gameID)
+		http.Error(w, "only the host can undo", http.StatusForbidden)
+		return
+	}
+	notice := func(msg string) {
+		log.Info("undo refused", "reason", msg)
+		w.Header().Set("HX-Trigger", `{"notice":`+strconv.Quote(msg)+`}`)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	tx, err := dbPool.Begin(r.Context())
+	if err != nil {
+		log.Error("begin transaction", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+	txq := queries.WithTx(tx)
+	if err := txq.EventUndoLock(r.Context(), gameID); err != nil {
+		log.Error("lock game for undo", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	last, err := txq.EventLastUndoable(r.Context(), gameID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		notice("Nothing to undo.")
+		return
+	}
+	if err != nil {
+		log.Error("find event to undo", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if err := s.undoable(last); err != nil {
+		notice(err.Error())
+		return
+	}
+	event, err := undo(r.Context(), txq, s, last)
+	if errors.Is(err, pgx.ErrNoRows) {
+		notice("That's changed since; there's nothing to take back.")
+		return
+	}
+	if errors.Is(err, ErrCloneChallenged) {
+		notice("That card is part of the open challenge; decide it first.")
+		return
+	}
+	if err != nil {
+		log.Error("undo", "error", err, "event_id", last.ID, "event_type", last.EventType)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if err := writeEvent(w, r, log, txq, event); err != nil {
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Error("commit", "error", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("event undone", "event_id", last.ID, "event_type", last.EventType)
+	invalidate(r.Context(), gameID)
+	w.Header().Set("HX-Trigger", "refreshTable")
+	w.WriteHeader(http.StatusOK)
+}
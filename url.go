@@ -18,14 +18,7 @@ const maxlenDNS = 253
 // the host must look like a host, so a client reaching the app without a
 // header-sanitizing proxy can't poison the absolute URLs.
 func baseURL(r *http.Request) string {
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
-	}
-	proto := strings.ToLower(firstToken(r.Header.Get("X-Forwarded-Proto")))
-	if proto == "http" || proto == "https" {
-		scheme = proto
-	}
+	scheme := requestScheme(r)
 	// Prefer a forwarded host, fall back to the host the server received,
 	// but require either to look like a host so an over-long or malformed
 	// Host header can't reflect into the absolute URL.
@@ -39,6 +32,28 @@ func baseURL(r *http.Request) string {
 	return scheme + "://" + host
 }
 
+// requestScheme returns "https" or "http", whichever the client used to reach
+// us: a TLS connection, or else a valid X-Forwarded-Proto from the proxy in
+// front.
+func requestScheme(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	proto := strings.ToLower(firstToken(r.Header.Get("X-Forwarded-Proto")))
+	if proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme
+}
+
+// secureRequest reports whether the client reached us over https, so the
+// cookies set for it can be Secure; a Secure cookie set over plain http, as in
+// local development, is never sent back.
+func secureRequest(r *http.Request) bool {
+	return requestScheme(r) == "https"
+}
+
 // firstToken returns the first comma-separated value of a header,
 // trimmed. Across a proxy chain X-Forwarded-* arrives comma-joined
 // ("https, http"); only the left-most value — set by the proxy closest
@@ -58,6 +58,13 @@ func TestBaseURL(t *testing.T) {
 	}
 }
 
+func TestSecureRequest(t *testing.T) {
+	require.False(t, secureRequest(newReq("localhost:7777", false, "", "")), "plain http in dev")
+	require.True(t, secureRequest(newReq("rulette.party", true, "", "")), "direct TLS")
+	require.True(t, secureRequest(newReq("10.0.0.11:7777", false, "https", "")), "TLS at the proxy")
+	require.False(t, secureRequest(newReq("rulette.party", false, "gopher", "")), "unknown scheme")
+}
+
 func TestValidHost(t *testing.T) {
 	tests := []struct {
 		name string
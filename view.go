@@ -0,0 +1,208 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// role is the part a caller plays in a game, which decides what their view
+// of it shows.
+type role string
+
+const (
+	roleHost      role = "host"
+	roleTurn      role = "turn" // the player whose turn it is
+	rolePlayer    role = "player"
+	roleSpectator role = "spectator" // not seated in the game
+)
+
+// playerView is a seated player as anyone may see them: no session key.
+type playerView struct {
+	PlayerID   int32
+	Name       string
+	Points     pgtype.Int4
+	Initiative pgtype.Int4
+	Bot        bool
+}
+
+// wheelSlotView is a wheel slot as anyone may see it: how deep its stack
+// runs, not what's on top.
+type wheelSlotView struct {
+	Slot      int32
+	StackSize int64
+}
+
+// view is what one caller may see of a game. Every template and JSON
+// response is built from a view rather than the cached state, which holds
+// every player's session key and the wheel's hidden cards.
+type view struct {
+	Role         role
+	Updated      time.Time
+	Game         sqlc.GameStateRow
+	Players      []playerView
+	Spectators   []string // names of those watching without a seat
+	Wheel        []wheelSlotView
+	CardsPlayers []sqlc.GameCardsPlayerViewRow // revealed cards held by players
+	Config       map[string]string
+	Infractions  []sqlc.Infractions
+	CallerID     int
+	CallerName   string
+	AwaitingAck  bool
+	TurnDeadline time.Time
+	CardsWritten []sqlc.GameCardsWrittenRow
+	// Decks lists the saved decks to pick from, for the host only.
+	Decks []sqlc.DecksRow
+	Packs []sqlc.GamePacksRow
+	// HostVotes is the tally for a new host, for the seated players.
+	HostVotes []sqlc.HostVotesRow
+	// Summary is the game in numbers and its awards, set for the game-over
+	// screen.
+	Summary *gameSummary `json:"summary,omitempty"`
+	// Chart draws the points ledger, set for the points fragment and the
+	// game-over screen.
+	Chart *pointsChart `json:"-"`
+	// CSRFToken goes back with every post; set by the page's handler.
+	CSRFToken string `json:"-"`
+}
+
+// roleOf returns the part the caller holding cookieKey plays in the game.
+func (s *state) roleOf(cookieKey string) role {
+	for _, p := range s.Players {
+		if !sameSession(p.SessionKey, cookieKey) {
+			continue
+		}
+		switch {
+		case p.Initiative.Int32 == 0:
+			return roleHost
+		case p.Initiative.Int32 == s.Game.InitiativeCurrent.Int32:
+			return roleTurn
+		default:
+			return rolePlayer
+		}
+	}
+	return roleSpectator
+}
+
+// viewFor builds the view of the game for the caller holding cookieKey,
+// who sees themselves as CallerID and CallerName, seated or watching.
+func (s *state) viewFor(cookieKey string) view {
+	v := view{
+		Role:         s.roleOf(cookieKey),
+		Updated:      s.Updated,
+		Game:         s.Game,
+		Players:      make([]playerView, 0, len(s.Players)),
+		CardsPlayers: s.CardsPlayers,
+		Config:       s.Config,
+		Infractions:  s.Infractions,
+		AwaitingAck:  s.AwaitingAck,
+		TurnDeadline: s.TurnDeadline,
+		CardsWritten: s.CardsWritten,
+		Packs:        s.Packs,
+	}
+	for _, p := range s.Players {
+		if sameSession(p.SessionKey, cookieKey) {
+			v.CallerID = int(p.PlayerID)
+			v.CallerName = p.Name
+		}
+		v.Players = append(v.Players, playerView{
+			PlayerID:   p.PlayerID,
+			Name:       p.Name,
+			Points:     p.Points,
+			Initiative: p.Initiative,
+			Bot:        p.Bot,
+		})
+	}
+	for _, sp := range s.Spectators {
+		if sameSession(sp.SessionKey, cookieKey) {
+			v.CallerID = int(sp.PlayerID)
+			v.CallerName = sp.Name
+		}
+		v.Spectators = append(v.Spectators, sp.Name)
+	}
+	// the wheel view has a row per card; a slot shows once
+	seen := make(map[int32]bool, s.Game.WheelSlots)
+	for _, c := range s.CardsWheel {
+		if !c.Slot.Valid || seen[c.Slot.Int32] {
+			continue
+		}
+		seen[c.Slot.Int32] = true
+		v.Wheel = append(v.Wheel, wheelSlotView{Slot: c.Slot.Int32, StackSize: c.StackSize})
+	}
+	if v.Role == roleHost {
+		v.Decks = s.Decks
+	}
+	if v.Role != roleSpectator {
+		v.HostVotes = s.HostVotes
+	}
+	return v
+}
+
+// HostVotesFor returns the votes for playerID to be the new host.
+func (v view) HostVotesFor(playerID int32) int64 {
+	for _, hv := range v.HostVotes {
+		if hv.CandidateID == playerID {
+			return hv.Votes
+		}
+	}
+	return 0
+}
+
+// CardsOwed returns how many more cards playerID must write before the
+// writing phase closes on its own.
+func (v view) CardsOwed(playerID int32) int {
+	return cardsOwed(v.Game, v.CardsWritten, playerID)
+}
+
+// TurnSecondsLeft is the whole seconds left on the turn clock, or -1 when no
+// clock is running.
+func (v view) TurnSecondsLeft() int {
+	return secondsLeft(v.TurnDeadline)
+}
+
+// Settings lists the game's settings for the lobby.
+func (v view) Settings() []settingView {
+	return settingViews(v.Game)
+}
+
+// Standings returns the non-host players ranked by points, highest first.
+func (v view) Standings() []playerView {
+	ranked := make([]playerView, 0, len(v.Players))
+	for _, p := range v.Players {
+		if p.Initiative.Int32 == 0 {
+			continue // skip the host
+		}
+		ranked = append(ranked, p)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Points.Int32 > ranked[j].Points.Int32
+	})
+	return ranked
+}
+
+// Winners returns the player(s) with the most points, including ties.
+func (v view) Winners() []playerView {
+	ranked := v.Standings()
+	if len(ranked) == 0 {
+		return nil
+	}
+	most := ranked[0].Points.Int32
+	var winners []playerView
+	for _, p := range ranked {
+		if p.Points.Int32 == most {
+			winners = append(winners, p)
+		}
+	}
+	return winners
+}
+
+// secondsLeft is the whole seconds until deadline, or -1 for a zero deadline.
+func secondsLeft(deadline time.Time) int {
+	if deadline.IsZero() {
+		return -1
+	}
+	return max(0, int(math.Ceil(time.Until(deadline).Seconds())))
+}
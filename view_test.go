@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	sqlc "github.com/grackleclub/rulette/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewFor(t *testing.T) {
+	key := func(k string) pgtype.Text { return pgtype.Text{String: k, Valid: true} }
+	s := state{
+		Game: sqlc.GameStateRow{StateID: stateTurn, InitiativeCurrent: pgInt(1), WheelSlots: 3},
+		Players: []sqlc.GamePlayerPointsRow{
+			{PlayerID: 10, Name: "Sam", SessionKey: key("host-secret"), Initiative: pgInt(0)},
+			{PlayerID: 11, Name: "Oscar", SessionKey: key("turn-secret"), Initiative: pgInt(1)},
+			{PlayerID: 12, Name: "Anna", SessionKey: key("player-secret"), Initiative: pgInt(2)},
+		},
+		Spectators: []sqlc.GameSpectatorsRow{
+			{PlayerID: 13, Name: "Jeremy", SessionKey: key("watcher-secret")},
+		},
+		CardsWheel: []sqlc.GameCardsWheelViewRow{
+			{Slot: pgInt(1), StackSize: 2, TopCardType: "rule"},
+			{Slot: pgInt(1), StackSize: 2, TopCardType: "rule"},
+			{Slot: pgInt(2), StackSize: 1, TopCardType: "modifier"},
+		},
+		Decks:     []sqlc.DecksRow{{ID: 1, Name: "house", CardCount: 12}},
+		HostVotes: []sqlc.HostVotesRow{{CandidateID: 11, Votes: 1}},
+	}
+
+	tests := []struct {
+		key       string
+		role      role
+		callerID  int
+		decks     bool
+		hostVotes bool
+	}{
+		{"host-secret", roleHost, 10, true, true},
+		{"turn-secret", roleTurn, 11, false, true},
+		{"player-secret", rolePlayer, 12, false, true},
+		{"watcher-secret", roleSpectator, 13, false, false},
+		{"", roleSpectator, 0, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.role), func(t *testing.T) {
+			v := s.viewFor(tc.key)
+			require.Equal(t, tc.role, v.Role)
+			require.Equal(t, tc.callerID, v.CallerID)
+			require.Len(t, v.Players, 3)
+			require.Equal(t, []string{"Jeremy"}, v.Spectators)
+			require.Equal(t, []wheelSlotView{{Slot: 1, StackSize: 2}, {Slot: 2, StackSize: 1}}, v.Wheel)
+			require.Equal(t, tc.decks, v.Decks != nil)
+			require.Equal(t, tc.hostVotes, v.HostVotes != nil)
+
+			body, err := json.Marshal(v)
+			require.NoError(t, err)
+			for _, secret := range []string{"secret", "SessionKey", "TopCardType", "modifier"} {
+				require.NotContains(t, string(body), secret)
+			}
+		})
+	}
+}
+
+func TestCanWatch(t *testing.T) {
+	key := func(k string) pgtype.Text { return pgtype.Text{String: k, Valid: true} }
+	s := state{
+		Players:    []sqlc.GamePlayerPointsRow{{PlayerID: 10, SessionKey: key("player")}},
+		Spectators: []sqlc.GameSpectatorsRow{{PlayerID: 11, SessionKey: key("watcher")}},
+	}
+	require.True(t, s.canWatch("player"))
+	require.True(t, s.canWatch("watcher"))
+	require.False(t, s.canWatch("stranger"))
+	require.False(t, s.isPlayerInGame("watcher"), "a spectator has no seat")
+	require.True(t, s.isSpectator("watcher"))
+	require.False(t, s.isSpectator("player"))
+}
+
+func TestHostVotesFor(t *testing.T) {
+	v := view{HostVotes: []sqlc.HostVotesRow{
+		{CandidateID: 11, Votes: 2},
+		{CandidateID: 12, Votes: 1},
+	}}
+	require.Equal(t, int64(2), v.HostVotesFor(11))
+	require.Equal(t, int64(1), v.HostVotesFor(12))
+	require.Equal(t, int64(0), v.HostVotesFor(13))
+}
+
+func TestWinners(t *testing.T) {
+	v := view{Players: []playerView{
+		{PlayerID: 10, Points: pgInt(99), Initiative: pgInt(0)},
+		{PlayerID: 11, Points: pgInt(20), Initiative: pgInt(1)},
+		{PlayerID: 12, Points: pgInt(25), Initiative: pgInt(2)},
+		{PlayerID: 13, Points: pgInt(25), Initiative: pgInt(3)},
+	}}
+	require.Equal(t, []int32{12, 13, 11}, ids(v.Standings()), "the host doesn't place")
+	require.Equal(t, []int32{12, 13}, ids(v.Winners()))
+}
+
+func ids(players []playerView) []int32 {
+	out := make([]int32, 0, len(players))
+	for _, p := range players {
+		out = append(out, p.PlayerID)
+	}
+	return out
+}